@@ -1,5 +1,10 @@
 // libmodbusgo golang binding for libmodbus
 //
+// # backends
+//
+// The package links libmodbus with cgo. When cgo is disabled or with the purego build tag, the same API is
+// implemented in Go, without libmodbus. The serial lines of the Go backend are only supported on Linux.
+//
 // # libmodbus functions convert
 //
 //	| C                                  | GO                              | comment |
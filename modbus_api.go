@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 package libmodbusgo
 
 /*
@@ -12,6 +14,7 @@ extern int get_errno_cgo();
 import "C"
 import (
 	"iter"
+	"syscall"
 	"time"
 	"unsafe"
 )
@@ -56,6 +59,9 @@ func ModbusStrError() error {
 // The broadcast address is MODBUS_BROADCAST_ADDRESS. This special value must be use when
 // you want all Modbus devices of the network receive the request.
 func (x *Modbus) SetSlave(slave int) (err error) {
+	if x.be != nil {
+		return x.be.setSlave(slave)
+	}
 	code := C.modbus_set_slave(x.ctx, C.int(slave))
 	if code < 0 {
 		err = ModbusStrError()
@@ -68,6 +74,9 @@ func (x *Modbus) SetSlave(slave int) (err error) {
 //
 // The modbus_get_slave() function shall get the slave number in the libmodbus context.
 func (x *Modbus) GetSlave() (slave int, err error) {
+	if x.be != nil {
+		return x.be.slave, nil
+	}
 	code := C.modbus_get_slave(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
@@ -102,6 +111,10 @@ func (x *Modbus) GetSlave() (slave int, err error) {
 //
 // It's not recommended to enable error recovery for a Modbus slave/server.
 func (x *Modbus) SetErrorRecovery(errorRecovery ModbusErrorRecoveryMode) (err error) {
+	if x.be != nil {
+		x.be.errorRecovery = errorRecovery
+		return
+	}
 	code := C.modbus_set_error_recovery(x.ctx, C.modbus_error_recovery_mode(errorRecovery))
 	if code < 0 {
 		err = ModbusStrError()
@@ -115,11 +128,19 @@ func (x *Modbus) SetErrorRecovery(errorRecovery ModbusErrorRecoveryMode) (err er
 // The modbus_connect() function shall establish a connection to a Modbus server, a network or a bus
 // using the context information of libmodbus context given in argument.
 func (x *Modbus) Connect() (err error) {
+	if x.be != nil {
+		return x.be.connect()
+	}
 	code := C.modbus_connect(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
 		return
 	}
+	if x.rtuConfig != nil {
+		if err = x.rtuApplyConfig(); err != nil {
+			C.modbus_close(x.ctx)
+		}
+	}
 	return
 }
 
@@ -128,6 +149,9 @@ func (x *Modbus) Connect() (err error) {
 // The modbus_set_socket() function shall set the socket or file descriptor in the libmodbus context.
 // This function is useful for managing multiple client connections to the same server.
 func (x *Modbus) SetSocket(s int) (err error) {
+	if x.be != nil {
+		return x.be.setSocket(s)
+	}
 	code := C.modbus_set_socket(x.ctx, C.int(s))
 	if code < 0 {
 		err = ModbusStrError()
@@ -140,6 +164,9 @@ func (x *Modbus) SetSocket(s int) (err error) {
 //
 // The modbus_get_socket() function shall return the current socket or file descriptor of the libmodbus context.
 func (x *Modbus) GetSocket() (s int, err error) {
+	if x.be != nil {
+		return x.be.socket()
+	}
 	code := C.modbus_get_socket(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
@@ -158,6 +185,13 @@ func (x *Modbus) GetSocket() (s int, err error) {
 //
 // The value of to_usec argument must be in the range 0 to 999999.
 func (x *Modbus) SetResponseTimeout(timeout time.Duration) (err error) {
+	if x.be != nil {
+		if timeout <= 0 {
+			return ErrorCode(syscall.EINVAL).Error()
+		}
+		x.be.responseTimeout = timeout
+		return
+	}
 	usec := timeout - time.Duration(timeout.Seconds())*time.Second
 	code := C.modbus_set_response_timeout(x.ctx, C.uint32_t(timeout.Seconds()), C.uint32_t(usec.Microseconds()))
 	if code < 0 {
@@ -172,6 +206,9 @@ func (x *Modbus) SetResponseTimeout(timeout time.Duration) (err error) {
 // The modbus_get_response_timeout() function shall return the timeout interval used to wait for a response
 // in the to_sec and to_usec arguments.
 func (x *Modbus) GetResponseTimeout() (timeout time.Duration, err error) {
+	if x.be != nil {
+		return x.be.responseTimeout, nil
+	}
 	to_sec := C.uint32_t(0)
 	to_usec := C.uint32_t(0)
 	code := C.modbus_get_response_timeout(x.ctx, &to_sec, &to_usec)
@@ -197,6 +234,13 @@ func (x *Modbus) GetResponseTimeout() (timeout time.Duration, err error) {
 // response must be received before expiration of the response timeout. When a byte timeout is set,
 // the response timeout is only used to wait for until the first byte of the response.
 func (x *Modbus) SetByteTimeout(timeout time.Duration) (err error) {
+	if x.be != nil {
+		if timeout < 0 {
+			return ErrorCode(syscall.EINVAL).Error()
+		}
+		x.be.byteTimeout = timeout
+		return
+	}
 	usec := timeout - time.Duration(timeout.Seconds())*time.Second
 	code := C.modbus_set_byte_timeout(x.ctx, C.uint32_t(timeout.Seconds()), C.uint32_t(usec.Microseconds()))
 	if code < 0 {
@@ -211,6 +255,9 @@ func (x *Modbus) SetByteTimeout(timeout time.Duration) (err error) {
 // The modbus_get_byte_timeout() function shall store the timeout interval between two
 // consecutive bytes of the same message in the to_sec and to_usec arguments.
 func (x *Modbus) GetByteTimeout() (timeout time.Duration, err error) {
+	if x.be != nil {
+		return x.be.byteTimeout, nil
+	}
 	to_sec := C.uint32_t(0)
 	to_usec := C.uint32_t(0)
 	code := C.modbus_get_byte_timeout(x.ctx, &to_sec, &to_usec)
@@ -232,6 +279,13 @@ func (x *Modbus) GetByteTimeout() (timeout time.Duration, err error) {
 // If both to_sec and to_usec are zero, this timeout will not be used at all. In this case,
 // the server will wait forever.
 func (x *Modbus) SetIndicationTimeout(timeout time.Duration) (err error) {
+	if x.be != nil {
+		if timeout < 0 {
+			return ErrorCode(syscall.EINVAL).Error()
+		}
+		x.be.indicationTimeout = timeout
+		return
+	}
 	usec := timeout - time.Duration(timeout.Seconds())*time.Second
 	code := C.modbus_set_indication_timeout(x.ctx, C.uint32_t(timeout.Seconds()), C.uint32_t(usec.Microseconds()))
 	if code < 0 {
@@ -250,6 +304,9 @@ func (x *Modbus) SetIndicationTimeout(timeout time.Duration) (err error) {
 //
 // The default value is zero, it means the server will wait forever.
 func (x *Modbus) GetIndicationTimeout() (timeout time.Duration, err error) {
+	if x.be != nil {
+		return x.be.indicationTimeout, nil
+	}
 	to_sec := C.uint32_t(0)
 	to_usec := C.uint32_t(0)
 	code := C.modbus_get_indication_timeout(x.ctx, &to_sec, &to_usec)
@@ -267,6 +324,9 @@ func (x *Modbus) GetIndicationTimeout() (timeout time.Duration, err error) {
 // the backend. This function is convenient to manipulate a message and so it's limited to
 // low-level operations.
 func (x *Modbus) GetHeaderLength() (length int) {
+	if x.be != nil {
+		return x.be.framing.headerLength()
+	}
 	code := C.modbus_get_header_length(x.ctx)
 	length = int(code)
 	return
@@ -276,22 +336,22 @@ func (x *Modbus) GetHeaderLength() (length int) {
 //
 // The modbus_free() function shall free an allocated modbus_t structure.
 func (x *Modbus) Free() {
+	if x.be != nil {
+		x.be.close()
+		return
+	}
+	rtsCallbacks.Delete(x.ctx)
 	C.modbus_free(x.ctx)
-	mapSetRtsCallback.Range(func(key, value any) bool {
-		cctx, ok := key.(*Modbus)
-		if ok {
-			if cctx == x {
-				mapSetRtsCallback.Delete(key)
-			}
-		}
-		return true
-	})
 }
 
 // Close modbus_close - close a Modbus connection
 //
 // The modbus_close() function shall close the connection established with the backend set in the context.
 func (x *Modbus) Close() {
+	if x.be != nil {
+		x.be.close()
+		return
+	}
 	if x.ctx != nil {
 		C.modbus_close(x.ctx)
 	}
@@ -305,6 +365,9 @@ func (x *Modbus) Close() {
 // The modbus_flush() function shall discard data received but not read to the socket or file descriptor associated
 // to the context 'ctx'.
 func (x *Modbus) Flush() (err error) {
+	if x.be != nil {
+		return x.be.flush()
+	}
 	code := C.modbus_flush(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
@@ -319,6 +382,10 @@ func (x *Modbus) Flush() (err error) {
 // By default, the boolean flag is set to FALSE. When the flag value is set to TRUE, many verbose messages are
 // displayed on stdout and stderr. For example, this flag is useful to display the bytes of the Modbus messages.
 func (x *Modbus) SetDebug(flag bool) (err error) {
+	if x.be != nil {
+		x.be.setDebug(flag)
+		return
+	}
 	f := C.FALSE
 	if flag {
 		f = C.TRUE
@@ -340,6 +407,9 @@ func (x *Modbus) SetDebug(flag bool) (err error) {
 //
 // The function uses the Modbus function code 0x01 (read coil status).
 func (x *Modbus) ReadBits(addr int, nb int) (out []byte, err error) {
+	if x.be != nil {
+		return x.be.readBits(MODBUS_FC_READ_COILS, addr, nb)
+	}
 	dest := make([]C.uint8_t, nb)
 	code := C.modbus_read_bits(x.ctx, C.int(addr), C.int(nb), unsafe.SliceData(dest))
 	if code < 0 {
@@ -361,6 +431,9 @@ func (x *Modbus) ReadBits(addr int, nb int) (out []byte, err error) {
 //
 // The function uses the Modbus function code 0x02 (read input status).
 func (x *Modbus) ReadInputBits(addr int, nb int) (out []byte, err error) {
+	if x.be != nil {
+		return x.be.readBits(MODBUS_FC_READ_DISCRETE_INPUTS, addr, nb)
+	}
 	dest := make([]C.uint8_t, nb)
 	code := C.modbus_read_input_bits(x.ctx, C.int(addr), C.int(nb), unsafe.SliceData(dest))
 	if code < 0 {
@@ -382,6 +455,9 @@ func (x *Modbus) ReadInputBits(addr int, nb int) (out []byte, err error) {
 //
 // The function uses the Modbus function code 0x03 (read holding registers).
 func (x *Modbus) ReadRegisters(addr int, nb int) (out []uint16, err error) {
+	if x.be != nil {
+		return x.be.readRegisters(MODBUS_FC_READ_HOLDING_REGISTERS, addr, nb)
+	}
 	dest := make([]C.uint16_t, nb)
 	code := C.modbus_read_registers(x.ctx, C.int(addr), C.int(nb), unsafe.SliceData(dest))
 	if code < 0 {
@@ -404,6 +480,9 @@ func (x *Modbus) ReadRegisters(addr int, nb int) (out []uint16, err error) {
 // The function uses the Modbus function code 0x04 (read input registers). The holding registers and input registers
 // have different historical meaning, but nowadays it's more common to use holding registers only.
 func (x *Modbus) ReadInputRegisters(addr int, nb int) (out []uint16, err error) {
+	if x.be != nil {
+		return x.be.readRegisters(MODBUS_FC_READ_INPUT_REGISTERS, addr, nb)
+	}
 	dest := make([]C.uint16_t, nb)
 	code := C.modbus_read_input_registers(x.ctx, C.int(addr), C.int(nb), unsafe.SliceData(dest))
 	if code < 0 {
@@ -423,6 +502,13 @@ func (x *Modbus) ReadInputRegisters(addr int, nb int) (out []uint16, err error)
 //
 // The function uses the Modbus function code 0x05 (force single coil).
 func (x *Modbus) WriteBit(addr int, status byte) (err error) {
+	if x.be != nil {
+		value := 0
+		if status != 0 {
+			value = 0xFF00
+		}
+		return x.be.writeSingle(MODBUS_FC_WRITE_SINGLE_COIL, addr, value)
+	}
 	code := C.modbus_write_bit(x.ctx, C.int(addr), C.int(status))
 	if code < 0 {
 		err = ModbusStrError()
@@ -438,6 +524,9 @@ func (x *Modbus) WriteBit(addr int, status byte) (err error) {
 //
 // he function uses the Modbus function code 0x06 (preset single register).
 func (x *Modbus) WriteRegister(addr int, value uint16) (err error) {
+	if x.be != nil {
+		return x.be.writeSingle(MODBUS_FC_WRITE_SINGLE_REGISTER, addr, int(value))
+	}
 	code := C.modbus_write_register(x.ctx, C.int(addr), C.uint16_t(value))
 	if code < 0 {
 		err = ModbusStrError()
@@ -453,6 +542,9 @@ func (x *Modbus) WriteRegister(addr int, value uint16) (err error) {
 //
 // The function uses the Modbus function code 0x0F (force multiple coils).
 func (x *Modbus) WriteBits(addr int, data []byte) (err error) {
+	if x.be != nil {
+		return x.be.writeBits(addr, data)
+	}
 	nb := len(data)
 	dest := make([]C.uint8_t, nb)
 	for k, v := range data {
@@ -473,6 +565,9 @@ func (x *Modbus) WriteBits(addr int, data []byte) (err error) {
 //
 // The function uses the Modbus function code 0x10 (preset multiple registers).
 func (x *Modbus) WriteRegisters(addr int, data []uint16) (err error) {
+	if x.be != nil {
+		return x.be.writeRegisters(addr, data)
+	}
 	nb := len(data)
 	dest := make([]C.uint16_t, nb)
 	for k, v := range data {
@@ -495,6 +590,9 @@ func (x *Modbus) WriteRegisters(addr int, data []uint16) (err error) {
 //
 // The function uses the Modbus function code 0x16 (mask single register).
 func (x *Modbus) MaskWriteRegister(addr int, andMask uint16, orMask uint16) (err error) {
+	if x.be != nil {
+		return x.be.maskWriteRegister(addr, andMask, orMask)
+	}
 	code := C.modbus_mask_write_register(x.ctx, C.int(addr), C.uint16_t(andMask), C.uint16_t(orMask))
 	if code < 0 {
 		err = ModbusStrError()
@@ -514,6 +612,9 @@ func (x *Modbus) MaskWriteRegister(addr int, andMask uint16, orMask uint16) (err
 //
 // The function uses the Modbus function code 0x17 (write/read registers).
 func (x *Modbus) WriteAndReadRegisters(writeAddr int, src []uint16, readAddr int, readNb int) (dest []uint16, err error) {
+	if x.be != nil {
+		return x.be.writeAndReadRegisters(writeAddr, src, readAddr, readNb)
+	}
 	writeNb := len(src)
 	csrc := make([]C.uint16_t, writeNb)
 	for k, v := range src {
@@ -546,6 +647,16 @@ func (x *Modbus) WriteAndReadRegisters(writeAddr int, src []uint16, readAddr int
 //
 // The function writes at most max_dest bytes from the response to dest so you must ensure that dest is large enough.
 func (x *Modbus) ReportSlaveId() (dest *ReportSlaveId, err error) {
+	if x.be != nil {
+		buff, err := x.be.reportSlaveId()
+		if err != nil {
+			return nil, err
+		}
+		if len(buff) < 2 {
+			return nil, EMBBADDATA.Error()
+		}
+		return &ReportSlaveId{SlaveId: buff[0], RunIndicatorStatus: buff[1], AdditionalData: buff[2:]}, nil
+	}
 	cdest := make([]C.uint8_t, MODBUS_MAX_PDU_LENGTH)
 	code := C.modbus_report_slave_id(x.ctx, C.int(MODBUS_MAX_PDU_LENGTH), unsafe.SliceData(cdest))
 	if code < 0 {
@@ -556,6 +667,9 @@ func (x *Modbus) ReportSlaveId() (dest *ReportSlaveId, err error) {
 	for i := range code {
 		buff = append(buff, byte(cdest[i]))
 	}
+	if len(buff) < 2 {
+		return nil, EMBBADDATA.Error()
+	}
 	dest = &ReportSlaveId{
 		SlaveId:            buff[0],
 		RunIndicatorStatus: buff[1],
@@ -758,6 +872,29 @@ func (mm *ModbusMapping) SetTabRegisters(addr int, v uint16) {
 	tab[addr-mm.StartRegisters()] = C.uint16_t(v)
 }
 
+// tabs alias the four tables of the mapping for the Go backends
+func (mm *ModbusMapping) tabs() *modbusTabs {
+	t := &modbusTabs{
+		startBits:           mm.StartBits(),
+		startInputBits:      mm.StartInputBits(),
+		startRegisters:      mm.StartRegisters(),
+		startInputRegisters: mm.StartInputRegisters(),
+	}
+	if mm.mb.tab_bits != nil {
+		t.bits = unsafe.Slice((*byte)(unsafe.Pointer(mm.mb.tab_bits)), mm.NbBits())
+	}
+	if mm.mb.tab_input_bits != nil {
+		t.inputBits = unsafe.Slice((*byte)(unsafe.Pointer(mm.mb.tab_input_bits)), mm.NbInputBits())
+	}
+	if mm.mb.tab_registers != nil {
+		t.registers = unsafe.Slice((*uint16)(unsafe.Pointer(mm.mb.tab_registers)), mm.NbRegisters())
+	}
+	if mm.mb.tab_input_registers != nil {
+		t.inputRegisters = unsafe.Slice((*uint16)(unsafe.Pointer(mm.mb.tab_input_registers)), mm.NbInputRegisters())
+	}
+	return t
+}
+
 // Free modbus_mapping_free - free a modbus_mapping_t structure
 //
 // The function shall free the four arrays of modbus_mapping_t structure and finally the modbus_mapping_t itself
@@ -776,6 +913,10 @@ func (mm *ModbusMapping) Free() {
 // The public header of libmodbus provides a list of supported Modbus functions codes, prefixed by MODBUS_FC_ (eg.
 // MODBUS_FC_READ_HOLDING_REGISTERS), to help build of raw requests.
 func (x *Modbus) SendRawRequest(raw []byte) (err error) {
+	if x.be != nil {
+		x.be.tid++
+		return x.be.sendRaw(raw, int(x.be.tid))
+	}
 	req := []C.uint8_t{}
 	for _, v := range raw {
 		req = append(req, C.uint8_t(v))
@@ -789,6 +930,9 @@ func (x *Modbus) SendRawRequest(raw []byte) (err error) {
 }
 
 func (x *Modbus) SendRawRequestTid(raw []byte, tid int) (err error) {
+	if x.be != nil {
+		return x.be.sendRaw(raw, tid)
+	}
 	req := []C.uint8_t{}
 	for _, v := range raw {
 		req = append(req, C.uint8_t(v))
@@ -809,6 +953,9 @@ func (x *Modbus) SendRawRequestTid(raw []byte, tid int) (err error) {
 // If you need to use another socket or file descriptor than the one defined in the context ctx, see the function
 // modbus_set_socket.
 func (x *Modbus) Receive() (req []byte, err error) {
+	if x.be != nil {
+		return x.be.receiveIndication()
+	}
 	recv := make([]C.uint8_t, MODBUS_MAX_ADU_LENGTH)
 	code := C.modbus_receive(x.ctx, unsafe.SliceData(recv))
 	if code < 0 {
@@ -832,6 +979,9 @@ func (x *Modbus) Receive() (req []byte, err error) {
 // use the constant MODBUS_MAX_ADU_LENGTH (maximum value of all libmodbus backends). Take care to allocate enough
 // memory to store responses to avoid crashes of your server.
 func (x *Modbus) ReceiveConfirmation() (rsp []byte, err error) {
+	if x.be != nil {
+		return x.be.receive(false)
+	}
 	recv := make([]C.uint8_t, MODBUS_MAX_ADU_LENGTH)
 	code := C.modbus_receive_confirmation(x.ctx, unsafe.SliceData(recv))
 	if code < 0 {
@@ -856,6 +1006,11 @@ func (x *Modbus) ReceiveConfirmation() (rsp []byte, err error) {
 //
 // This function is designed for Modbus servers.
 func (x *Modbus) Reply(req []byte, mm *ModbusMapping) (err error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if x.be != nil {
+		return x.be.reply(req, mm.tabs())
+	}
 	raw := []C.uint8_t{}
 	for _, v := range req {
 		raw = append(raw, C.uint8_t(v))
@@ -888,6 +1043,9 @@ func (x *Modbus) Reply(req []byte, mm *ModbusMapping) (err error) {
 //
 // The initial request req is required to build a valid response.
 func (x *Modbus) ReplyException(req []byte, ecode uint) (err error) {
+	if x.be != nil {
+		return x.be.replyException(req, ecode)
+	}
 	raw := []C.uint8_t{}
 	for _, v := range req {
 		raw = append(raw, C.uint8_t(v))
@@ -913,6 +1071,10 @@ func (x *Modbus) ReplyException(req []byte, ecode uint) (err error) {
 //
 // You can combine the flags by using the bitwise OR operator.
 func (x *Modbus) EnableQuirks(quirksMask ModbusQuirks) (err error) {
+	if x.be != nil {
+		x.be.quirks |= quirksMask
+		return
+	}
 	code := C.modbus_enable_quirks(x.ctx, C.uint(quirksMask))
 	if code < 0 {
 		err = ModbusStrError()
@@ -935,6 +1097,10 @@ func (x *Modbus) EnableQuirks(quirksMask ModbusQuirks) (err error) {
 //	// Reset all quirks
 //	modbus_disable_quirks(ctx, MODBUS_QUIRK_ALL);
 func (x *Modbus) DisableQuirks(quirksMask ModbusQuirks) (err error) {
+	if x.be != nil {
+		x.be.quirks &^= quirksMask
+		return
+	}
 	code := C.modbus_disable_quirks(x.ctx, C.uint(quirksMask))
 	if code < 0 {
 		err = ModbusStrError()
@@ -943,38 +1109,6 @@ func (x *Modbus) DisableQuirks(quirksMask ModbusQuirks) (err error) {
 	return
 }
 
-func ModbusGetHighByte[T int16 | uint16 | int32 | uint32 | int64 | uint64](data T) byte {
-	return byte((uint64(data) >> 8) & 0xFF)
-}
-
-func ModbusGetLowByte[T int16 | uint16 | int32 | uint32 | int64 | uint64](data T) byte {
-	return byte(uint64(data) & 0xFF)
-}
-
-func ModbusGetInt64FromInt16(tab []int16) int64 {
-	return int64(tab[0])<<48 | int64(tab[1])<<32 | int64(tab[2])<<16 | int64(tab[3])
-}
-
-func ModbusGetInt32FromInt16(tab []int16) int32 {
-	return int32(tab[0])<<16 | int32(tab[1])
-}
-
-func ModbusGetInt16FromInt8(tab []int8) int16 {
-	return int16(tab[0])<<8 | int16(tab[1])
-}
-
-func ModbusSetInt16ToInt8(value int16) []int8 {
-	return []int8{int8(value >> 8), int8(value)}
-}
-
-func ModbusSetInt32ToInt16(value int32) []int16 {
-	return []int16{int16(value >> 16), int16(value)}
-}
-
-func ModbusSetInt64ToInt16(value int64) []int16 {
-	return []int16{int16(value >> 48), int16(value >> 32), int16(value >> 16), int16(value)}
-}
-
 // ModbusSetBitsFromByte modbus_set_bits_from_byte - set many bits from a single byte value
 //
 // The modbus_set_bits_from_byte() function shall set many bits from a single byte. All 8 bits from the byte value will
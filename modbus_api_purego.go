@@ -0,0 +1,478 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+import (
+	"iter"
+	"math"
+	"syscall"
+	"time"
+)
+
+// ModbusStrError modbus_strerror - return the error message
+//
+// The modbus_strerror() function shall return the error message of the last error raised by the library.
+func ModbusStrError() error {
+	return ErrorCode(lastErrno.Load()).Error()
+}
+
+// SetSlave modbus_set_slave - set slave number in the context
+func (x *Modbus) SetSlave(slave int) (err error) {
+	return x.be.setSlave(slave)
+}
+
+// GetSlave modbus_get_slave - get slave number in the context
+func (x *Modbus) GetSlave() (slave int, err error) {
+	return x.be.slave, nil
+}
+
+// SetErrorRecovery modbus_set_error_recovery - set the error recovery mode
+func (x *Modbus) SetErrorRecovery(errorRecovery ModbusErrorRecoveryMode) (err error) {
+	x.be.errorRecovery = errorRecovery
+	return
+}
+
+// Connect modbus_connect - establish a Modbus connection
+func (x *Modbus) Connect() (err error) {
+	return x.be.connect()
+}
+
+// SetSocket modbus_set_socket - set socket of the context
+//
+// The context owns a single connection, the current one is closed when s is another descriptor.
+func (x *Modbus) SetSocket(s int) (err error) {
+	return x.be.setSocket(s)
+}
+
+// GetSocket modbus_get_socket - get the current socket of the context
+func (x *Modbus) GetSocket() (s int, err error) {
+	return x.be.socket()
+}
+
+// SetResponseTimeout modbus_set_response_timeout - set timeout for response
+func (x *Modbus) SetResponseTimeout(timeout time.Duration) (err error) {
+	if timeout <= 0 {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	x.be.responseTimeout = timeout
+	return
+}
+
+// GetResponseTimeout modbus_get_response_timeout - get timeout for response
+func (x *Modbus) GetResponseTimeout() (timeout time.Duration, err error) {
+	return x.be.responseTimeout, nil
+}
+
+// SetByteTimeout modbus_set_byte_timeout - set timeout between bytes
+func (x *Modbus) SetByteTimeout(timeout time.Duration) (err error) {
+	if timeout < 0 {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	x.be.byteTimeout = timeout
+	return
+}
+
+// GetByteTimeout modbus_get_byte_timeout - get timeout between bytes
+func (x *Modbus) GetByteTimeout() (timeout time.Duration, err error) {
+	return x.be.byteTimeout, nil
+}
+
+// SetIndicationTimeout modbus_set_indication_timeout - set timeout between indications
+func (x *Modbus) SetIndicationTimeout(timeout time.Duration) (err error) {
+	if timeout < 0 {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	x.be.indicationTimeout = timeout
+	return
+}
+
+// GetIndicationTimeout modbus_get_indication_timeout - get timeout used to wait for an indication
+func (x *Modbus) GetIndicationTimeout() (timeout time.Duration, err error) {
+	return x.be.indicationTimeout, nil
+}
+
+// GetHeaderLength modbus_get_header_length - retrieve the current header length
+func (x *Modbus) GetHeaderLength() (length int) {
+	return x.be.framing.headerLength()
+}
+
+// Free modbus_free - free a libmodbus context
+func (x *Modbus) Free() {
+	x.be.close()
+}
+
+// Close modbus_close - close a Modbus connection
+func (x *Modbus) Close() {
+	x.be.close()
+}
+
+// Flush modbus_flush - flush non-transmitted data
+func (x *Modbus) Flush() (err error) {
+	return x.be.flush()
+}
+
+// SetDebug modbus_set_debug - set debug flag of the context
+func (x *Modbus) SetDebug(flag bool) (err error) {
+	x.be.setDebug(flag)
+	return
+}
+
+// ReadBits modbus_read_bits - read many bits
+func (x *Modbus) ReadBits(addr int, nb int) (out []byte, err error) {
+	return x.be.readBits(MODBUS_FC_READ_COILS, addr, nb)
+}
+
+// ReadInputBits modbus_read_input_bits - read many input bits
+func (x *Modbus) ReadInputBits(addr int, nb int) (out []byte, err error) {
+	return x.be.readBits(MODBUS_FC_READ_DISCRETE_INPUTS, addr, nb)
+}
+
+// ReadRegisters modbus_read_registers - read many registers
+func (x *Modbus) ReadRegisters(addr int, nb int) (out []uint16, err error) {
+	return x.be.readRegisters(MODBUS_FC_READ_HOLDING_REGISTERS, addr, nb)
+}
+
+// ReadInputRegisters modbus_read_input_registers - read many input registers
+func (x *Modbus) ReadInputRegisters(addr int, nb int) (out []uint16, err error) {
+	return x.be.readRegisters(MODBUS_FC_READ_INPUT_REGISTERS, addr, nb)
+}
+
+// WriteBit modbus_write_bit - write a single bit
+func (x *Modbus) WriteBit(addr int, status byte) (err error) {
+	value := 0
+	if status != 0 {
+		value = 0xFF00
+	}
+	return x.be.writeSingle(MODBUS_FC_WRITE_SINGLE_COIL, addr, value)
+}
+
+// WriteRegister modbus_write_register - write a single register
+func (x *Modbus) WriteRegister(addr int, value uint16) (err error) {
+	return x.be.writeSingle(MODBUS_FC_WRITE_SINGLE_REGISTER, addr, int(value))
+}
+
+// WriteBits modbus_write_bits - write many bits
+func (x *Modbus) WriteBits(addr int, data []byte) (err error) {
+	return x.be.writeBits(addr, data)
+}
+
+// WriteRegisters modbus_write_registers - write many registers
+func (x *Modbus) WriteRegisters(addr int, data []uint16) (err error) {
+	return x.be.writeRegisters(addr, data)
+}
+
+// MaskWriteRegister modbus_mask_write_register - mask a single register
+func (x *Modbus) MaskWriteRegister(addr int, andMask uint16, orMask uint16) (err error) {
+	return x.be.maskWriteRegister(addr, andMask, orMask)
+}
+
+// WriteAndReadRegisters modbus_write_and_read_registers - write and read many registers in a single transaction
+func (x *Modbus) WriteAndReadRegisters(writeAddr int, src []uint16, readAddr int, readNb int) (dest []uint16, err error) {
+	return x.be.writeAndReadRegisters(writeAddr, src, readAddr, readNb)
+}
+
+// ReportSlaveId modbus_report_slave_id - returns a description of the controller
+func (x *Modbus) ReportSlaveId() (dest *ReportSlaveId, err error) {
+	buff, err := x.be.reportSlaveId()
+	if err != nil {
+		return nil, err
+	}
+	if len(buff) < 2 {
+		return nil, EMBBADDATA.Error()
+	}
+	return &ReportSlaveId{SlaveId: buff[0], RunIndicatorStatus: buff[1], AdditionalData: buff[2:]}, nil
+}
+
+// ModbusMappingNewStartAddress modbus_mapping_new_start_address - allocate four arrays of bits and registers accessible
+// from their starting addresses
+func ModbusMappingNewStartAddress(
+	startBits uint,
+	nbBits uint,
+	startInputBits uint,
+	nbInputBits uint,
+	startRegisters uint,
+	nbRegisters uint,
+	startInputRegisters uint,
+	nbInputRegisters uint,
+) *ModbusMapping {
+	t := &modbusTabs{
+		startBits:           int(startBits),
+		startInputBits:      int(startInputBits),
+		startRegisters:      int(startRegisters),
+		startInputRegisters: int(startInputRegisters),
+	}
+	if nbBits > 0 {
+		t.bits = make([]byte, nbBits)
+	}
+	if nbInputBits > 0 {
+		t.inputBits = make([]byte, nbInputBits)
+	}
+	if nbRegisters > 0 {
+		t.registers = make([]uint16, nbRegisters)
+	}
+	if nbInputRegisters > 0 {
+		t.inputRegisters = make([]uint16, nbInputRegisters)
+	}
+	return &ModbusMapping{t: t}
+}
+
+// ModbusMappingNew modbus_mapping_new - allocate four arrays of bits and registers
+func ModbusMappingNew(nbBits int, nbInputBits int, nbRegisters int, nbInputRegisters int) *ModbusMapping {
+	if nbBits < 0 || nbInputBits < 0 || nbRegisters < 0 || nbInputRegisters < 0 {
+		return nil
+	}
+	return ModbusMappingNewStartAddress(0, uint(nbBits), 0, uint(nbInputBits), 0, uint(nbRegisters), 0, uint(nbInputRegisters))
+}
+
+func (mm *ModbusMapping) NbBits() int {
+	return len(mm.t.bits)
+}
+
+func (mm *ModbusMapping) StartBits() int {
+	return mm.t.startBits
+}
+
+func (mm *ModbusMapping) NbInputBits() int {
+	return len(mm.t.inputBits)
+}
+
+func (mm *ModbusMapping) StartInputBits() int {
+	return mm.t.startInputBits
+}
+
+func (mm *ModbusMapping) NbInputRegisters() int {
+	return len(mm.t.inputRegisters)
+}
+
+func (mm *ModbusMapping) StartInputRegisters() int {
+	return mm.t.startInputRegisters
+}
+
+func (mm *ModbusMapping) NbRegisters() int {
+	return len(mm.t.registers)
+}
+
+func (mm *ModbusMapping) StartRegisters() int {
+	return mm.t.startRegisters
+}
+
+func (mm *ModbusMapping) TabBits() iter.Seq2[int, byte] {
+	return func(yield func(int, byte) bool) {
+		for k, v := range mm.t.bits {
+			if !yield(mm.StartBits()+k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (mm *ModbusMapping) GetTabBits(addr int) byte {
+	return mm.t.bits[addr-mm.StartBits()]
+}
+
+func (mm *ModbusMapping) SetTabBits(addr int, v byte) {
+	mm.t.bits[addr-mm.StartBits()] = v
+}
+
+func (mm *ModbusMapping) TabInputBits() iter.Seq2[int, byte] {
+	return func(yield func(int, byte) bool) {
+		for k, v := range mm.t.inputBits {
+			if !yield(mm.StartInputBits()+k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (mm *ModbusMapping) GetTabInputBits(addr int) byte {
+	return mm.t.inputBits[addr-mm.StartInputBits()]
+}
+
+func (mm *ModbusMapping) SetTabInputBits(addr int, v byte) {
+	mm.t.inputBits[addr-mm.StartInputBits()] = v
+}
+
+func (mm *ModbusMapping) TabInputRegisters() iter.Seq2[int, uint16] {
+	return func(yield func(int, uint16) bool) {
+		for k, v := range mm.t.inputRegisters {
+			if !yield(mm.StartInputRegisters()+k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (mm *ModbusMapping) GetTabInputRegisters(addr int) uint16 {
+	return mm.t.inputRegisters[addr-mm.StartInputRegisters()]
+}
+
+func (mm *ModbusMapping) SetTabInputRegisters(addr int, v uint16) {
+	mm.t.inputRegisters[addr-mm.StartInputRegisters()] = v
+}
+
+func (mm *ModbusMapping) TabRegisters() iter.Seq2[int, uint16] {
+	return func(yield func(int, uint16) bool) {
+		for k, v := range mm.t.registers {
+			if !yield(mm.StartRegisters()+k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (mm *ModbusMapping) GetTabRegisters(addr int) uint16 {
+	return mm.t.registers[addr-mm.StartRegisters()]
+}
+
+func (mm *ModbusMapping) SetTabRegisters(addr int, v uint16) {
+	mm.t.registers[addr-mm.StartRegisters()] = v
+}
+
+func (mm *ModbusMapping) tabs() *modbusTabs {
+	return mm.t
+}
+
+// Free modbus_mapping_free - free a modbus_mapping_t structure
+func (mm *ModbusMapping) Free() {
+}
+
+// SendRawRequest modbus_send_raw_request - send a raw request
+func (x *Modbus) SendRawRequest(raw []byte) (err error) {
+	x.be.tid++
+	return x.be.sendRaw(raw, int(x.be.tid))
+}
+
+// SendRawRequestTid modbus_send_raw_request_tid - send a raw request with a transaction identifier
+func (x *Modbus) SendRawRequestTid(raw []byte, tid int) (err error) {
+	return x.be.sendRaw(raw, tid)
+}
+
+// Receive modbus_receive - receive an indication request
+func (x *Modbus) Receive() (req []byte, err error) {
+	return x.be.receiveIndication()
+}
+
+// ReceiveConfirmation modbus_receive_confirmation - receive a confirmation request
+func (x *Modbus) ReceiveConfirmation() (rsp []byte, err error) {
+	return x.be.receive(false)
+}
+
+// Reply modbus_reply - send a response to the received request
+func (x *Modbus) Reply(req []byte, mm *ModbusMapping) (err error) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return x.be.reply(req, mm.tabs())
+}
+
+// ReplyException modbus_reply_exception - send an exception response
+func (x *Modbus) ReplyException(req []byte, ecode uint) (err error) {
+	return x.be.replyException(req, ecode)
+}
+
+// EnableQuirks modbus_enable_quirks - enable a list of quirks according to a mask
+func (x *Modbus) EnableQuirks(quirksMask ModbusQuirks) (err error) {
+	x.be.quirks |= quirksMask
+	return
+}
+
+// DisableQuirks modbus_disable_quirks - disable a list of quirks according to a mask
+func (x *Modbus) DisableQuirks(quirksMask ModbusQuirks) (err error) {
+	x.be.quirks &^= quirksMask
+	return
+}
+
+// ModbusSetBitsFromByte modbus_set_bits_from_byte - set many bits from a single byte value
+func ModbusSetBitsFromByte(dest []byte, index int, value byte) {
+	for i := range 8 {
+		dest[index+i] = value >> i & 1
+	}
+}
+
+// ModbusSetBitsFromBytes modbus_set_bits_from_bytes - set many bits from an array of bytes
+func ModbusSetBitsFromBytes(dest []byte, index int, nb uint, tab []byte) {
+	for i := range int(nb) {
+		dest[index+i] = tab[i/8] >> (i % 8) & 1
+	}
+}
+
+// ModbusGetByteFromBits modbus_get_byte_from_bits - get the value from many bits
+func ModbusGetByteFromBits(src []byte, index int, nb uint) byte {
+	var value byte
+	for i := range int(min(nb, 8)) {
+		value |= src[index+i] << i
+	}
+	return value
+}
+
+// modbusFloatBytes bytes A, B, C and D of f, A being the most significant
+func modbusFloatBytes(f float32) (a, b, c, d uint16) {
+	i := math.Float32bits(f)
+	return uint16(i >> 24), uint16(i >> 16 & 0xFF), uint16(i >> 8 & 0xFF), uint16(i & 0xFF)
+}
+
+// modbusFloatFrom float of the bytes A, B, C and D, A being the most significant
+func modbusFloatFrom(a, b, c, d uint16) float32 {
+	return math.Float32frombits(uint32(a)<<24 | uint32(b)<<16 | uint32(c)<<8 | uint32(d))
+}
+
+// ModbusGetFloat modbus_get_float - get a float value from 2 registers, deprecated in favour of ModbusGetFloatCdab
+func ModbusGetFloat(src []uint16) float32 {
+	return math.Float32frombits(uint32(src[1])<<16 | uint32(src[0]))
+}
+
+// ModbusGetFloatAbcd modbus_get_float_abcd - get a float value from 2 registers in ABCD byte order
+func ModbusGetFloatAbcd(src []uint16) float32 {
+	return modbusFloatFrom(src[0]>>8, src[0]&0xFF, src[1]>>8, src[1]&0xFF)
+}
+
+// ModbusGetFloatDcba modbus_get_float_dcba - get a float value from 2 registers in DCBA byte order
+func ModbusGetFloatDcba(src []uint16) float32 {
+	return modbusFloatFrom(src[1]&0xFF, src[1]>>8, src[0]&0xFF, src[0]>>8)
+}
+
+// ModbusGetFloatBadc modbus_get_float_badc - get a float value from 2 registers in BADC byte order
+func ModbusGetFloatBadc(src []uint16) float32 {
+	return modbusFloatFrom(src[0]&0xFF, src[0]>>8, src[1]&0xFF, src[1]>>8)
+}
+
+// ModbusGetFloatCdab modbus_get_float_cdab - get a float value from 2 registers in CDAB byte order
+func ModbusGetFloatCdab(src []uint16) float32 {
+	return modbusFloatFrom(src[1]>>8, src[1]&0xFF, src[0]>>8, src[0]&0xFF)
+}
+
+// ModbusSetFloat modbus_set_float - set a float value in 2 registers, deprecated in favour of ModbusSetFloatCdab
+func ModbusSetFloat(f float32, dest []uint16) {
+	i := math.Float32bits(f)
+	dest[0] = uint16(i)
+	dest[1] = uint16(i >> 16)
+}
+
+// The setters store the bytes as libmodbus 3.1.10 does on little-endian hosts, where they do not mirror the getters
+
+// ModbusSetFloatAbcd modbus_set_float_abcd - set a float value in 2 registers using ABCD byte order
+func ModbusSetFloatAbcd(f float32, dest []uint16) {
+	a, b, c, d := modbusFloatBytes(f)
+	dest[0] = b<<8 | a
+	dest[1] = d<<8 | c
+}
+
+// ModbusSetFloatDcba modbus_set_float_dcba - set a float value in 2 registers using DCBA byte order
+func ModbusSetFloatDcba(f float32, dest []uint16) {
+	a, b, c, d := modbusFloatBytes(f)
+	dest[0] = c<<8 | d
+	dest[1] = a<<8 | b
+}
+
+// ModbusSetFloatBadc modbus_set_float_badc - set a float value in 2 registers using BADC byte order
+func ModbusSetFloatBadc(f float32, dest []uint16) {
+	a, b, c, d := modbusFloatBytes(f)
+	dest[0] = a<<8 | b
+	dest[1] = c<<8 | d
+}
+
+// ModbusSetFloatCdab modbus_set_float_cdab - set a float value in 2 registers using CDAB byte order
+func ModbusSetFloatCdab(f float32, dest []uint16) {
+	a, b, c, d := modbusFloatBytes(f)
+	dest[0] = d<<8 | c
+	dest[1] = b<<8 | a
+}
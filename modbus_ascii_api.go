@@ -0,0 +1,121 @@
+package libmodbusgo
+
+import (
+	"io"
+)
+
+const modbusHexDigits = "0123456789ABCDEF"
+
+// modbusLrc longitudinal redundancy check of an ASCII frame, the two's complement of the sum of the bytes
+func modbusLrc(b []byte) byte {
+	var sum byte
+	for _, v := range b {
+		sum += v
+	}
+	return -sum
+}
+
+func (modbusAscii) headerLength() int   { return modbusAsciiHeaderLength }
+func (modbusAscii) checksumLength() int { return modbusAsciiChecksumLength }
+func (modbusAscii) serial() bool        { return true }
+
+func (modbusAscii) encode(adu []byte) []byte {
+	frame := make([]byte, 0, 2*len(adu)+5)
+	frame = append(frame, ':')
+	for _, v := range append(adu, modbusLrc(adu)) {
+		frame = append(frame, modbusHexDigits[v>>4], modbusHexDigits[v&0x0F])
+	}
+	return append(frame, '\r', '\n')
+}
+
+func modbusHexValue(c byte) (v byte, ok bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	}
+	return 0, false
+}
+
+// read receive a frame, characters before the ':' are skipped and a ':' in the middle of a frame starts it again
+func (modbusAscii) read(rd *modbusReader, indication bool) (adu []byte, err error) {
+	var c byte
+	for {
+		c, err = rd.readByte()
+		if err != nil {
+			return
+		}
+		if c == ':' {
+			break
+		}
+		rd.started = false
+	}
+
+	adu = make([]byte, 0, (MODBUS_ASCII_MAX_ADU_LENGTH-3)/2)
+	var high byte
+	half := false
+	for {
+		c, err = rd.readByte()
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case ':':
+			adu, half = adu[:0], false
+			continue
+		case '\r':
+			c, err = rd.readByte()
+			if err != nil {
+				return nil, err
+			}
+			if c != '\n' || half || len(adu) < modbusAsciiHeaderLength+1+modbusAsciiChecksumLength {
+				return nil, EMBBADDATA.Error()
+			}
+			if modbusLrc(adu) != 0 {
+				return nil, EMBBADCRC.Error()
+			}
+			return
+		}
+		v, ok := modbusHexValue(c)
+		if !ok {
+			return nil, EMBBADDATA.Error()
+		}
+		if !half {
+			high, half = v, true
+			continue
+		}
+		if len(adu) == cap(adu) {
+			return nil, EMBBADDATA.Error()
+		}
+		adu, half = append(adu, high<<4|v), false
+	}
+}
+
+// ModbusNewAscii create a context for Modbus ASCII
+//
+// The ModbusNewAscii() function shall allocate and initialize a Modbus context to communicate in ASCII mode on a
+// serial line. The arguments are the ones of ModbusNewRtu(), Modbus ASCII lines usually use 7 data bits with even
+// parity.
+//
+// Each message starts with a ':' followed by the slave, the PDU and the LRC as pairs of hexadecimal characters, and
+// ends with CR LF. A message whose LRC is wrong is rejected with EMBBADCRC. The byte timeout is the maximum delay
+// between two characters of a message, it defaults to MODBUS_ASCII_BYTE_TIMEOUT.
+//
+// The context has the same client and server functions as an RTU context, requests are addressed with SetSlave() and
+// broadcasts are not answered. The serial mode and RTS functions of the RTU backend apply, the other functions
+// specific to the RTU and TCP backends return EINVAL.
+func ModbusNewAscii(device string, baud int, parity byte, dataBits int, stopBits int) *Modbus {
+	if serialCheck(device, baud, parity, dataBits, stopBits) != nil {
+		return nil
+	}
+	be := modbusBackendNew(modbusAscii{}, func() (io.ReadWriteCloser, error) {
+		return serialOpen(device, baud, parity, dataBits, stopBits)
+	})
+	be.byteTimeout = MODBUS_ASCII_BYTE_TIMEOUT
+	be.oneByteTime = serialOneByteTime(baud, parity, dataBits, stopBits)
+	be.rtsDelay = be.oneByteTime
+	return &Modbus{be: be}
+}
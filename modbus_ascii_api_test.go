@@ -0,0 +1,147 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty open a pseudo-terminal and return its master and the path of its slave
+func openPty(t *testing.T) (master *os.File, slave string) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	if err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(fd)
+		t.Fatal(err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(fd)
+		t.Fatal(err)
+	}
+	master = os.NewFile(uintptr(fd), "/dev/ptmx")
+	t.Cleanup(func() { master.Close() })
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestModbusAscii_Encode(t *testing.T) {
+	frame := modbusAscii{}.encode([]byte{0x01, MODBUS_FC_READ_HOLDING_REGISTERS, 0x00, 0x00, 0x00, 0x01})
+	if string(frame) != ":010300000001FB\r\n" {
+		t.Errorf("%q", frame)
+	}
+}
+
+func TestModbusNewAscii(t *testing.T) {
+	master, slave := openPty(t)
+
+	mm := ModbusMappingNew(10, 0, 10, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+
+	server := ModbusNewConn(master, MODBUS_FRAMING_ASCII)
+	if server == nil {
+		t.FailNow()
+	}
+	server.SetSlave(17)
+	go func() {
+		for {
+			req, err := server.Receive()
+			if err != nil {
+				return
+			}
+			if len(req) == 0 {
+				continue
+			}
+			server.Reply(req, mm)
+		}
+	}()
+
+	ctx := ModbusNewAscii(slave, 9600, 'E', 7, 1)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(200 * time.Millisecond)
+	if err := ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.SetSlave(17)
+
+	if err := ctx.WriteRegisters(2, []uint16{0x1234, 0xABCD}); err != nil {
+		t.Fatal(err)
+	}
+	regs, err := ctx.ReadRegisters(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regs[0] != 0 || regs[1] != 0x1234 || regs[2] != 0xABCD {
+		t.Errorf("registers %X", regs)
+	}
+	if err = ctx.WriteBit(9, 1); err != nil || mm.GetTabBits(9) != 1 {
+		t.Error("bit 9 not written", err)
+	}
+
+	var merr *Error
+	_, err = ctx.ReadRegisters(8, 5)
+	if !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+		t.Error("expected illegal data address, got", err)
+	}
+
+	// the server ignores other slaves and broadcasts are not answered
+	ctx.SetSlave(18)
+	_, err = ctx.ReadRegisters(0, 1)
+	if !errors.As(err, &merr) || merr.Code() != ErrorCode(unix.ETIMEDOUT) {
+		t.Error("expected a timeout, got", err)
+	}
+	// as libmodbus, the client waits for a response to the broadcast
+	ctx.SetSlave(MODBUS_BROADCAST_ADDRESS)
+	err = ctx.WriteRegister(0, 7)
+	if !errors.As(err, &merr) || merr.Code() != ErrorCode(unix.ETIMEDOUT) {
+		t.Error("expected a timeout, got", err)
+	}
+	ctx.SetSlave(17)
+	regs, err = ctx.ReadRegisters(0, 1)
+	if err != nil || regs[0] != 7 {
+		t.Error("broadcast not served", regs, err)
+	}
+}
+
+func TestModbusAscii_BadLrc(t *testing.T) {
+	master, slave := openPty(t)
+
+	ctx := ModbusNewAscii(slave, 19200, 'N', 8, 1)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetIndicationTimeout(time.Second)
+	if err := ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.SetSlave(1)
+
+	// noise then a frame restarted by ':', with a wrong LRC
+	master.Write([]byte("xx:0103:010300000001FA\r\n"))
+	var merr *Error
+	_, err := ctx.Receive()
+	if !errors.As(err, &merr) || merr.Code() != EMBBADCRC {
+		t.Error("expected a bad LRC, got", err)
+	}
+
+	master.Write([]byte(":010300000001FB\r\n"))
+	req, err := ctx.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("% X", req) != "01 03 00 00 00 01 FB" {
+		t.Errorf("% X", req)
+	}
+}
@@ -0,0 +1,20 @@
+package libmodbusgo
+
+import "time"
+
+const (
+	// MODBUS_ASCII_MAX_ADU_LENGTH Modbus over serial line specification and implementation guide V1.02 Chapter 2 Section
+	// 5 Page 17, ':' + slave (2 chars) + PDU (2 x 253 chars) + LRC (2 chars) + CR LF = 513 chars
+	MODBUS_ASCII_MAX_ADU_LENGTH = 513
+
+	// MODBUS_ASCII_BYTE_TIMEOUT default timeout between two characters, the specification allows up to one second
+	MODBUS_ASCII_BYTE_TIMEOUT = time.Second
+)
+
+const (
+	modbusAsciiHeaderLength   = 1
+	modbusAsciiChecksumLength = 1
+)
+
+// modbusAscii framing of Modbus ASCII, the binary ADU is the slave, the PDU and the LRC
+type modbusAscii struct{}
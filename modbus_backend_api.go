@@ -0,0 +1,924 @@
+package libmodbusgo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// _REPORT_SLAVE_ID slave id returned by libmodbus servers to MODBUS_FC_REPORT_SLAVE_ID
+const modbusReportSlaveId = 180
+
+func modbusBackendNew(framing modbusFramer, dial func() (io.ReadWriteCloser, error)) *modbusBackend {
+	return &modbusBackend{
+		framing:         framing,
+		dial:            dial,
+		slave:           -1,
+		responseTimeout: 500 * time.Millisecond,
+		byteTimeout:     500 * time.Millisecond,
+	}
+}
+
+// modbusErrno convert an I/O error to the errno libmodbus would report
+func modbusErrno(err error) error {
+	var merr *Error
+	if errors.As(err, &merr) {
+		return err
+	}
+	var errno syscall.Errno
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		errno = syscall.ETIMEDOUT
+	case errors.As(err, &errno):
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		errno = syscall.ECONNRESET
+	case errors.Is(err, net.ErrClosed), errors.Is(err, os.ErrClosed):
+		errno = syscall.EBADF
+	default:
+		errno = syscall.EIO
+	}
+	return ErrorCode(errno).Error()
+}
+
+// use replace the connection, the previous one is closed
+func (b *modbusBackend) use(conn io.ReadWriteCloser) {
+	rd := &modbusReader{
+		conn:     conn,
+		buf:      bufio.NewReaderSize(conn, MODBUS_MAX_ADU_LENGTH),
+		debug:    b.debug,
+		datagram: b.datagram,
+	}
+	b.mu.Lock()
+	prev := b.conn
+	b.conn, b.rd = conn, rd
+	b.mu.Unlock()
+	if prev != nil && prev != conn {
+		prev.Close()
+	}
+}
+
+// current the connection and its reader, nil when not connected
+func (b *modbusBackend) current() (conn io.ReadWriteCloser, rd *modbusReader) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.conn, b.rd
+}
+
+// drop close the connection
+func (b *modbusBackend) drop() {
+	b.mu.Lock()
+	conn := b.conn
+	b.conn = nil
+	b.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (b *modbusBackend) connect() (err error) {
+	if b.dial == nil {
+		// the connection was given at creation, it cannot be established again once closed
+		if conn, _ := b.current(); conn == nil {
+			return ErrorCode(syscall.EBADF).Error()
+		}
+		return
+	}
+	b.drop()
+	conn, err := b.dial()
+	if err != nil {
+		return modbusErrno(err)
+	}
+	b.use(conn)
+	if b.config != nil {
+		if err = b.applyConfig(); err != nil {
+			b.drop()
+		}
+	}
+	return
+}
+
+func (b *modbusBackend) close() {
+	b.drop()
+	b.mu.Lock()
+	listener := b.listener
+	b.listener = nil
+	b.mu.Unlock()
+	if listener != nil {
+		listener.Close()
+	}
+}
+
+// listenSocket start listening for connections and return the listening socket, see modbus_tcp_listen()
+func (b *modbusBackend) listenSocket() (s int, err error) {
+	if b.listen == nil {
+		return -1, ErrorCode(syscall.EINVAL).Error()
+	}
+	listener, err := b.listen()
+	if err != nil {
+		return -1, modbusErrno(err)
+	}
+	b.mu.Lock()
+	prev := b.listener
+	b.listener = listener
+	b.mu.Unlock()
+	if prev != nil {
+		prev.Close()
+	}
+	return modbusFd(listener)
+}
+
+// accept wait for a connection on the listening socket, it replaces the current connection
+func (b *modbusBackend) accept() (err error) {
+	b.mu.Lock()
+	listener := b.listener
+	b.mu.Unlock()
+	if listener == nil {
+		return ErrorCode(syscall.EBADF).Error()
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		return modbusErrno(err)
+	}
+	if c, ok := conn.(interface{ Handshake() error }); ok {
+		if err = c.Handshake(); err != nil {
+			conn.Close()
+			if b.debug {
+				fmt.Printf("The handshake with %s failed: %v\n", conn.RemoteAddr(), err)
+			}
+			return ErrorCode(syscall.ECONNABORTED).Error()
+		}
+	}
+	if b.debug {
+		fmt.Printf("The client connection from %s is accepted\n", conn.RemoteAddr())
+	}
+	b.use(conn)
+	return
+}
+
+func (b *modbusBackend) setSlave(slave int) (err error) {
+	maxSlave := 247
+	if b.quirks&MODBUS_QUIRK_MAX_SLAVE != 0 {
+		maxSlave = 255
+	}
+	if (slave < 0 || slave > maxSlave) && (b.framing.serial() || slave != MODBUS_TCP_SLAVE) {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	b.slave = slave
+	return
+}
+
+func (b *modbusBackend) setDebug(flag bool) {
+	b.debug = flag
+	if _, rd := b.current(); rd != nil {
+		rd.debug = flag
+	}
+}
+
+// setSocket use the descriptor s as connection, see modbus_set_socket(). A backend owns a single connection so the
+// current one is closed when s is another descriptor.
+func (b *modbusBackend) setSocket(s int) (err error) {
+	if cur, err := b.socket(); err == nil && cur == s {
+		return nil
+	}
+	if s < 0 {
+		return ErrorCode(syscall.EBADF).Error()
+	}
+	b.use(os.NewFile(uintptr(s), "socket"))
+	return
+}
+
+// socket return the file descriptor of the connection when it has one
+func (b *modbusBackend) socket() (s int, err error) {
+	conn, _ := b.current()
+	return modbusFd(conn)
+}
+
+func modbusFd(v any) (s int, err error) {
+	if c, ok := v.(interface{ NetConn() net.Conn }); ok {
+		v = c.NetConn()
+	}
+	sc, ok := v.(syscall.Conn)
+	if !ok {
+		return -1, ErrorCode(syscall.EBADF).Error()
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return -1, modbusErrno(err)
+	}
+	raw.Control(func(fd uintptr) {
+		s = int(fd)
+	})
+	return
+}
+
+func (b *modbusBackend) flush() (err error) {
+	conn, rd := b.current()
+	if conn == nil {
+		return ErrorCode(syscall.EBADF).Error()
+	}
+	rd.buf.Discard(rd.buf.Buffered())
+	dl, ok := conn.(interface{ SetReadDeadline(time.Time) error })
+	if !ok {
+		return
+	}
+	buf := make([]byte, MODBUS_MAX_ADU_LENGTH)
+	for {
+		dl.SetReadDeadline(time.Now().Add(time.Millisecond))
+		if _, err := conn.Read(buf); err != nil {
+			break
+		}
+	}
+	dl.SetReadDeadline(time.Time{})
+	return
+}
+
+// start prepare the reader for a new message
+func (rd *modbusReader) start(first time.Duration, next time.Duration, whole time.Duration) {
+	rd.first = first
+	rd.next = next
+	rd.deadline = time.Time{}
+	if next == 0 && whole > 0 {
+		rd.deadline = time.Now().Add(whole)
+	}
+	rd.started = false
+}
+
+func (rd *modbusReader) readByte() (c byte, err error) {
+	if rd.buf.Buffered() == 0 {
+		if rd.datagram && rd.started {
+			// truncated datagram
+			return 0, EMBBADDATA.Error()
+		}
+		if dl, ok := rd.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+			deadline := rd.deadline
+			timeout := rd.next
+			if !rd.started {
+				timeout = rd.first
+			}
+			if timeout > 0 {
+				deadline = time.Now().Add(timeout)
+			}
+			dl.SetReadDeadline(deadline)
+		}
+	}
+	c, err = rd.buf.ReadByte()
+	if err != nil {
+		return
+	}
+	rd.started = true
+	if rd.debug {
+		fmt.Printf("<%.2X>", c)
+	}
+	return
+}
+
+func (rd *modbusReader) readFull(n int) (b []byte, err error) {
+	b = make([]byte, n)
+	for i := range b {
+		b[i], err = rd.readByte()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// header build the header of a request sent to the current slave
+func (b *modbusBackend) header(pduLength int) []byte {
+	if b.framing.headerLength() == modbusTcpHeaderLength {
+		b.tid++
+		length := pduLength + 1
+		return []byte{byte(b.tid >> 8), byte(b.tid), 0, 0, byte(length >> 8), byte(length), byte(b.slave)}
+	}
+	return []byte{byte(b.slave)}
+}
+
+// responseHeader build the header of a response to req
+func (b *modbusBackend) responseHeader(req []byte, pduLength int) []byte {
+	offset := b.framing.headerLength()
+	h := append([]byte{}, req[:offset]...)
+	if offset == modbusTcpHeaderLength {
+		length := pduLength + 1
+		h[4], h[5] = byte(length>>8), byte(length)
+	}
+	return h
+}
+
+func (b *modbusBackend) send(adu []byte) (err error) {
+	conn, _ := b.current()
+	if conn == nil {
+		return ErrorCode(syscall.EBADF).Error()
+	}
+	frame := b.framing.encode(adu)
+	if b.debug {
+		for _, c := range frame {
+			fmt.Printf("[%.2X]", c)
+		}
+		fmt.Println()
+	}
+	if b.rts != MODBUS_RTU_RTS_NONE {
+		return b.sendRts(conn, frame)
+	}
+	_, err = conn.Write(frame)
+	if err != nil && b.errorRecovery&MODBUS_ERROR_RECOVERY_LINK != 0 {
+		if b.connect() == nil {
+			conn, _ = b.current()
+			_, err = conn.Write(frame)
+		}
+	}
+	if err != nil {
+		return modbusErrno(err)
+	}
+	return
+}
+
+func (b *modbusBackend) receive(indication bool) (adu []byte, err error) {
+	conn, rd := b.current()
+	if conn == nil {
+		return nil, ErrorCode(syscall.EBADF).Error()
+	}
+	if indication {
+		if b.debug {
+			fmt.Println("Waiting for an indication...")
+		}
+		rd.start(b.indicationTimeout, b.byteTimeout, 0)
+	} else {
+		rd.start(b.responseTimeout, b.byteTimeout, b.responseTimeout)
+	}
+	adu, err = b.framing.read(rd, indication)
+	if b.debug {
+		fmt.Println()
+	}
+	if rd.datagram {
+		// drop the rest of a datagram longer than its message
+		rd.buf.Discard(rd.buf.Buffered())
+	}
+	if err != nil {
+		adu, err = nil, modbusErrno(err)
+		var merr *Error
+		if errors.As(err, &merr) && merr.Code() != ErrorCode(syscall.ETIMEDOUT) &&
+			b.errorRecovery&MODBUS_ERROR_RECOVERY_PROTOCOL != 0 {
+			time.Sleep(b.responseTimeout)
+			b.flush()
+		}
+	}
+	return
+}
+
+// receiveIndication receive a request, requests addressed to other slaves of a serial line are returned empty
+func (b *modbusBackend) receiveIndication() (req []byte, err error) {
+	if b.confirmationToIgnore {
+		b.confirmationToIgnore = false
+		b.receive(false)
+		return []byte{}, nil
+	}
+	req, err = b.receive(true)
+	if err != nil {
+		return
+	}
+	if b.framing.serial() {
+		slave := int(req[b.framing.headerLength()-1])
+		if slave != b.slave && slave != MODBUS_BROADCAST_ADDRESS {
+			if b.debug {
+				fmt.Printf("Request for slave %d ignored (not %d)\n", slave, b.slave)
+			}
+			b.confirmationToIgnore = b.framing.checksumLength() == modbusRtuChecksumLength
+			return []byte{}, nil
+		}
+	}
+	return
+}
+
+// request send a PDU to the current slave and return the PDU of the confirmation, checked against the request
+func (b *modbusBackend) request(pdu []byte) (rsp []byte, err error) {
+	req := append(b.header(len(pdu)), pdu...)
+	err = b.send(req)
+	if err != nil {
+		return
+	}
+	if b.datagram {
+		return b.requestDatagram(req)
+	}
+	adu, err := b.receive(false)
+	if err != nil {
+		return
+	}
+	return b.checkConfirmation(req, adu)
+}
+
+// requestDatagram wait for the confirmation of req, the request is sent again when the response timeout expires and
+// the confirmations of other transactions, late answers to previous requests, are skipped
+func (b *modbusBackend) requestDatagram(req []byte) (rsp []byte, err error) {
+	for retry := 0; ; retry++ {
+		var adu []byte
+		deadline := time.Now().Add(b.responseTimeout)
+		for {
+			adu, err = b.receive(false)
+			var merr *Error
+			if errors.As(err, &merr) && merr.Code() == ErrorCode(syscall.ETIMEDOUT) && retry < b.retries {
+				break
+			}
+			if err != nil {
+				return
+			}
+			if adu[0] == req[0] && adu[1] == req[1] {
+				return b.checkConfirmation(req, adu)
+			}
+			if b.debug {
+				fmt.Printf("Stale transaction ID received 0x%X (not 0x%X)\n",
+					int(adu[0])<<8|int(adu[1]), int(req[0])<<8|int(req[1]))
+			}
+			if time.Now().After(deadline) && retry < b.retries {
+				break
+			}
+		}
+		if b.debug {
+			fmt.Println("Retransmission of the request")
+		}
+		if err = b.send(req); err != nil {
+			return
+		}
+	}
+}
+
+func (b *modbusBackend) checkConfirmation(req []byte, adu []byte) (rsp []byte, err error) {
+	offset := b.framing.headerLength()
+	if len(adu) < offset+b.framing.checksumLength()+2 {
+		return nil, EMBBADDATA.Error()
+	}
+	if offset == modbusTcpHeaderLength {
+		if adu[0] != req[0] || adu[1] != req[1] {
+			if b.debug {
+				fmt.Printf("Invalid transaction ID received 0x%X (not 0x%X)\n",
+					int(adu[0])<<8|int(adu[1]), int(req[0])<<8|int(req[1]))
+			}
+			return nil, EMBBADDATA.Error()
+		}
+		if adu[2] != 0 || adu[3] != 0 {
+			return nil, EMBBADDATA.Error()
+		}
+	} else if adu[0] != req[0] && req[0] != MODBUS_BROADCAST_ADDRESS {
+		if b.debug {
+			fmt.Printf("The responding slave %d isn't the requested slave %d\n", adu[0], req[0])
+		}
+		return nil, EMBBADSLAVE.Error()
+	}
+
+	rsp = adu[offset : len(adu)-b.framing.checksumLength()]
+	pdu := req[offset:]
+	function := pdu[0]
+	if rsp[0] == function|0x80 {
+		code := ModbusException(rsp[1])
+		if code > 0 && code < MODBUS_EXCEPTION_MAX {
+			return nil, (MODBUS_ENOBASE + ErrorCode(code)).Error()
+		}
+		return nil, EMBBADEXC.Error()
+	}
+	if rsp[0] != function {
+		if b.debug {
+			fmt.Printf("Received function not corresponding to the request (0x%X != 0x%X)\n", rsp[0], function)
+		}
+		return nil, EMBBADDATA.Error()
+	}
+
+	word := func(b []byte, i int) int {
+		if len(b) < i+2 {
+			return -1
+		}
+		return int(b[i])<<8 | int(b[i+1])
+	}
+	switch function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS, MODBUS_FC_READ_HOLDING_REGISTERS,
+		MODBUS_FC_READ_INPUT_REGISTERS, MODBUS_FC_WRITE_AND_READ_REGISTERS, MODBUS_FC_REPORT_SLAVE_ID:
+		// the byte count must match the data received
+		if len(rsp)-2 != int(rsp[1]) {
+			if b.debug {
+				fmt.Printf("Byte count %d not corresponding to the data received (%d)\n", rsp[1], len(rsp)-2)
+			}
+			return nil, EMBBADDATA.Error()
+		}
+	}
+	reqNb, rspNb := 1, 1
+	switch function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS:
+		reqNb = (word(pdu, 3) + 7) / 8
+		rspNb = int(rsp[1])
+	case MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_READ_INPUT_REGISTERS, MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		reqNb = word(pdu, 3)
+		rspNb = int(rsp[1]) / 2
+	case MODBUS_FC_WRITE_MULTIPLE_COILS, MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		reqNb = word(pdu, 3)
+		rspNb = word(rsp, 3)
+	case MODBUS_FC_REPORT_SLAVE_ID:
+		reqNb = int(rsp[1])
+		rspNb = len(rsp) - 2
+	}
+	if reqNb != rspNb {
+		if b.debug {
+			fmt.Printf("Received data not corresponding to the request (%d != %d)\n", rspNb, reqNb)
+		}
+		return nil, EMBBADDATA.Error()
+	}
+	return
+}
+
+func (b *modbusBackend) readBits(function int, addr int, nb int) (out []byte, err error) {
+	if nb > MODBUS_MAX_READ_BITS {
+		return nil, EMBMDATA.Error()
+	}
+	rsp, err := b.request([]byte{byte(function), byte(addr >> 8), byte(addr), byte(nb >> 8), byte(nb)})
+	if err != nil || rsp == nil {
+		return
+	}
+	out = make([]byte, nb)
+	for i := range out {
+		out[i] = (rsp[2+i/8] >> (i % 8)) & 1
+	}
+	return
+}
+
+func (b *modbusBackend) readRegisters(function int, addr int, nb int) (out []uint16, err error) {
+	if nb > MODBUS_MAX_READ_REGISTERS {
+		return nil, EMBMDATA.Error()
+	}
+	rsp, err := b.request([]byte{byte(function), byte(addr >> 8), byte(addr), byte(nb >> 8), byte(nb)})
+	if err != nil || rsp == nil {
+		return
+	}
+	return registersFromBytes(rsp[2:]), nil
+}
+
+func (b *modbusBackend) writeSingle(function int, addr int, value int) (err error) {
+	_, err = b.request([]byte{byte(function), byte(addr >> 8), byte(addr), byte(value >> 8), byte(value)})
+	return
+}
+
+func (b *modbusBackend) writeBits(addr int, data []byte) (err error) {
+	nb := len(data)
+	if nb > MODBUS_MAX_WRITE_BITS {
+		return EMBMDATA.Error()
+	}
+	packed := make([]byte, (nb+7)/8)
+	for i, v := range data {
+		if v != 0 {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	pdu := []byte{MODBUS_FC_WRITE_MULTIPLE_COILS, byte(addr >> 8), byte(addr), byte(nb >> 8), byte(nb), byte(len(packed))}
+	_, err = b.request(append(pdu, packed...))
+	return
+}
+
+func (b *modbusBackend) writeRegisters(addr int, data []uint16) (err error) {
+	nb := len(data)
+	if nb > MODBUS_MAX_WRITE_REGISTERS {
+		return EMBMDATA.Error()
+	}
+	pdu := []byte{MODBUS_FC_WRITE_MULTIPLE_REGISTERS, byte(addr >> 8), byte(addr), byte(nb >> 8), byte(nb), byte(2 * nb)}
+	for _, v := range data {
+		pdu = append(pdu, byte(v>>8), byte(v))
+	}
+	_, err = b.request(pdu)
+	return
+}
+
+func (b *modbusBackend) maskWriteRegister(addr int, andMask uint16, orMask uint16) (err error) {
+	_, err = b.request([]byte{MODBUS_FC_MASK_WRITE_REGISTER, byte(addr >> 8), byte(addr),
+		byte(andMask >> 8), byte(andMask), byte(orMask >> 8), byte(orMask)})
+	return
+}
+
+func (b *modbusBackend) writeAndReadRegisters(writeAddr int, src []uint16, readAddr int, readNb int) (dest []uint16, err error) {
+	writeNb := len(src)
+	if writeNb > MODBUS_MAX_WR_WRITE_REGISTERS || readNb > MODBUS_MAX_WR_READ_REGISTERS {
+		return nil, EMBMDATA.Error()
+	}
+	pdu := []byte{MODBUS_FC_WRITE_AND_READ_REGISTERS,
+		byte(readAddr >> 8), byte(readAddr), byte(readNb >> 8), byte(readNb),
+		byte(writeAddr >> 8), byte(writeAddr), byte(writeNb >> 8), byte(writeNb), byte(2 * writeNb)}
+	for _, v := range src {
+		pdu = append(pdu, byte(v>>8), byte(v))
+	}
+	rsp, err := b.request(pdu)
+	if err != nil || rsp == nil {
+		return
+	}
+	return registersFromBytes(rsp[2:]), nil
+}
+
+func (b *modbusBackend) reportSlaveId() (data []byte, err error) {
+	rsp, err := b.request([]byte{MODBUS_FC_REPORT_SLAVE_ID})
+	if err != nil || rsp == nil {
+		return
+	}
+	return rsp[2:], nil
+}
+
+// sendRaw send a PDU prefixed by the slave number, the header is built as a response basis like
+// modbus_send_raw_request_tid() does
+func (b *modbusBackend) sendRaw(raw []byte, tid int) (err error) {
+	if len(raw) < 2 || len(raw) > MODBUS_MAX_PDU_LENGTH+1 {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	var adu []byte
+	if b.framing.headerLength() == modbusTcpHeaderLength {
+		length := len(raw)
+		adu = []byte{byte(tid >> 8), byte(tid), 0, 0, byte(length >> 8), byte(length)}
+	}
+	return b.send(append(adu, raw...))
+}
+
+// reply serve req from the tables, see modbus_reply()
+func (b *modbusBackend) reply(req []byte, t *modbusTabs) (err error) {
+	offset := b.framing.headerLength()
+	if len(req) <= offset {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	slave := int(req[offset-1])
+	pdu := req[offset : len(req)-min(b.framing.checksumLength(), len(req)-offset-1)]
+	function := pdu[0]
+	word := func(i int) int {
+		if len(pdu) < i+2 {
+			return -1
+		}
+		return int(pdu[i])<<8 | int(pdu[i+1])
+	}
+
+	var rsp []byte
+	exception := ModbusException(0)
+	// malformed requests are followed by a flush of the line, see response_exception()
+	flush := false
+	bitsRange := func(start int, tab []byte, addr int, nb int) []byte {
+		a := addr - start
+		if a < 0 || a+nb > len(tab) {
+			exception = MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS
+			return nil
+		}
+		return tab[a : a+nb]
+	}
+	registersRange := func(start int, tab []uint16, addr int, nb int) []uint16 {
+		a := addr - start
+		if a < 0 || a+nb > len(tab) {
+			exception = MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS
+			return nil
+		}
+		return tab[a : a+nb]
+	}
+
+	addr, nb := word(1), word(3)
+	switch function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS:
+		start, tab := t.startBits, t.bits
+		if function == MODBUS_FC_READ_DISCRETE_INPUTS {
+			start, tab = t.startInputBits, t.inputBits
+		}
+		if nb < 1 || nb > MODBUS_MAX_READ_BITS {
+			exception, flush = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE, true
+			break
+		}
+		bits := bitsRange(start, tab, addr, nb)
+		if bits == nil {
+			break
+		}
+		packed := make([]byte, (nb+7)/8)
+		for i, v := range bits {
+			if v != 0 {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		rsp = append([]byte{function, byte(len(packed))}, packed...)
+	case MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_READ_INPUT_REGISTERS:
+		start, tab := t.startRegisters, t.registers
+		if function == MODBUS_FC_READ_INPUT_REGISTERS {
+			start, tab = t.startInputRegisters, t.inputRegisters
+		}
+		if nb < 1 || nb > MODBUS_MAX_READ_REGISTERS {
+			exception, flush = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE, true
+			break
+		}
+		regs := registersRange(start, tab, addr, nb)
+		if regs == nil {
+			break
+		}
+		rsp = []byte{function, byte(2 * nb)}
+		for _, v := range regs {
+			rsp = append(rsp, byte(v>>8), byte(v))
+		}
+	case MODBUS_FC_WRITE_SINGLE_COIL:
+		bits := bitsRange(t.startBits, t.bits, addr, 1)
+		if bits == nil {
+			break
+		}
+		if nb != 0xFF00 && nb != 0 {
+			exception = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+			break
+		}
+		bits[0] = 0
+		if nb != 0 {
+			bits[0] = 1
+		}
+		rsp = pdu[:5]
+	case MODBUS_FC_WRITE_SINGLE_REGISTER:
+		regs := registersRange(t.startRegisters, t.registers, addr, 1)
+		if regs == nil {
+			break
+		}
+		if nb < 0 {
+			exception, flush = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE, true
+			break
+		}
+		regs[0] = uint16(nb)
+		rsp = pdu[:5]
+	case MODBUS_FC_WRITE_MULTIPLE_COILS:
+		if nb < 1 || nb > MODBUS_MAX_WRITE_BITS || len(pdu) < 6 || int(pdu[5])*8 < nb || len(pdu) < 6+int(pdu[5]) {
+			exception, flush = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE, true
+			break
+		}
+		bits := bitsRange(t.startBits, t.bits, addr, nb)
+		if bits == nil {
+			break
+		}
+		for i := range bits {
+			bits[i] = (pdu[6+i/8] >> (i % 8)) & 1
+		}
+		rsp = pdu[:5]
+	case MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		if nb < 1 || nb > MODBUS_MAX_WRITE_REGISTERS || len(pdu) < 6 || int(pdu[5]) != 2*nb || len(pdu) < 6+2*nb {
+			exception, flush = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE, true
+			break
+		}
+		regs := registersRange(t.startRegisters, t.registers, addr, nb)
+		if regs == nil {
+			break
+		}
+		copy(regs, registersFromBytes(pdu[6:6+2*nb]))
+		rsp = pdu[:5]
+	case MODBUS_FC_READ_EXCEPTION_STATUS:
+		// not implemented by modbus_reply(), nothing is sent
+		return ErrorCode(syscall.ENOPROTOOPT).Error()
+	case MODBUS_FC_REPORT_SLAVE_ID:
+		id := "LMB" + LIBMODBUS_VERSION_STRING
+		rsp = append([]byte{function, byte(len(id) + 2), modbusReportSlaveId, 0xFF}, id...)
+	case MODBUS_FC_MASK_WRITE_REGISTER:
+		regs := registersRange(t.startRegisters, t.registers, addr, 1)
+		if regs == nil {
+			break
+		}
+		andMask, orMask := word(3), word(5)
+		if orMask < 0 {
+			exception = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+			break
+		}
+		regs[0] = (regs[0] & uint16(andMask)) | (uint16(orMask) &^ uint16(andMask))
+		rsp = pdu[:7]
+	case MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		writeAddr, writeNb := word(5), word(7)
+		if writeNb < 1 || writeNb > MODBUS_MAX_WR_WRITE_REGISTERS || nb < 1 || nb > MODBUS_MAX_WR_READ_REGISTERS ||
+			len(pdu) < 10 || int(pdu[9]) != 2*writeNb || len(pdu) < 10+2*writeNb {
+			exception, flush = MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE, true
+			break
+		}
+		wregs := registersRange(t.startRegisters, t.registers, writeAddr, writeNb)
+		regs := registersRange(t.startRegisters, t.registers, addr, nb)
+		if wregs == nil || regs == nil {
+			break
+		}
+		// write first then read
+		copy(wregs, registersFromBytes(pdu[10:10+2*writeNb]))
+		rsp = []byte{function, byte(2 * nb)}
+		for _, v := range regs {
+			rsp = append(rsp, byte(v>>8), byte(v))
+		}
+	default:
+		exception, flush = MODBUS_EXCEPTION_ILLEGAL_FUNCTION, true
+	}
+	if exception != 0 {
+		rsp = []byte{function | 0x80, byte(exception)}
+	}
+	if flush {
+		time.Sleep(b.responseTimeout)
+		b.flush()
+	}
+
+	// suppress any responses in serial mode when the request was a broadcast, excepted when quirk is enabled
+	if b.framing.serial() && slave == MODBUS_BROADCAST_ADDRESS && b.quirks&MODBUS_QUIRK_REPLY_TO_BROADCAST == 0 {
+		return
+	}
+	return b.send(append(b.responseHeader(req, len(rsp)), rsp...))
+}
+
+func (b *modbusBackend) replyException(req []byte, code uint) (err error) {
+	offset := b.framing.headerLength()
+	if len(req) <= offset || code >= uint(MODBUS_EXCEPTION_MAX) {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	rsp := []byte{req[offset] | 0x80, byte(code)}
+	return b.send(append(b.responseHeader(req, len(rsp)), rsp...))
+}
+
+// sendRts send a frame driving the RTS line around it, see _modbus_rtu_send()
+func (b *modbusBackend) sendRts(conn io.ReadWriteCloser, frame []byte) (err error) {
+	b.driveRts(b.rts == MODBUS_RTU_RTS_UP)
+	time.Sleep(b.rtsDelay)
+	_, err = conn.Write(frame)
+	time.Sleep(b.oneByteTime*time.Duration(len(frame)) + b.rtsDelay)
+	b.driveRts(b.rts != MODBUS_RTU_RTS_UP)
+	if err != nil {
+		return modbusErrno(err)
+	}
+	return
+}
+
+func (b *modbusBackend) driveRts(on bool) {
+	if b.debug {
+		fmt.Printf("Setting RTS to %t\n", on)
+	}
+	if b.setRts != nil {
+		b.setRts(on)
+		return
+	}
+	if fd, err := b.socket(); err == nil {
+		serialSetRts(fd, on)
+	}
+}
+
+func (b *modbusBackend) setSerialMode(mode int) (err error) {
+	if !b.framing.serial() || (mode != MODBUS_RTU_RS232 && mode != MODBUS_RTU_RS485) {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	if mode == b.serialMode {
+		return
+	}
+	fd, err := b.socket()
+	if err != nil {
+		return
+	}
+	if err = serialSetRs485Enabled(fd, mode == MODBUS_RTU_RS485); err != nil {
+		return serialSettingError(serialModeSetting(mode), err)
+	}
+	b.serialMode = mode
+	return
+}
+
+func (b *modbusBackend) setConfig(cfg RtuConfig) (err error) {
+	if !b.framing.serial() {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	b.config = &cfg
+	if conn, _ := b.current(); conn != nil {
+		return b.applyConfig()
+	}
+	return
+}
+
+// applyConfig apply the serial port options to the connected port
+func (b *modbusBackend) applyConfig() (err error) {
+	fd, err := b.socket()
+	if err != nil {
+		return
+	}
+	if err = serialApplyConfig(fd, b.config); err != nil {
+		return
+	}
+	if b.config.Rs485 != nil {
+		b.serialMode = MODBUS_RTU_RS485
+	}
+	return
+}
+
+// backendRts RTS function of a Go backend calling the custom callback of x, nil when there is no callback
+func backendRts(x *Modbus, cb SetRtsCallback) func(on bool) {
+	if cb == nil {
+		return nil
+	}
+	return func(on bool) {
+		v := 0
+		if on {
+			v = 1
+		}
+		cb(x, v)
+	}
+}
+
+func (b *modbusBackend) setRtsMode(mode int) (err error) {
+	if !b.framing.serial() || (mode != MODBUS_RTU_RTS_NONE && mode != MODBUS_RTU_RTS_UP && mode != MODBUS_RTU_RTS_DOWN) {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	b.rts = mode
+	// set the RTS line to its idle level
+	b.driveRts(mode != MODBUS_RTU_RTS_UP)
+	return
+}
+
+func (b *modbusBackend) setRtsDelay(delay time.Duration) (err error) {
+	if !b.framing.serial() || delay < 0 {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	b.rtsDelay = delay
+	return
+}
@@ -0,0 +1,86 @@
+package libmodbusgo
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// modbusFramer framing of the application data units handled by a Go backend
+//
+// The ADU exchanged with the backend is the header, the PDU and the checksum in binary form, the layout returned by
+// Receive() with libmodbus backends.
+type modbusFramer interface {
+	headerLength() int
+	checksumLength() int
+	// encode build the frame sent on the wire from the header and PDU of adu, the checksum is added here
+	encode(adu []byte) []byte
+	// read receive one frame and return its binary ADU, checksum verified
+	read(rd *modbusReader, indication bool) (adu []byte, err error)
+	// serial report whether the slave filtering and broadcast rules of serial lines apply
+	serial() bool
+}
+
+// modbusReader buffered reader enforcing the response, indication and byte timeouts on a connection
+type modbusReader struct {
+	conn     io.Reader
+	buf      *bufio.Reader
+	first    time.Duration // timeout of the first byte, 0 waits forever
+	next     time.Duration // timeout between two bytes, 0 uses deadline
+	deadline time.Time     // deadline of the whole message, zero waits forever
+	started  bool
+	debug    bool
+	datagram bool // each read of conn returns one message, a message never continues in the next read
+}
+
+// modbusBackend Modbus context implemented in Go, used for the transports libmodbus does not provide
+type modbusBackend struct {
+	framing modbusFramer
+	dial    func() (io.ReadWriteCloser, error)
+	// mu guards conn, rd and listener, close() being called from other goroutines to stop a blocking server
+	mu   sync.Mutex
+	conn io.ReadWriteCloser
+	rd   *modbusReader
+
+	listen   func() (net.Listener, error) // nil when the transport has no server side connection
+	listener net.Listener
+	bind     func() (io.ReadWriteCloser, error) // server side connection of the transports without accept
+
+	// datagram transports retransmit the requests and skip the confirmations of other transactions
+	datagram bool
+	retries  int
+
+	slave             int
+	debug             bool
+	errorRecovery     ModbusErrorRecoveryMode
+	quirks            ModbusQuirks
+	responseTimeout   time.Duration
+	byteTimeout       time.Duration
+	indicationTimeout time.Duration
+
+	// serial line control of the RTU contexts, see modbus_rtu_set_serial_mode() and modbus_rtu_set_rts()
+	serialMode  int
+	rts         int
+	rtsDelay    time.Duration
+	oneByteTime time.Duration
+	setRts      func(on bool) // nil toggles the RTS line of the serial port
+	config      *RtuConfig    // serial port options applied after each connection, see RtuSetConfig()
+
+	tid uint16
+	// the frame following a request addressed to another slave is its confirmation, see _modbus_rtu_receive()
+	confirmationToIgnore bool
+}
+
+// modbusTabs tables of a ModbusMapping as seen by a Go backend, the slices alias the mapping memory
+type modbusTabs struct {
+	startBits           int
+	bits                []byte
+	startInputBits      int
+	inputBits           []byte
+	startRegisters      int
+	registers           []uint16
+	startInputRegisters int
+	inputRegisters      []uint16
+}
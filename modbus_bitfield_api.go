@@ -0,0 +1,196 @@
+package libmodbusgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModbusBitFieldsNew create the decoder of a status register of size bits, 16 or 32
+//
+// A field out of the register, overlapping another one or with the name of another one returns EINVAL.
+func ModbusBitFieldsNew(size int, fields ...ModbusBitField) (f *ModbusBitFields, err error) {
+	if size != 16 && size != 32 {
+		return nil, marshalError("status register of %d bits", size)
+	}
+	var used uint32
+	names := map[string]bool{}
+	f = &ModbusBitFields{size: size, fields: make([]ModbusBitField, len(fields))}
+	for i, field := range fields {
+		if field.Bits == 0 {
+			field.Bits = 1
+		}
+		if field.Name == "" || names[field.Name] {
+			return nil, marshalError("field %d: empty or duplicate name %q", i, field.Name)
+		}
+		if field.Bit < 0 || field.Bits < 0 || field.Bit+field.Bits > size {
+			return nil, marshalError("field %s: bits %d-%d out of the %d bits register", field.Name, field.Bit,
+				field.Bit+field.Bits-1, size)
+		}
+		mask := field.mask()
+		if used&mask != 0 {
+			return nil, marshalError("field %s: bits %d-%d overlap another field", field.Name, field.Bit,
+				field.Bit+field.Bits-1)
+		}
+		for v := range field.Enum {
+			if v > mask>>field.Bit {
+				return nil, marshalError("field %s: enum value %d does not fit in %d bits", field.Name, v, field.Bits)
+			}
+		}
+		used |= mask
+		names[field.Name] = true
+		f.fields[i] = field
+	}
+	return
+}
+
+func (field *ModbusBitField) mask() uint32 {
+	return uint32(1<<field.Bits-1) << field.Bit
+}
+
+func (field *ModbusBitField) value(v uint32) (b ModbusBitValue) {
+	b = ModbusBitValue{Name: field.Name, Value: v & field.mask() >> field.Bit, Flag: field.Bits == 1}
+	b.Enum = field.Enum[b.Value]
+	return
+}
+
+// String the name of the value of an enum, the value of a number, or the name of a flag if set
+func (b ModbusBitValue) String() string {
+	switch {
+	case b.Enum != "":
+		return b.Name + "=" + b.Enum
+	case !b.Flag:
+		return fmt.Sprintf("%s=%d", b.Name, b.Value)
+	case b.Value != 0:
+		return b.Name
+	}
+	return "!" + b.Name
+}
+
+// Size number of bits of the register
+func (f *ModbusBitFields) Size() int {
+	return f.size
+}
+
+// Decode values of the fields of a status register, in the order of the fields
+//
+// The registers of 32 bits are decoded from their registers with Decode[uint32]().
+func (f *ModbusBitFields) Decode(v uint32) (values []ModbusBitValue) {
+	values = make([]ModbusBitValue, len(f.fields))
+	for i := range f.fields {
+		values[i] = f.fields[i].value(v)
+	}
+	return
+}
+
+// Flags names of the flags set in a status register
+func (f *ModbusBitFields) Flags(v uint32) (names []string) {
+	for i := range f.fields {
+		if field := &f.fields[i]; field.Bits == 1 && v&field.mask() != 0 {
+			names = append(names, field.Name)
+		}
+	}
+	return
+}
+
+// Get value of a field of a status register, false if the field does not exist
+func (f *ModbusBitFields) Get(v uint32, name string) (b ModbusBitValue, ok bool) {
+	for i := range f.fields {
+		if f.fields[i].Name == name {
+			return f.fields[i].value(v), true
+		}
+	}
+	return
+}
+
+// Set store a field in a status register, value being a number, a bool for a flag or the name of an enum value
+func (f *ModbusBitFields) Set(v uint32, name string, value any) (r uint32, err error) {
+	var field *ModbusBitField
+	for i := range f.fields {
+		if f.fields[i].Name == name {
+			field = &f.fields[i]
+		}
+	}
+	if field == nil {
+		return v, marshalError("unknown field %s", name)
+	}
+	var x uint32
+	switch value := value.(type) {
+	case bool:
+		if value {
+			x = 1
+		}
+	case int:
+		if value < 0 {
+			return v, marshalError("field %s: negative value %d", name, value)
+		}
+		if uint64(value) > uint64(field.mask()>>field.Bit) {
+			return v, marshalError("field %s: %v does not fit in %d bits", name, value, field.Bits)
+		}
+		x = uint32(value)
+	case uint32:
+		x = value
+	case string:
+		found := false
+		for k, s := range field.Enum {
+			if strings.EqualFold(s, value) {
+				x, found = k, true
+			}
+		}
+		if !found {
+			return v, marshalError("field %s: unknown value %s", name, value)
+		}
+	default:
+		return v, marshalError("field %s: unsupported value %T", name, value)
+	}
+	if x > field.mask()>>field.Bit {
+		return v, marshalError("field %s: %v does not fit in %d bits", name, value, field.Bits)
+	}
+	return v&^field.mask() | x<<field.Bit, nil
+}
+
+// Watch create the detection of the changes of the fields across polls
+//
+// The first poll is compared with a register of zero, the flags already set are raised.
+func (f *ModbusBitFields) Watch() *ModbusBitWatcher {
+	return &ModbusBitWatcher{fields: f}
+}
+
+// Update compare a poll of the register with the previous one and return the changes of the fields, in the order of
+// the fields
+func (w *ModbusBitWatcher) Update(v uint32) (edges []ModbusBitEdge) {
+	for i := range w.fields.fields {
+		field := &w.fields.fields[i]
+		if (w.last^v)&field.mask() == 0 {
+			continue
+		}
+		e := ModbusBitEdge{Name: field.Name, Old: field.value(w.last), New: field.value(v)}
+		if field.Bits == 1 {
+			e.Raised, e.Cleared = e.New.Value != 0, e.New.Value == 0
+		}
+		edges = append(edges, e)
+	}
+	w.last = v
+	return
+}
+
+// Last the previous poll of the register
+func (w *ModbusBitWatcher) Last() uint32 {
+	return w.last
+}
+
+// Reset forget the previous poll, the next one is compared with a register of zero
+func (w *ModbusBitWatcher) Reset() {
+	w.last = 0
+}
+
+// String the change of a field as "name raised", "name cleared" or "name old -> new"
+func (e ModbusBitEdge) String() string {
+	switch {
+	case e.Raised:
+		return e.Name + " raised"
+	case e.Cleared:
+		return e.Name + " cleared"
+	}
+	return fmt.Sprintf("%s %s -> %s", e.Name, strings.TrimPrefix(e.Old.String(), e.Name+"="),
+		strings.TrimPrefix(e.New.String(), e.Name+"="))
+}
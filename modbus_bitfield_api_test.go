@@ -0,0 +1,105 @@
+package libmodbusgo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func bitFieldsTest(t *testing.T) *ModbusBitFields {
+	f, err := ModbusBitFieldsNew(16,
+		ModbusBitField{Name: "fault", Bit: 0},
+		ModbusBitField{Name: "overtemp", Bit: 1},
+		ModbusBitField{Name: "mode", Bit: 4, Bits: 3, Enum: map[uint32]string{0: "off", 1: "standby", 2: "running"}},
+		ModbusBitField{Name: "level", Bit: 8, Bits: 4},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestModbusBitFields(t *testing.T) {
+	f := bitFieldsTest(t)
+	v := uint32(0x0521)
+	if got := fmt.Sprint(f.Decode(v)); got != "[fault !overtemp mode=running level=5]" {
+		t.Error(got)
+	}
+	if got := f.Flags(v); !slices.Equal(got, []string{"fault"}) {
+		t.Error(got)
+	}
+	if b, ok := f.Get(v, "mode"); !ok || b.Value != 2 || b.Enum != "running" {
+		t.Error(b)
+	}
+	if b, _ := f.Get(0x0071, "mode"); b.Value != 7 || b.Enum != "" || b.String() != "mode=7" {
+		t.Error(b)
+	}
+
+	r, err := f.Set(v, "mode", "Standby")
+	if err != nil || r != 0x0511 {
+		t.Errorf("%04X %v", r, err)
+	}
+	if r, err = f.Set(r, "overtemp", true); err != nil || r != 0x0513 {
+		t.Errorf("%04X %v", r, err)
+	}
+	if r, err = f.Set(r, "level", 15); err != nil || r != 0x0F13 {
+		t.Errorf("%04X %v", r, err)
+	}
+	for _, c := range []struct {
+		name  string
+		value any
+	}{{"level", 16}, {"level", -1}, {"mode", "boost"}, {"unknown", 1}, {"fault", 1.5}} {
+		if _, err = f.Set(0, c.name, c.value); err == nil {
+			t.Errorf("%s %v", c.name, c.value)
+		}
+	}
+	// an int beyond 32 bits is not truncated into the field
+	if big := 1; strconv.IntSize == 64 {
+		big <<= 32
+		if r, err = f.Set(0, "level", big); err == nil {
+			t.Errorf("%04X", r)
+		}
+	}
+
+	for _, fields := range [][]ModbusBitField{
+		{{Name: "a", Bit: 15, Bits: 2}},
+		{{Name: "a", Bit: 0, Bits: 4}, {Name: "b", Bit: 3}},
+		{{Name: "a", Bit: 0}, {Name: "a", Bit: 1}},
+		{{Name: "a", Bit: 0, Bits: 2, Enum: map[uint32]string{4: "x"}}},
+	} {
+		if _, err = ModbusBitFieldsNew(16, fields...); err == nil {
+			t.Errorf("%+v", fields)
+		}
+	}
+	if _, err = ModbusBitFieldsNew(32, ModbusBitField{Name: "all", Bits: 32}); err != nil {
+		t.Error(err)
+	}
+	if _, err = ModbusBitFieldsNew(8); err == nil {
+		t.Error("8 bits")
+	}
+}
+
+func TestModbusBitWatcher(t *testing.T) {
+	w := bitFieldsTest(t).Watch()
+	for _, c := range []struct {
+		v     uint32
+		edges string
+	}{
+		{0x0011, "[fault raised mode off -> standby]"},
+		{0x0011, "[]"},
+		{0x0022, "[fault cleared overtemp raised mode standby -> running]"},
+		{0x0302, "[mode running -> off level 0 -> 3]"},
+	} {
+		if got := fmt.Sprint(w.Update(c.v)); got != c.edges {
+			t.Errorf("%04X: %s", c.v, got)
+		}
+	}
+	if w.Last() != 0x0302 {
+		t.Error(w.Last())
+	}
+	w.Reset()
+	if got := fmt.Sprint(w.Update(0x0002)); got != "[overtemp raised]" {
+		t.Error(got)
+	}
+}
@@ -0,0 +1,41 @@
+package libmodbusgo
+
+// ModbusBitField field of bits of a status register
+type ModbusBitField struct {
+	Name string
+	Bit  int // lowest bit of the field
+	Bits int // number of bits, 1 when 0: the field is a flag
+	// Enum names of the values of the field, nil when the field is a flag or a number
+	Enum map[uint32]string
+}
+
+// ModbusBitFields decoder of a status register of 16 or 32 bits into named flags and enum values, see
+// ModbusBitFieldsNew()
+type ModbusBitFields struct {
+	size   int
+	fields []ModbusBitField
+}
+
+// ModbusBitValue value of a field of a status register
+type ModbusBitValue struct {
+	Name  string
+	Value uint32
+	Enum  string // name of the value, empty when the field is not an enum or the value has no name
+	Flag  bool   // field of one bit
+}
+
+// ModbusBitEdge change of a field of a status register between two polls
+type ModbusBitEdge struct {
+	Name    string
+	Old     ModbusBitValue
+	New     ModbusBitValue
+	Raised  bool // flag set
+	Cleared bool // flag cleared
+}
+
+// ModbusBitWatcher detection of the changes of the fields of a status register across successive polls, see
+// ModbusBitFields.Watch()
+type ModbusBitWatcher struct {
+	fields *ModbusBitFields
+	last   uint32
+}
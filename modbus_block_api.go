@@ -0,0 +1,185 @@
+package libmodbusgo
+
+import "slices"
+
+// End address following the last value of the block
+func (b ModbusBlock[T]) End() int {
+	return b.Start + len(b.Values)
+}
+
+// Get value at an address, false if the block does not hold it
+func (b ModbusBlock[T]) Get(addr int) (v T, ok bool) {
+	if addr < b.Start || addr >= b.End() {
+		return
+	}
+	return b.Values[addr-b.Start], true
+}
+
+// Clone copy of the block not sharing its values
+func (b ModbusBlock[T]) Clone() ModbusBlock[T] {
+	return ModbusBlock[T]{Start: b.Start, Values: slices.Clone(b.Values)}
+}
+
+// Slice copy of the values from start up to end excluded, an address range out of the block returns EINVAL
+func (b ModbusBlock[T]) Slice(start int, end int) (s ModbusBlock[T], err error) {
+	if start < b.Start || end > b.End() || start > end {
+		return s, marshalError("addresses %d-%d out of the block %d-%d", start, end, b.Start, b.End())
+	}
+	return ModbusBlock[T]{Start: start, Values: slices.Clone(b.Values[start-b.Start : end-b.Start])}, nil
+}
+
+// Merge union of two overlapping or adjacent blocks, the values of o replacing the ones of b where they overlap
+//
+// Two blocks separated by a gap return EINVAL, an empty block merges with any other.
+func (b ModbusBlock[T]) Merge(o ModbusBlock[T]) (m ModbusBlock[T], err error) {
+	switch {
+	case len(o.Values) == 0:
+		return b.Clone(), nil
+	case len(b.Values) == 0:
+		return o.Clone(), nil
+	case o.Start > b.End() || b.Start > o.End():
+		return m, marshalError("blocks %d-%d and %d-%d are not contiguous", b.Start, b.End(), o.Start, o.End())
+	}
+	m.Start = min(b.Start, o.Start)
+	m.Values = make([]T, max(b.End(), o.End())-m.Start)
+	copy(m.Values[b.Start-m.Start:], b.Values)
+	copy(m.Values[o.Start-m.Start:], o.Values)
+	return
+}
+
+// Diff runs of consecutive values of o differing from b, the addresses of o out of b being changes
+//
+// Writing the changes to a device holding b makes it hold o over the addresses of o.
+func (b ModbusBlock[T]) Diff(o ModbusBlock[T]) (changes []ModbusBlock[T]) {
+	for i, v := range o.Values {
+		addr := o.Start + i
+		if old, ok := b.Get(addr); ok && old == v {
+			continue
+		}
+		if n := len(changes); n > 0 && changes[n-1].End() == addr {
+			changes[n-1].Values = append(changes[n-1].Values, v)
+			continue
+		}
+		changes = append(changes, ModbusBlock[T]{Start: addr, Values: []T{v}})
+	}
+	return
+}
+
+// ReadRegisterBlock read holding registers into a block, see ReadRegisters()
+func (x *Modbus) ReadRegisterBlock(addr int, nb int) (b RegisterBlock, err error) {
+	values, err := x.ReadRegisters(addr, nb)
+	if err != nil {
+		return
+	}
+	return RegisterBlock{Start: addr, Values: values}, nil
+}
+
+// ReadInputRegisterBlock read input registers into a block, see ReadInputRegisters()
+func (x *Modbus) ReadInputRegisterBlock(addr int, nb int) (b RegisterBlock, err error) {
+	values, err := x.ReadInputRegisters(addr, nb)
+	if err != nil {
+		return
+	}
+	return RegisterBlock{Start: addr, Values: values}, nil
+}
+
+// ReadBitBlock read coils into a block, see ReadBits()
+func (x *Modbus) ReadBitBlock(addr int, nb int) (b CoilBlock, err error) {
+	values, err := x.ReadBits(addr, nb)
+	if err != nil {
+		return
+	}
+	return CoilBlock{Start: addr, Values: values}, nil
+}
+
+// ReadInputBitBlock read discrete inputs into a block, see ReadInputBits()
+func (x *Modbus) ReadInputBitBlock(addr int, nb int) (b CoilBlock, err error) {
+	values, err := x.ReadInputBits(addr, nb)
+	if err != nil {
+		return
+	}
+	return CoilBlock{Start: addr, Values: values}, nil
+}
+
+// WriteRegisterBlock write a block to holding registers, see WriteRegisters()
+func (x *Modbus) WriteRegisterBlock(b RegisterBlock) (err error) {
+	return x.WriteRegisters(b.Start, b.Values)
+}
+
+// WriteBitBlock write a block to coils, see WriteBits()
+func (x *Modbus) WriteBitBlock(b CoilBlock) (err error) {
+	return x.WriteBits(b.Start, b.Values)
+}
+
+// checkBlock check a block of addresses of a table of the mapping, bits telling whether the table is a bit table
+func (mm *ModbusMapping) checkBlock(t ModbusTable, bits bool, addr int, nb int) (err error) {
+	switch t {
+	case MODBUS_TABLE_BITS, MODBUS_TABLE_INPUT_BITS:
+		if !bits {
+			return marshalError("%s do not hold registers", t)
+		}
+	case MODBUS_TABLE_REGISTERS, MODBUS_TABLE_INPUT_REGISTERS:
+		if bits {
+			return marshalError("%s do not hold bits", t)
+		}
+	default:
+		return marshalError("unknown table %d", t)
+	}
+	start, n := mm.tableRange(t)
+	if nb < 0 || addr < start || addr+nb > start+n {
+		return EMBXILADD.Error()
+	}
+	return
+}
+
+// RegisterBlock copy registers of the mapping into a block, t being MODBUS_TABLE_REGISTERS or
+// MODBUS_TABLE_INPUT_REGISTERS
+//
+// The addresses out of the mapping return EMBXILADD.
+func (mm *ModbusMapping) RegisterBlock(t ModbusTable, addr int, nb int) (b RegisterBlock, err error) {
+	if err = mm.checkBlock(t, false, addr, nb); err != nil {
+		return
+	}
+	b = RegisterBlock{Start: addr, Values: make([]uint16, nb)}
+	for i := range b.Values {
+		b.Values[i] = mm.getTab(t, addr+i)
+	}
+	return
+}
+
+// CoilBlock copy bits of the mapping into a block, t being MODBUS_TABLE_BITS or MODBUS_TABLE_INPUT_BITS, see
+// RegisterBlock()
+func (mm *ModbusMapping) CoilBlock(t ModbusTable, addr int, nb int) (b CoilBlock, err error) {
+	if err = mm.checkBlock(t, true, addr, nb); err != nil {
+		return
+	}
+	b = CoilBlock{Start: addr, Values: make([]byte, nb)}
+	for i := range b.Values {
+		b.Values[i] = byte(mm.getTab(t, addr+i))
+	}
+	return
+}
+
+// LoadRegisterBlock copy a block into registers of the mapping, see RegisterBlock()
+//
+// A block out of the mapping returns EMBXILADD and leaves the mapping untouched.
+func (mm *ModbusMapping) LoadRegisterBlock(t ModbusTable, b RegisterBlock) (err error) {
+	if err = mm.checkBlock(t, false, b.Start, len(b.Values)); err != nil {
+		return
+	}
+	for i, v := range b.Values {
+		mm.setTab(t, b.Start+i, v)
+	}
+	return
+}
+
+// LoadCoilBlock copy a block into bits of the mapping, the non-zero values being 1, see LoadRegisterBlock()
+func (mm *ModbusMapping) LoadCoilBlock(t ModbusTable, b CoilBlock) (err error) {
+	if err = mm.checkBlock(t, true, b.Start, len(b.Values)); err != nil {
+		return
+	}
+	for i, v := range b.Values {
+		mm.setTab(t, b.Start+i, uint16(v))
+	}
+	return
+}
@@ -0,0 +1,163 @@
+package libmodbusgo
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestModbusBlock(t *testing.T) {
+	b := RegisterBlock{Start: 100, Values: []uint16{1, 2, 3, 4}}
+	if b.End() != 104 {
+		t.Error(b.End())
+	}
+	if v, ok := b.Get(102); !ok || v != 3 {
+		t.Error(v)
+	}
+	if _, ok := b.Get(104); ok {
+		t.Error("out of the block")
+	}
+
+	s, err := b.Slice(101, 103)
+	if err != nil || s.Start != 101 || !slices.Equal(s.Values, []uint16{2, 3}) {
+		t.Errorf("%+v %v", s, err)
+	}
+	s.Values[0] = 9
+	if b.Values[1] != 2 {
+		t.Error("slice shares the values")
+	}
+	for _, r := range [][2]int{{99, 101}, {102, 105}, {103, 102}} {
+		if _, err = b.Slice(r[0], r[1]); err == nil {
+			t.Error(r)
+		}
+	}
+
+	for _, c := range []struct {
+		o    RegisterBlock
+		want string
+	}{
+		{RegisterBlock{Start: 102, Values: []uint16{7, 8, 9}}, "{100 [1 2 7 8 9]}"},
+		{RegisterBlock{Start: 98, Values: []uint16{5, 6}}, "{98 [5 6 1 2 3 4]}"},
+		{RegisterBlock{Start: 101, Values: []uint16{0}}, "{100 [1 0 3 4]}"},
+		{RegisterBlock{Start: 7}, "{100 [1 2 3 4]}"},
+	} {
+		m, err := b.Merge(c.o)
+		if err != nil || fmt.Sprint(m) != c.want {
+			t.Errorf("%+v: %v %v", c.o, m, err)
+		}
+	}
+	if _, err = b.Merge(RegisterBlock{Start: 105, Values: []uint16{1}}); err == nil {
+		t.Error("gap")
+	}
+	if m, _ := (RegisterBlock{}).Merge(b); fmt.Sprint(m) != "{100 [1 2 3 4]}" {
+		t.Error(m)
+	}
+
+	o := RegisterBlock{Start: 101, Values: []uint16{2, 0, 0, 5, 6}}
+	if d := b.Diff(o); fmt.Sprint(d) != "[{102 [0 0 5 6]}]" {
+		t.Error(d)
+	}
+	o = RegisterBlock{Start: 100, Values: []uint16{0, 2, 3, 0}}
+	if d := b.Diff(o); fmt.Sprint(d) != "[{100 [0]} {103 [0]}]" {
+		t.Error(d)
+	}
+	if d := b.Diff(b); len(d) != 0 {
+		t.Error(d)
+	}
+	c := CoilBlock{Start: 0, Values: []byte{1, 0, 1}}
+	if d := c.Diff(CoilBlock{Start: 1, Values: []byte{1, 1}}); fmt.Sprint(d) != "[{1 [1]}]" {
+		t.Error(d)
+	}
+}
+
+func TestModbusMapping_RegisterBlock(t *testing.T) {
+	// a gateway polling a device and serving its values from another mapping
+	device := ModbusMappingNewStartAddress(0, 8, 0, 0, 100, 10, 0, 4)
+	gateway := ModbusMappingNewStartAddress(0, 8, 0, 0, 100, 10, 0, 4)
+	if device == nil || gateway == nil {
+		t.FailNow()
+	}
+	defer device.Free()
+	defer gateway.Free()
+	for i := range 10 {
+		device.SetTabRegisters(100+i, uint16(i*i))
+	}
+	device.SetTabBits(3, 1)
+	device.SetTabInputRegisters(2, 42)
+
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, device)
+	defer server.close()
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+
+	regs, err := ctx.ReadRegisterBlock(100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coils, err := ctx.ReadBitBlock(0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := ctx.ReadInputRegisterBlock(0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ctx.ReadInputBitBlock(0, 1); err == nil {
+		t.Error("no discrete inputs")
+	}
+	if err = gateway.LoadRegisterBlock(MODBUS_TABLE_REGISTERS, regs); err != nil {
+		t.Fatal(err)
+	}
+	if err = gateway.LoadCoilBlock(MODBUS_TABLE_BITS, coils); err != nil {
+		t.Fatal(err)
+	}
+	if err = gateway.LoadRegisterBlock(MODBUS_TABLE_INPUT_REGISTERS, inputs); err != nil {
+		t.Fatal(err)
+	}
+	if gateway.GetTabRegisters(109) != 81 || gateway.GetTabBits(3) != 1 || gateway.GetTabInputRegisters(2) != 42 {
+		t.Error("gateway mapping")
+	}
+
+	// the changes made to the gateway are written back to the device
+	gateway.SetTabRegisters(104, 1000)
+	gateway.SetTabRegisters(105, 1001)
+	gateway.SetTabBits(0, 1)
+	dump, err := gateway.RegisterBlock(MODBUS_TABLE_REGISTERS, 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range regs.Diff(dump) {
+		if err = ctx.WriteRegisterBlock(change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bits, _ := gateway.CoilBlock(MODBUS_TABLE_BITS, 0, 8)
+	for _, change := range coils.Diff(bits) {
+		if err = ctx.WriteBitBlock(change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if device.GetTabRegisters(104) != 1000 || device.GetTabRegisters(105) != 1001 || device.GetTabBits(0) != 1 {
+		t.Error("device mapping")
+	}
+
+	if err = gateway.LoadRegisterBlock(MODBUS_TABLE_REGISTERS, RegisterBlock{Start: 108, Values: []uint16{1, 2, 3}}); conformanceCode(err) != EMBXILADD {
+		t.Error(err)
+	}
+	if gateway.GetTabRegisters(108) != 64 {
+		t.Error("partial load")
+	}
+	if _, err = gateway.RegisterBlock(MODBUS_TABLE_BITS, 0, 1); err == nil {
+		t.Error("registers of the coils")
+	}
+	if _, err = gateway.CoilBlock(MODBUS_TABLE_INPUT_BITS, 0, 1); conformanceCode(err) != EMBXILADD {
+		t.Error(err)
+	}
+}
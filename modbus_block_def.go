@@ -0,0 +1,13 @@
+package libmodbusgo
+
+// ModbusBlock consecutive values of a data table starting at Start
+type ModbusBlock[T uint16 | byte] struct {
+	Start  int
+	Values []T
+}
+
+// RegisterBlock consecutive registers, holding or input registers
+type RegisterBlock = ModbusBlock[uint16]
+
+// CoilBlock consecutive bits, coils or discrete inputs, one byte 0 or 1 per bit as returned by ReadBits()
+type CoilBlock = ModbusBlock[byte]
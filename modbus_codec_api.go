@@ -0,0 +1,190 @@
+package libmodbusgo
+
+import (
+	"math"
+	"strings"
+	"unsafe"
+)
+
+// permutation position in the value of each byte of the registers, 0 being the most significant, for a value of n
+// bytes
+func (o ModbusByteOrder) permutation(n int) (p []int, err error) {
+	order := strings.ToUpper(string(o))
+	if order == "" {
+		order = string(MODBUS_ORDER_ABCD)
+	}
+	p = make([]int, n)
+	if len(order) == 4 && n != 4 {
+		var wordSwap, byteSwap bool
+		switch ModbusByteOrder(order) {
+		case MODBUS_ORDER_ABCD:
+		case MODBUS_ORDER_CDAB:
+			wordSwap = true
+		case MODBUS_ORDER_BADC:
+			byteSwap = true
+		case MODBUS_ORDER_DCBA:
+			wordSwap, byteSwap = true, true
+		default:
+			return nil, marshalError("unknown order %s", o)
+		}
+		for i := range p {
+			w, b := i/2, i%2
+			if wordSwap {
+				w = n/2 - 1 - w
+			}
+			if byteSwap {
+				b = 1 - b
+			}
+			p[i] = 2*w + b
+		}
+		return
+	}
+	if len(order) != n {
+		return nil, marshalError("order %s does not apply to a %d bytes value", o, n)
+	}
+	seen := 0
+	for i := range order {
+		k := int(order[i]) - 'A'
+		if k < 0 || k >= n || seen&(1<<k) != 0 {
+			return nil, marshalError("unknown order %s", o)
+		}
+		seen |= 1 << k
+		p[i] = k
+	}
+	return
+}
+
+// Valid report whether the order is empty or a permutation of 4 or 8 bytes
+func (o ModbusByteOrder) Valid() bool {
+	if o == "" {
+		return true
+	}
+	if len(o) != 4 && len(o) != 8 {
+		return false
+	}
+	_, err := o.permutation(len(o))
+	return err == nil
+}
+
+// modbusGetRaw value of the registers, their bytes being placed at the positions p of the value
+func modbusGetRaw(regs []uint16, p []int) (v uint64) {
+	n := len(p)
+	for i, k := range p {
+		b := regs[i/2] >> 8
+		if i%2 == 1 {
+			b = regs[i/2] & 0xFF
+		}
+		v |= uint64(b) << (8 * (n - 1 - k))
+	}
+	return
+}
+
+// modbusSetRaw set the registers to a value, see modbusGetRaw()
+func modbusSetRaw(regs []uint16, p []int, v uint64) {
+	n := len(p)
+	for i, k := range p {
+		b := uint16(v>>(8*(n-1-k))) & 0xFF
+		if i%2 == 0 {
+			regs[i/2] = regs[i/2]&0x00FF | b<<8
+		} else {
+			regs[i/2] = regs[i/2]&0xFF00 | b
+		}
+	}
+}
+
+// modbusSizeOf number of registers of a numeric type
+func modbusSizeOf[T ModbusNumber]() int {
+	var v T
+	return int(unsafe.Sizeof(v)) / 2
+}
+
+// modbusIsFloat report whether a numeric type is a float
+func modbusIsFloat[T ModbusNumber]() bool {
+	one := 1
+	return T(one)/2 != 0
+}
+
+// Decode decode a number from the first registers of regs in the given byte order
+//
+// A 16 bits value uses 1 register, a 32 bits one 2 registers and a 64 bits one 4 registers. An empty order is
+// MODBUS_ORDER_ABCD. For example the registers 0x2000 0x47F1 are the float32 123456.0 in MODBUS_ORDER_CDAB, like
+// ModbusGetFloatCdab() reads them.
+func Decode[T ModbusNumber](regs []uint16, order ModbusByteOrder) (v T, err error) {
+	n := modbusSizeOf[T]()
+	if len(regs) < n {
+		return v, marshalError("%d registers needed by %T, got %d", n, v, len(regs))
+	}
+	p, err := order.permutation(2 * n)
+	if err != nil {
+		return
+	}
+	raw := modbusGetRaw(regs, p)
+	switch {
+	case modbusIsFloat[T]() && n == 2:
+		return T(math.Float32frombits(uint32(raw))), nil
+	case modbusIsFloat[T]():
+		return T(math.Float64frombits(raw)), nil
+	case n == 1:
+		// the conversion of an unsigned value of the same size keeps the sign
+		return T(uint16(raw)), nil
+	case n == 2:
+		return T(uint32(raw)), nil
+	}
+	return T(raw), nil
+}
+
+// Encode encode a number into registers in the given byte order, see Decode()
+func Encode[T ModbusNumber](v T, order ModbusByteOrder) (regs []uint16, err error) {
+	n := modbusSizeOf[T]()
+	p, err := order.permutation(2 * n)
+	if err != nil {
+		return
+	}
+	var raw uint64
+	switch {
+	case modbusIsFloat[T]() && n == 2:
+		raw = uint64(math.Float32bits(float32(v)))
+	case modbusIsFloat[T]():
+		raw = math.Float64bits(float64(v))
+	default:
+		// the sign extension of the negative values is dropped by modbusSetRaw
+		raw = uint64(v)
+	}
+	regs = make([]uint16, n)
+	modbusSetRaw(regs, p, raw)
+	return
+}
+
+// DecodeSlice decode consecutive numbers from the registers, see Decode()
+func DecodeSlice[T ModbusNumber](regs []uint16, order ModbusByteOrder) (values []T, err error) {
+	n := modbusSizeOf[T]()
+	if len(regs)%n != 0 {
+		return nil, marshalError("%d registers are not a multiple of %d", len(regs), n)
+	}
+	values = make([]T, len(regs)/n)
+	for i := range values {
+		if values[i], err = Decode[T](regs[i*n:], order); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// EncodeSlice encode consecutive numbers into registers, see Encode()
+func EncodeSlice[T ModbusNumber](values []T, order ModbusByteOrder) (regs []uint16, err error) {
+	for _, v := range values {
+		r, err := Encode(v, order)
+		if err != nil {
+			return nil, err
+		}
+		regs = append(regs, r...)
+	}
+	return
+}
+
+func (o ModbusByteOrder) String() string {
+	if o == "" {
+		return string(MODBUS_ORDER_ABCD)
+	}
+	return string(o)
+}
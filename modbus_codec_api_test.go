@@ -0,0 +1,119 @@
+package libmodbusgo
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	regs := []uint16{0x0102, 0x0304, 0x0506, 0x0708}
+	for _, c := range []struct {
+		order ModbusByteOrder
+		u16   uint16
+		u32   uint32
+		u64   uint64
+	}{
+		{"", 0x0102, 0x01020304, 0x0102030405060708},
+		{MODBUS_ORDER_ABCD, 0x0102, 0x01020304, 0x0102030405060708},
+		{MODBUS_ORDER_CDAB, 0x0102, 0x03040102, 0x0708050603040102},
+		{MODBUS_ORDER_BADC, 0x0201, 0x02010403, 0x0201040306050807},
+		{MODBUS_ORDER_DCBA, 0x0201, 0x04030201, 0x0807060504030201},
+		{"cdab", 0x0102, 0x03040102, 0x0708050603040102},
+	} {
+		u16, err := Decode[uint16](regs, c.order)
+		u32, _ := Decode[uint32](regs, c.order)
+		u64, _ := Decode[uint64](regs, c.order)
+		if err != nil || u16 != c.u16 || u32 != c.u32 || u64 != c.u64 {
+			t.Errorf("%s: %04X %08X %016X %v", c.order, u16, u32, u64, err)
+		}
+		for _, r := range [][]uint16{must(Encode(u16, c.order)), must(Encode(u32, c.order)), must(Encode(u64, c.order))} {
+			if !slices.Equal(r, regs[:len(r)]) {
+				t.Errorf("%s: encoded % X", c.order, r)
+			}
+		}
+	}
+	for order, want := range map[ModbusByteOrder]uint64{
+		MODBUS_ORDER_ABCDEFGH: 0x0102030405060708,
+		MODBUS_ORDER_GHEFCDAB: 0x0708050603040102,
+		MODBUS_ORDER_BADCFEHG: 0x0201040306050807,
+		MODBUS_ORDER_HGFEDCBA: 0x0807060504030201,
+		MODBUS_ORDER_CDABGHEF: 0x0304010207080506,
+		MODBUS_ORDER_EFGHABCD: 0x0506070801020304,
+		"FEHGBADC":            0x0605080702010403,
+	} {
+		if u64, err := Decode[uint64](regs, order); err != nil || u64 != want {
+			t.Errorf("%s: %016X %v", order, u64, err)
+		}
+		if r, err := Encode(want, order); err != nil || !slices.Equal(r, regs) {
+			t.Errorf("%s: encoded % X %v", order, r, err)
+		}
+	}
+
+	// the float orders read like libmodbus
+	floats := []uint16{0x0020, 0xF147}
+	for order, get := range map[ModbusByteOrder]func([]uint16) float32{
+		MODBUS_ORDER_ABCD: ModbusGetFloatAbcd,
+		MODBUS_ORDER_CDAB: ModbusGetFloatCdab,
+		MODBUS_ORDER_BADC: ModbusGetFloatBadc,
+		MODBUS_ORDER_DCBA: ModbusGetFloatDcba,
+	} {
+		if f, err := Decode[float32](floats, order); err != nil || math.Float32bits(f) != math.Float32bits(get(floats)) {
+			t.Errorf("%s: %v, libmodbus %v", order, f, get(floats))
+		}
+	}
+	if f, _ := Decode[float32]([]uint16{0x2000, 0x47F1}, MODBUS_ORDER_CDAB); f != 123456 {
+		t.Errorf("%v", f)
+	}
+	r, _ := Encode(-1.5, MODBUS_ORDER_HGFEDCBA)
+	if f, _ := Decode[float64](r, MODBUS_ORDER_HGFEDCBA); f != -1.5 || r[3] != 0xF8BF {
+		t.Errorf("% X", r)
+	}
+
+	if i, _ := Decode[int16]([]uint16{0xFFFE}, ""); i != -2 {
+		t.Errorf("%d", i)
+	}
+	if i, _ := Decode[int32]([]uint16{0xFFFF, 0xFFFE}, MODBUS_ORDER_CDAB); i != -65537 {
+		t.Errorf("%d", i)
+	}
+	if i, _ := Decode[int64]([]uint16{0xFFFF, 0xFFFF, 0xFFFF, 0xFFFF}, ""); i != -1 {
+		t.Errorf("%d", i)
+	}
+	type level int32
+	if l, _ := Decode[level]([]uint16{0xFFFF, 0xFFFF}, ""); l != -1 {
+		t.Errorf("%d", l)
+	}
+	if r, _ := Encode(int16(-2), MODBUS_ORDER_BADC); r[0] != 0xFEFF {
+		t.Errorf("% X", r)
+	}
+
+	values, err := DecodeSlice[int32]([]uint16{0, 1, 0xFFFF, 0xFFFF}, "")
+	if err != nil || !slices.Equal(values, []int32{1, -1}) {
+		t.Errorf("%v %v", values, err)
+	}
+	if r, err := EncodeSlice([]int32{1, -1}, MODBUS_ORDER_CDAB); err != nil || !slices.Equal(r, []uint16{1, 0, 0xFFFF, 0xFFFF}) {
+		t.Errorf("% X %v", r, err)
+	}
+
+	if _, err = Decode[uint32]([]uint16{1}, ""); err == nil {
+		t.Error("short registers")
+	}
+	if _, err = DecodeSlice[uint32]([]uint16{1, 2, 3}, ""); err == nil {
+		t.Error("partial value")
+	}
+	for _, order := range []ModbusByteOrder{"ABCC", "ABCDEFGH", "ACBD", "XYZW"} {
+		if _, err = Decode[uint32](regs, order); err == nil && order != "ACBD" {
+			t.Errorf("%s accepted", order)
+		}
+	}
+	if !MODBUS_ORDER_GHEFCDAB.Valid() || ModbusByteOrder("ABC").Valid() || ModbusByteOrder("AABB").Valid() {
+		t.Error("Valid")
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
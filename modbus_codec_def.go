@@ -0,0 +1,28 @@
+package libmodbusgo
+
+// ModbusByteOrder order of the bytes of a value spread over registers
+//
+// The letters name the bytes of the value from the most significant (A) and are listed in their order in the
+// registers, the first register holding the first two letters. The four orders of 4 letters apply to the values of
+// any size: ABCD keeps the value big endian, CDAB swaps the registers, BADC swaps the bytes of each register and DCBA
+// does both. An order of 8 letters, eg. GHEFCDAB, gives any permutation of a 64 bits value.
+type ModbusByteOrder string
+
+const (
+	MODBUS_ORDER_ABCD ModbusByteOrder = "ABCD" // big endian, the Modbus order
+	MODBUS_ORDER_CDAB ModbusByteOrder = "CDAB" // registers swapped, little endian words of big endian bytes
+	MODBUS_ORDER_BADC ModbusByteOrder = "BADC" // bytes swapped in each register
+	MODBUS_ORDER_DCBA ModbusByteOrder = "DCBA" // little endian
+
+	MODBUS_ORDER_ABCDEFGH ModbusByteOrder = "ABCDEFGH" // 64 bits big endian
+	MODBUS_ORDER_GHEFCDAB ModbusByteOrder = "GHEFCDAB" // 64 bits registers swapped
+	MODBUS_ORDER_BADCFEHG ModbusByteOrder = "BADCFEHG" // 64 bits bytes swapped in each register
+	MODBUS_ORDER_HGFEDCBA ModbusByteOrder = "HGFEDCBA" // 64 bits little endian
+	MODBUS_ORDER_CDABGHEF ModbusByteOrder = "CDABGHEF" // 64 bits registers swapped in each 32 bits half
+	MODBUS_ORDER_EFGHABCD ModbusByteOrder = "EFGHABCD" // 64 bits 32 bits halves swapped
+)
+
+// ModbusNumber numeric types encoded in registers by Encode() and decoded by Decode()
+type ModbusNumber interface {
+	~int16 | ~uint16 | ~int32 | ~uint32 | ~int64 | ~uint64 | ~float32 | ~float64
+}
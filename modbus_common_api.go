@@ -0,0 +1,44 @@
+package libmodbusgo
+
+// checksumLength length of the checksum trailing the messages of the backend
+func (x *Modbus) checksumLength() int {
+	if x.be != nil {
+		return x.be.framing.checksumLength()
+	}
+	if x.GetHeaderLength() == modbusRtuHeaderLength {
+		return modbusRtuChecksumLength
+	}
+	return 0
+}
+
+func ModbusGetHighByte[T int16 | uint16 | int32 | uint32 | int64 | uint64](data T) byte {
+	return byte((uint64(data) >> 8) & 0xFF)
+}
+
+func ModbusGetLowByte[T int16 | uint16 | int32 | uint32 | int64 | uint64](data T) byte {
+	return byte(uint64(data) & 0xFF)
+}
+
+func ModbusGetInt64FromInt16(tab []int16) int64 {
+	return int64(tab[0])<<48 | int64(tab[1])<<32 | int64(tab[2])<<16 | int64(tab[3])
+}
+
+func ModbusGetInt32FromInt16(tab []int16) int32 {
+	return int32(tab[0])<<16 | int32(tab[1])
+}
+
+func ModbusGetInt16FromInt8(tab []int8) int16 {
+	return int16(tab[0])<<8 | int16(tab[1])
+}
+
+func ModbusSetInt16ToInt8(value int16) []int8 {
+	return []int8{int8(value >> 8), int8(value)}
+}
+
+func ModbusSetInt32ToInt16(value int32) []int16 {
+	return []int16{int16(value >> 16), int16(value)}
+}
+
+func ModbusSetInt64ToInt16(value int64) []int16 {
+	return []int16{int16(value >> 48), int16(value >> 32), int16(value >> 16), int16(value)}
+}
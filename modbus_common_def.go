@@ -0,0 +1,66 @@
+package libmodbusgo
+
+import (
+	"fmt"
+)
+
+// ModbusException Protocol exceptions
+type ModbusException int
+
+func (e ModbusException) String() string {
+	switch e {
+	case MODBUS_EXCEPTION_ILLEGAL_FUNCTION:
+		return "illegal function"
+	case MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS:
+		return "illegal data address"
+	case MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE:
+		return "illegal data value"
+	case MODBUS_EXCEPTION_SLAVE_OR_SERVER_FAILURE:
+		return "slave or server failure"
+	case MODBUS_EXCEPTION_ACKNOWLEDGE:
+		return "acknowledge"
+	case MODBUS_EXCEPTION_SLAVE_OR_SERVER_BUSY:
+		return "slave or server busy"
+	case MODBUS_EXCEPTION_NEGATIVE_ACKNOWLEDGE:
+		return "negative acknowledge"
+	case MODBUS_EXCEPTION_MEMORY_PARITY:
+		return "memory parity error"
+	case MODBUS_EXCEPTION_GATEWAY_PATH:
+		return "gateway path unavailable"
+	case MODBUS_EXCEPTION_GATEWAY_TARGET:
+		return "target device failed to respond"
+	}
+	return fmt.Sprintf("exception %d", int(e))
+}
+
+// Error make protocol exceptions usable as errors, a server handler returns one to reply with it
+func (e ModbusException) Error() string {
+	return e.String()
+}
+
+type ErrorCode int
+
+type SetRtsCallback func(ctx *Modbus, on int)
+
+type ModbusErrorRecoveryMode byte
+
+type ModbusQuirks byte
+
+type Error struct {
+	code    ErrorCode
+	message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("code: %d message: %s", e.code, e.message)
+}
+
+func (e *Error) Code() ErrorCode {
+	return e.code
+}
+
+type ReportSlaveId struct {
+	SlaveId            byte
+	RunIndicatorStatus byte
+	AdditionalData     []byte
+}
@@ -0,0 +1,433 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// conformanceServer serve a mapping until stopped and record the requests it receives
+type conformanceServer struct {
+	ctx      *Modbus
+	stop     chan struct{}
+	done     sync.WaitGroup
+	mu       sync.Mutex
+	requests []string
+}
+
+func conformanceServe(ctx *Modbus, slave int, mm *ModbusMapping) *conformanceServer {
+	s := &conformanceServer{ctx: ctx, stop: make(chan struct{})}
+	ctx.SetSlave(slave)
+	ctx.SetIndicationTimeout(50 * time.Millisecond)
+	// the delay before the exception responses to malformed requests
+	ctx.SetResponseTimeout(50 * time.Millisecond)
+	hdr := ctx.GetHeaderLength()
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		for {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			// the errors are timeouts or the end of the connection, the loop runs until stopped
+			req, err := ctx.Receive()
+			if err != nil || len(req) == 0 {
+				continue
+			}
+			// the transaction identifiers may differ
+			s.mu.Lock()
+			s.requests = append(s.requests, fmt.Sprintf("% X", req[hdr-1:]))
+			s.mu.Unlock()
+			ctx.Reply(req, mm)
+		}
+	}()
+	return s
+}
+
+func (s *conformanceServer) close() []string {
+	close(s.stop)
+	s.done.Wait()
+	s.ctx.Free()
+	return s.requests
+}
+
+func conformanceCode(err error) ErrorCode {
+	var merr *Error
+	if errors.As(err, &merr) {
+		return merr.Code()
+	}
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+func conformanceMapping(t *testing.T) *ModbusMapping {
+	mm := ModbusMappingNew(20, 10, 10, 5)
+	if mm == nil {
+		t.FailNow()
+	}
+	mm.SetTabInputBits(2, 1)
+	mm.SetTabInputBits(4, 1)
+	mm.SetTabInputRegisters(0, 0x1111)
+	mm.SetTabInputRegisters(1, 0x2222)
+	return mm
+}
+
+// conformanceScenario run the same calls on a client and return their transcript
+func conformanceScenario(t *testing.T, c *Modbus, slave int, serial bool) (transcript []string) {
+	logf := func(format string, a ...any) {
+		transcript = append(transcript, fmt.Sprintf(format, a...))
+	}
+	c.SetResponseTimeout(200 * time.Millisecond)
+	c.SetSlave(slave)
+
+	err := c.WriteBits(0, []byte{1, 0, 1, 1, 0, 0, 1, 0, 1})
+	logf("write bits %d", conformanceCode(err))
+	bits, err := c.ReadBits(0, 9)
+	logf("read bits %v %d", bits, conformanceCode(err))
+	err = c.WriteBit(12, 1)
+	logf("write bit %d", conformanceCode(err))
+	bits, err = c.ReadBits(11, 3)
+	logf("read bits %v %d", bits, conformanceCode(err))
+	bits, err = c.ReadInputBits(1, 5)
+	logf("read input bits %v %d", bits, conformanceCode(err))
+
+	err = c.WriteRegisters(1, []uint16{0x1234, 0xABCD, 7})
+	logf("write registers %d", conformanceCode(err))
+	regs, err := c.ReadRegisters(0, 4)
+	logf("read registers %X %d", regs, conformanceCode(err))
+	if err != nil || regs[1] != 0x1234 || regs[3] != 7 {
+		t.Error("registers", regs, err)
+	}
+	err = c.WriteRegister(5, 0xBEEF)
+	logf("write register %d", conformanceCode(err))
+	err = c.MaskWriteRegister(5, 0xF0F0, 0x0005)
+	logf("mask write register %d", conformanceCode(err))
+	regs, err = c.WriteAndReadRegisters(6, []uint16{1, 2}, 5, 3)
+	logf("write and read registers %X %d", regs, conformanceCode(err))
+	if err != nil || regs[0] != 0xB0E5 || regs[2] != 2 {
+		t.Error("mask write and write and read registers", regs, err)
+	}
+	regs, err = c.ReadInputRegisters(0, 2)
+	logf("read input registers %X %d", regs, conformanceCode(err))
+
+	_, err = c.ReadRegisters(8, 5)
+	logf("illegal data address %d", conformanceCode(err))
+	if conformanceCode(err) != EMBXILADD {
+		t.Error("expected illegal data address, got", err)
+	}
+	_, err = c.ReadBits(0, MODBUS_MAX_READ_BITS+1)
+	logf("too many bits %d", conformanceCode(err))
+	err = c.WriteRegisters(0, make([]uint16, MODBUS_MAX_WRITE_REGISTERS+1))
+	logf("too many registers %d", conformanceCode(err))
+	if conformanceCode(err) != EMBMDATA {
+		t.Error("expected too many data, got", err)
+	}
+
+	id, err := c.ReportSlaveId()
+	if err == nil {
+		logf("report slave id %d %X %q", id.SlaveId, id.RunIndicatorStatus, id.AdditionalData)
+	} else {
+		logf("report slave id %d", conformanceCode(err))
+	}
+
+	// an unknown function code is answered with an exception
+	err = c.SendRawRequest([]byte{byte(slave), 0x42})
+	logf("raw request %d", conformanceCode(err))
+	rsp, err := c.ReceiveConfirmation()
+	if err == nil {
+		rsp = rsp[c.GetHeaderLength()-1:]
+	}
+	logf("raw confirmation % X %d", rsp, conformanceCode(err))
+	if conformanceCode(err) != 0 {
+		t.Error("expected an illegal function exception, got", err)
+	}
+
+	if serial {
+		c.SetSlave(slave + 1)
+		_, err = c.ReadRegisters(0, 1)
+		logf("other slave %d", conformanceCode(err))
+		if conformanceCode(err) != ErrorCode(syscall.ETIMEDOUT) {
+			t.Error("expected a timeout, got", err)
+		}
+		// a broadcast is served without response
+		c.SetSlave(MODBUS_BROADCAST_ADDRESS)
+		err = c.WriteRegister(9, 0x55)
+		logf("broadcast %d", conformanceCode(err))
+		c.SetSlave(slave)
+		regs, err = c.ReadRegisters(9, 1)
+		logf("read broadcast register %X %d", regs, conformanceCode(err))
+	}
+	return
+}
+
+// conformancePair client and server of different implementations sharing a transport
+type conformancePair struct {
+	name string
+	open func(t *testing.T, mm *ModbusMapping) (client *Modbus, server *conformanceServer)
+}
+
+func conformanceTcpPairs() []conformancePair {
+	return []conformancePair{
+		{"native-client/conn-server", func(t *testing.T, mm *ModbusMapping) (*Modbus, *conformanceServer) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			client := ModbusNewTcp("127.0.0.1", ln.Addr().(*net.TCPAddr).Port)
+			if client == nil {
+				t.FailNow()
+			}
+			if err = client.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return client, conformanceServe(ModbusNewConn(conn, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+		}},
+		{"conn-client/native-server", func(t *testing.T, mm *ModbusMapping) (*Modbus, *conformanceServer) {
+			// a port free a moment ago, released for the server to listen on
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			address := ln.Addr().String()
+			ln.Close()
+			server := ModbusNewTcp("127.0.0.1", ln.Addr().(*net.TCPAddr).Port)
+			if server == nil {
+				t.FailNow()
+			}
+			s, err := server.TcpListen(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// libmodbus leaves the listening socket open, the Go backend closes it on Free
+			if server.be == nil {
+				t.Cleanup(func() { syscall.Close(s) })
+			}
+			conn, err := net.Dial("tcp", address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = server.TcpAccept(); err != nil {
+				t.Fatal(err)
+			}
+			return ModbusNewConn(conn, MODBUS_FRAMING_TCP), conformanceServe(server, MODBUS_TCP_SLAVE, mm)
+		}},
+	}
+}
+
+func conformanceRtuPairs() []conformancePair {
+	return []conformancePair{
+		{"native-client/conn-server", func(t *testing.T, mm *ModbusMapping) (*Modbus, *conformanceServer) {
+			master, slave := openPty(t)
+			client := ModbusNewRtu(slave, 115200, 'N', 8, 1)
+			if client == nil {
+				t.FailNow()
+			}
+			if err := client.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			return client, conformanceServe(ModbusNewConn(master, MODBUS_FRAMING_RTU), 17, mm)
+		}},
+		{"conn-client/native-server", func(t *testing.T, mm *ModbusMapping) (*Modbus, *conformanceServer) {
+			master, slave := openPty(t)
+			server := ModbusNewRtu(slave, 115200, 'N', 8, 1)
+			if server == nil {
+				t.FailNow()
+			}
+			if err := server.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			return ModbusNewConn(master, MODBUS_FRAMING_RTU), conformanceServe(server, 17, mm)
+		}},
+	}
+}
+
+// TestModbusConformance run a scenario between the native backend of the build, libmodbus or the pure Go one, and
+// the Go protocol engine in both roles; the results and the requests on the wire must be the same.
+func TestModbusConformance(t *testing.T) {
+	for _, suite := range []struct {
+		name   string
+		slave  int
+		serial bool
+		pairs  []conformancePair
+	}{
+		{"tcp", MODBUS_TCP_SLAVE, false, conformanceTcpPairs()},
+		{"rtu", 17, true, conformanceRtuPairs()},
+	} {
+		t.Run(suite.name, func(t *testing.T) {
+			var transcripts, requests [][]string
+			for _, pair := range suite.pairs {
+				t.Run(pair.name, func(t *testing.T) {
+					mm := conformanceMapping(t)
+					defer mm.Free()
+					client, server := pair.open(t, mm)
+					transcripts = append(transcripts, conformanceScenario(t, client, suite.slave, suite.serial))
+					client.Free()
+					requests = append(requests, server.close())
+				})
+			}
+			for i := 1; i < len(transcripts); i++ {
+				if !slices.Equal(transcripts[0], transcripts[i]) {
+					t.Errorf("transcripts differ\n%s: %q\n%s: %q", suite.pairs[0].name, transcripts[0], suite.pairs[i].name, transcripts[i])
+				}
+				if !slices.Equal(requests[0], requests[i]) {
+					t.Errorf("requests differ\n%s: %q\n%s: %q", suite.pairs[0].name, requests[0], suite.pairs[i].name, requests[i])
+				}
+			}
+		})
+	}
+}
+
+// TestModbusConformance_Data check the data helpers against the values of libmodbus 3.1.10
+func TestModbusConformance_Data(t *testing.T) {
+	dest := make([]uint16, 2)
+	for _, c := range []struct {
+		set  func(float32, []uint16)
+		get  func([]uint16) float32
+		regs [2]uint16
+		bits uint32
+	}{
+		{ModbusSetFloat, ModbusGetFloat, [2]uint16{0xE979, 0x42F6}, 0x56781234},
+		{ModbusSetFloatAbcd, ModbusGetFloatAbcd, [2]uint16{0xF642, 0x79E9}, 0x12345678},
+		{ModbusSetFloatDcba, ModbusGetFloatDcba, [2]uint16{0xE979, 0x42F6}, 0x78563412},
+		{ModbusSetFloatBadc, ModbusGetFloatBadc, [2]uint16{0x42F6, 0xE979}, 0x34127856},
+		{ModbusSetFloatCdab, ModbusGetFloatCdab, [2]uint16{0x79E9, 0xF642}, 0x56781234},
+	} {
+		c.set(123.456, dest)
+		if dest[0] != c.regs[0] || dest[1] != c.regs[1] {
+			t.Errorf("set %X, expected %X", dest, c.regs)
+		}
+		if bits := math.Float32bits(c.get([]uint16{0x1234, 0x5678})); bits != c.bits {
+			t.Errorf("get %08X, expected %08X", bits, c.bits)
+		}
+	}
+
+	tab := make([]byte, 20)
+	ModbusSetBitsFromByte(tab, 1, 0xA5)
+	ModbusSetBitsFromBytes(tab, 9, 10, []byte{0x3C, 0x02})
+	if fmt.Sprint(tab) != "[0 1 0 1 0 0 1 0 1 0 0 1 1 1 1 0 0 0 1 0]" {
+		t.Error("bits", tab)
+	}
+	if v := ModbusGetByteFromBits(tab, 3, 6); v != 41 {
+		t.Error("byte from bits", v)
+	}
+	if !ModbusVersionCheck(3, 1, 10) || ModbusVersionCheck(3, 2, 0) || LIBMODBUS_VERSION_HEX != 0x03010A {
+		t.Error("version", LIBMODBUS_VERSION_STRING)
+	}
+}
+
+// TestModbusConformance_ShortFrames the responses whose byte count exceeds the data received are refused
+func TestModbusConformance_ShortFrames(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		pdu  []byte
+		call func(x *Modbus) error
+	}{
+		{"read bits", []byte{MODBUS_FC_READ_COILS, 1}, func(x *Modbus) error {
+			_, err := x.ReadBits(0, 8)
+			return err
+		}},
+		{"read input bits", []byte{MODBUS_FC_READ_DISCRETE_INPUTS, 2, 0xFF}, func(x *Modbus) error {
+			_, err := x.ReadInputBits(0, 16)
+			return err
+		}},
+		{"read registers", []byte{MODBUS_FC_READ_HOLDING_REGISTERS, 4, 0, 1}, func(x *Modbus) error {
+			_, err := x.ReadRegisters(0, 2)
+			return err
+		}},
+		{"read input registers", []byte{MODBUS_FC_READ_INPUT_REGISTERS, 4, 0, 1}, func(x *Modbus) error {
+			_, err := x.ReadInputRegisters(0, 2)
+			return err
+		}},
+		{"report slave id", []byte{MODBUS_FC_REPORT_SLAVE_ID, 5, 1, 0xFF}, func(x *Modbus) error {
+			_, err := x.ReportSlaveId()
+			return err
+		}},
+		{"report slave id without run indicator", []byte{MODBUS_FC_REPORT_SLAVE_ID, 1, 1}, func(x *Modbus) error {
+			_, err := x.ReportSlaveId()
+			return err
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			go func() {
+				// answer the request with the same transaction identifier and the malformed PDU
+				hdr := make([]byte, 7)
+				if _, err := io.ReadFull(c1, hdr); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(c1, make([]byte, int(hdr[4])<<8|int(hdr[5])-1)); err != nil {
+					return
+				}
+				n := len(c.pdu) + 1
+				c1.Write(append([]byte{hdr[0], hdr[1], 0, 0, byte(n >> 8), byte(n), hdr[6]}, c.pdu...))
+			}()
+			x := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+			if x == nil {
+				t.FailNow()
+			}
+			defer x.Free()
+			x.SetResponseTimeout(200 * time.Millisecond)
+			if err := c.call(x); conformanceCode(err) != EMBBADDATA {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestModbusConformance_ShortRequests the Go backend answers the requests missing their value with an exception
+func TestModbusConformance_ShortRequests(t *testing.T) {
+	mm := conformanceMapping(t)
+	defer mm.Free()
+	for _, c := range []struct {
+		name string
+		pdu  []byte
+		want []byte
+	}{
+		{"write single coil", []byte{MODBUS_FC_WRITE_SINGLE_COIL, 0, 1}, []byte{0x85, 0x03}},
+		{"write single register", []byte{MODBUS_FC_WRITE_SINGLE_REGISTER, 0, 1}, []byte{0x86, 0x03}},
+		{"write single register out of the mapping", []byte{MODBUS_FC_WRITE_SINGLE_REGISTER, 0, 99}, []byte{0x86, 0x02}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c2.Close()
+			x := ModbusNewConn(c1, MODBUS_FRAMING_TCP)
+			if x == nil {
+				t.FailNow()
+			}
+			defer x.Free()
+			x.SetResponseTimeout(time.Millisecond)
+			n := len(c.pdu) + 1
+			req := append([]byte{0, 1, 0, 0, byte(n >> 8), byte(n), MODBUS_TCP_SLAVE}, c.pdu...)
+			done := make(chan error, 1)
+			go func() { done <- x.Reply(req, mm) }()
+			c2.SetReadDeadline(time.Now().Add(time.Second))
+			rsp := make([]byte, 7+len(c.want))
+			if _, err := io.ReadFull(c2, rsp); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+			if !slices.Equal(rsp[7:], c.want) || rsp[5] != byte(len(c.want)+1) {
+				t.Errorf("% X", rsp)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package libmodbusgo
+
+import (
+	"io"
+)
+
+func (f ModbusFraming) framer() modbusFramer {
+	switch f {
+	case MODBUS_FRAMING_TCP:
+		return modbusMbap{}
+	case MODBUS_FRAMING_RTU:
+		return modbusRtu{}
+	case MODBUS_FRAMING_ASCII:
+		return modbusAscii{}
+	}
+	return nil
+}
+
+// ModbusNewConn create a context communicating over an established connection
+//
+// The ModbusNewConn() function shall allocate and initialize a Modbus context exchanging messages with the given
+// framing over conn, any net.Conn or io.ReadWriteCloser such as a tunnel, a WebSocket, a net.Pipe() or a custom serial
+// driver. The context is connected at creation, Connect() does nothing and Close() closes conn for good.
+//
+// The response, byte and indication timeouts are only enforced when conn has a SetReadDeadline method, as net.Conn
+// and os.File do. With MODBUS_FRAMING_RTU or MODBUS_FRAMING_ASCII, the slave addressing and broadcast rules of serial
+// lines apply and the slave must be set with SetSlave().
+func ModbusNewConn(conn io.ReadWriteCloser, framing ModbusFraming) *Modbus {
+	f := framing.framer()
+	if conn == nil || f == nil {
+		return nil
+	}
+	be := modbusBackendNew(f, nil)
+	if framing == MODBUS_FRAMING_ASCII {
+		be.byteTimeout = MODBUS_ASCII_BYTE_TIMEOUT
+	}
+	be.use(conn)
+	return &Modbus{be: be}
+}
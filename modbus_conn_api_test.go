@@ -0,0 +1,84 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestModbusNewConn(t *testing.T) {
+	for _, framing := range []ModbusFraming{MODBUS_FRAMING_TCP, MODBUS_FRAMING_RTU, MODBUS_FRAMING_ASCII} {
+		t.Run(framing.String(), func(t *testing.T) {
+			mm := ModbusMappingNew(16, 0, 10, 0)
+			if mm == nil {
+				t.FailNow()
+			}
+			defer mm.Free()
+
+			c1, c2 := net.Pipe()
+			server := ModbusNewConn(c1, framing)
+			if server == nil {
+				t.FailNow()
+			}
+			defer server.Free()
+			server.SetSlave(1)
+			go func() {
+				for {
+					req, err := server.Receive()
+					if err != nil {
+						return
+					}
+					if len(req) > 0 {
+						server.Reply(req, mm)
+					}
+				}
+			}()
+
+			ctx := ModbusNewConn(c2, framing)
+			if ctx == nil {
+				t.FailNow()
+			}
+			defer ctx.Free()
+			ctx.SetResponseTimeout(100 * time.Millisecond)
+			ctx.SetSlave(1)
+			if err := ctx.Connect(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ctx.WriteBits(3, []byte{1, 0, 1}); err != nil {
+				t.Fatal(err)
+			}
+			bits, err := ctx.ReadBits(2, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bits[1] != 1 || bits[2] != 0 || bits[3] != 1 {
+				t.Errorf("bits %v", bits)
+			}
+			regs, err := ctx.WriteAndReadRegisters(0, []uint16{7, 8}, 1, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if regs[0] != 8 || regs[1] != 0 {
+				t.Errorf("registers %v", regs)
+			}
+			id, err := ctx.ReportSlaveId()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(id.AdditionalData) != "LMB"+LIBMODBUS_VERSION_STRING {
+				t.Errorf("slave id %q", id.AdditionalData)
+			}
+			var merr *Error
+			if _, err = ctx.ReadInputRegisters(0, 1); !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+				t.Error("expected illegal data address, got", err)
+			}
+
+			server.Close()
+			if _, err = ctx.ReadRegisters(0, 1); err == nil {
+				t.Error("confirmation from a closed server")
+			}
+		})
+	}
+}
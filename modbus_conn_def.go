@@ -0,0 +1,22 @@
+package libmodbusgo
+
+// ModbusFraming framing of the messages exchanged over a connection given to ModbusNewConn()
+type ModbusFraming int
+
+const (
+	MODBUS_FRAMING_TCP   ModbusFraming = iota // MBAP header, as ModbusNewTcp()
+	MODBUS_FRAMING_RTU                        // slave and CRC, as ModbusNewRtu()
+	MODBUS_FRAMING_ASCII                      // ':', hexadecimal characters, LRC and CR LF, as ModbusNewAscii()
+)
+
+func (f ModbusFraming) String() string {
+	switch f {
+	case MODBUS_FRAMING_TCP:
+		return "tcp"
+	case MODBUS_FRAMING_RTU:
+		return "rtu"
+	case MODBUS_FRAMING_ASCII:
+		return "ascii"
+	}
+	return "unknown"
+}
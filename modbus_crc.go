@@ -0,0 +1,17 @@
+package libmodbusgo
+
+// modbusCrc16 CRC-16/MODBUS of an RTU frame, the low byte is sent first on the wire
+func modbusCrc16(b []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, v := range b {
+		crc ^= uint16(v)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
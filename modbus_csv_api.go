@@ -0,0 +1,240 @@
+package libmodbusgo
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func (c *ModbusCsvColumns) names() []string {
+	names := []string{c.Address, c.Name, c.Type, c.Scale, c.Unit, c.Access, c.Table, c.Order, c.Description, c.Value}
+	for i, def := range []string{"address", "name", "type", "scale", "unit", "access", "table", "order",
+		"description", "value"} {
+		if names[i] == "" {
+			names[i] = def
+		}
+	}
+	return names
+}
+
+// columns of the names of ModbusCsvColumns
+const (
+	csvAddress = iota
+	csvName
+	csvType
+	csvScale
+	csvUnit
+	csvAccess
+	csvTable
+	csvOrder
+	csvDescription
+	csvValue
+)
+
+// modbusCsvType raw type of the type names of the vendor register maps
+func modbusCsvType(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "u16", "word", "unsigned", "uint":
+		return "uint16"
+	case "s16", "i16", "short", "signed", "int":
+		return "int16"
+	case "u32", "dword", "udint", "acc32":
+		return "uint32"
+	case "s32", "i32", "dint":
+		return "int32"
+	case "u64", "acc64":
+		return "uint64"
+	case "s64", "i64":
+		return "int64"
+	case "float", "real", "f32":
+		return "float32"
+	case "double", "lreal", "f64":
+		return "float64"
+	case "bool", "bit", "coil":
+		return ""
+	}
+	return s
+}
+
+// modbusCsvAccess access mode of the access names of the vendor register maps
+func modbusCsvAccess(s string) (string, bool) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", "")) {
+	case "":
+		return "", true
+	case "r", "ro", "read", "readonly":
+		return "r", true
+	case "w", "wo", "write", "writeonly":
+		return "w", true
+	case "rw", "r/w", "wr", "read/write", "readwrite":
+		return "rw", true
+	}
+	return "", false
+}
+
+func (opts *ModbusCsvOptions) reader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return cr
+}
+
+// ModbusCsvImport read a register map in CSV into a profile
+//
+// The first row is the header, the lines starting with # are comments. A malformed row returns an error naming its
+// line, the profile is then validated, see ModbusProfile.Validate(), and its errors name the line of the point and
+// its address as written. opts may be nil.
+func ModbusCsvImport(r io.Reader, opts *ModbusCsvOptions) (p *ModbusProfile, err error) {
+	if opts == nil {
+		opts = &ModbusCsvOptions{}
+	}
+	cr := opts.reader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, marshalError("line 1: no header")
+	}
+	if err != nil {
+		return
+	}
+	columns := opts.Columns.names()
+	index := make([]int, len(columns))
+	for i, name := range columns {
+		index[i] = -1
+		for j, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				index[i] = j
+			}
+		}
+	}
+	line, _ := cr.FieldPos(0)
+	for _, i := range []int{csvAddress, csvName} {
+		if index[i] < 0 {
+			return nil, marshalError("line %d: no %s column", line, columns[i])
+		}
+	}
+
+	p = &ModbusProfile{}
+	src := &modbusProfileSource{}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ = cr.FieldPos(0)
+		field := func(i int) string {
+			if index[i] < 0 || index[i] >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index[i]])
+		}
+		pp := ModbusProfilePoint{Name: field(csvName), Type: modbusCsvType(field(csvType)), Unit: field(csvUnit),
+			Table: field(csvTable), Order: ModbusByteOrder(strings.ToUpper(field(csvOrder))),
+			Description: field(csvDescription)}
+		if pp.Name == "" {
+			return nil, marshalError("line %d: no name", line)
+		}
+		address := field(csvAddress)
+		if opts.Modicon {
+			ref, err := ModbusParseReference(address)
+			if err != nil {
+				return nil, marshalError("line %d: %s", line, err.(*Error).message)
+			}
+			pp.Table, pp.Address = ref.Table.String(), ref.Addr
+		} else {
+			// zero padded offsets are decimal, hexadecimal needs the 0x prefix
+			var addr int64
+			if h, ok := strings.CutPrefix(strings.ToLower(address), "0x"); ok {
+				addr, err = strconv.ParseInt(h, 16, 32)
+			} else {
+				addr, err = strconv.ParseInt(address, 10, 32)
+			}
+			if err != nil {
+				return nil, marshalError("line %d: invalid address %q", line, address)
+			}
+			pp.Address = int(addr)
+		}
+		if s := field(csvScale); s != "" {
+			if pp.Scale, err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, marshalError("line %d: invalid scale %q", line, s)
+			}
+		}
+		ok := false
+		if pp.Access, ok = modbusCsvAccess(field(csvAccess)); !ok {
+			return nil, marshalError("line %d: invalid access %q", line, field(csvAccess))
+		}
+		if t, ok := modbusParseTable(pp.Table); ok && (t == MODBUS_TABLE_BITS || t == MODBUS_TABLE_INPUT_BITS) {
+			pp.Type = ""
+		}
+		p.Points = append(p.Points, pp)
+		src.lines = append(src.lines, line)
+		src.addresses = append(src.addresses, address)
+	}
+	if _, err = p.compileFrom(src); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// ModbusCsvExport write the points of a device and their current engineering values in CSV
+//
+// The values are read with ModbusDevice.ReadAll(), the value of the write only points is empty. opts may be nil.
+func ModbusCsvExport(w io.Writer, d *ModbusDevice, opts *ModbusCsvOptions) (err error) {
+	if opts == nil {
+		opts = &ModbusCsvOptions{}
+	}
+	values, err := d.ReadAll()
+	if err != nil {
+		return
+	}
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	columns := opts.Columns.names()
+	header := []string{columns[csvAddress], columns[csvName], columns[csvType], columns[csvScale],
+		columns[csvUnit], columns[csvAccess], columns[csvValue]}
+	if !opts.Modicon {
+		header = append(header[:2], append([]string{columns[csvTable]}, header[2:]...)...)
+	}
+	if err = cw.Write(header); err != nil {
+		return
+	}
+	for i := range d.entries {
+		e := &d.entries[i]
+		pt := &e.point
+		record := []string{strconv.Itoa(pt.Addr), pt.Name}
+		if opts.Modicon {
+			record[0] = ModbusReference{pt.table(), pt.Addr}.String()
+		} else {
+			record = append(record, pt.table().String())
+		}
+		access := map[[2]bool]string{{true, false}: "r", {false, true}: "w", {true, true}: "rw"}[[2]bool{e.read, e.write}]
+		scale := ""
+		if pt.Scale != 0 {
+			scale = strconv.FormatFloat(pt.Scale, 'g', -1, 64)
+		}
+		value := ""
+		if v, ok := values[pt.Name]; ok {
+			value = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		typ := pt.Type
+		if typ == "" && !pt.bit() {
+			typ = "uint16"
+		}
+		record = append(record, typ, scale, pt.Unit, access, value)
+		if err = cw.Write(record); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,141 @@
+package libmodbusgo
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const csvTest = `Register;Tag;Data Type;Gain;Units;R/W;Comment
+# measurements
+30001;voltage;U16;0.1;V;R;Phase voltage
+30002;current;FLOAT;;A;R;
+400101;setpoint;s16;0.5;%;R/W;Power limit
+00003;reset;bool;;;W;
+`
+
+func TestModbusCsvImport(t *testing.T) {
+	opts := &ModbusCsvOptions{Comma: ';', Modicon: true, Columns: ModbusCsvColumns{Address: "register", Name: "tag",
+		Type: "data type", Scale: "gain", Unit: "units", Access: "r/w", Description: "comment"}}
+	p, err := ModbusCsvImport(strings.NewReader(csvTest), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := p.ModbusPoints()
+	if err != nil || len(points) != 4 {
+		t.Fatal(points, err)
+	}
+	for i, want := range []ModbusPoint{
+		{Name: "voltage", Table: MODBUS_TABLE_INPUT_REGISTERS, Addr: 0, Type: "uint16", Scale: 0.1, Unit: "V"},
+		{Name: "current", Table: MODBUS_TABLE_INPUT_REGISTERS, Addr: 1, Type: "float32", Unit: "A"},
+		{Name: "setpoint", Table: MODBUS_TABLE_REGISTERS, Addr: 100, Type: "int16", Scale: 0.5, Unit: "%"},
+		{Name: "reset", Table: MODBUS_TABLE_BITS, Addr: 2},
+	} {
+		if points[i] != want {
+			t.Errorf("%+v", points[i])
+		}
+	}
+	if p.Points[0].Description != "Phase voltage" || p.Points[3].Access != "w" {
+		t.Errorf("%+v", p.Points)
+	}
+
+	// zero padded addresses are decimal
+	p, err = ModbusCsvImport(strings.NewReader("address,name\n0010,a\n0099,b\n0x0100,c\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Points[0].Address != 10 || p.Points[1].Address != 99 || p.Points[2].Address != 256 {
+		t.Errorf("%+v", p.Points)
+	}
+
+	for _, c := range []struct {
+		csv string
+		err string
+	}{
+		{"name,type\nx,uint16\n", "line 1: no address column"},
+		{"address,name,scale\n0,a,1\n1,b,x\n", "line 3: invalid scale"},
+		{"address,name\n0,a\n\n0x,b\n", "line 4: invalid address"},
+		{"address,name,access\n0,a,maybe\n", "line 2: invalid access"},
+		{"address,name\n1,\n", "line 2: no name"},
+		{"address,name\n0,a\n0,b\n", "line 3: point b: address 0 overlaps point a"},
+		{"address,name\n0,a\n# comment\n1,a\n", "line 4: point a: duplicate name"},
+		{"address,name,type\n0,a,int24\n", "line 2: point a: unknown type"},
+		{"address,name,type\n0,a,uint16\n0xFFFF,b,uint32\n", "line 3: point b: address 0xFFFF out of the table"},
+		{"", "no header"},
+	} {
+		_, err = ModbusCsvImport(strings.NewReader(c.csv), nil)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%q: %v", c.csv, err)
+		}
+	}
+	_, err = ModbusCsvImport(strings.NewReader(csvTest+"400100;limit;s32;;;R/W;\n"), opts)
+	if err == nil || !strings.Contains(err.Error(), "line 5: point setpoint: address 400101 overlaps point limit") {
+		t.Errorf("%v", err)
+	}
+	if _, err = ModbusCsvImport(strings.NewReader("address,name\n50001,a\n"), &ModbusCsvOptions{Modicon: true}); err == nil ||
+		!strings.Contains(err.Error(), "line 2: invalid reference") {
+		t.Error(err)
+	}
+}
+
+func TestModbusCsvExport(t *testing.T) {
+	p, err := ModbusCsvImport(strings.NewReader(`address,name,table,type,scale,unit,access
+0,voltage,input,uint16,0.1,V,r
+100,setpoint,holding,int16,0.5,%,rw
+10000,energy,holding,uint32,,kWh,r
+2,reset,coils,,,,w
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm := ModbusMappingNew(10, 0, 10002, 10)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	mm.SetTabInputRegisters(0, 2304)
+	mm.SetTabRegisters(100, 0xFFEC)
+	mm.SetTabRegisters(10001, 42)
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	defer server.close()
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+	d, err := ModbusDeviceNew(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err = ModbusCsvExport(&b, d, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `address,name,table,type,scale,unit,access,value
+0,voltage,input_registers,uint16,0.1,V,r,230.4
+100,setpoint,registers,int16,0.5,%,rw,-10
+10000,energy,registers,uint32,,kWh,r,42
+2,reset,bits,,,,w,
+`
+	if b.String() != want {
+		t.Error(b.String())
+	}
+	b.Reset()
+	if err = ModbusCsvExport(&b, d, &ModbusCsvOptions{Modicon: true, Comma: ';'}); err != nil {
+		t.Fatal(err)
+	}
+	want = `address;name;type;scale;unit;access;value
+30001;voltage;uint16;0.1;V;r;230.4
+40101;setpoint;int16;0.5;%;rw;-10
+410001;energy;uint32;;kWh;r;42
+00003;reset;;;;w;
+`
+	if b.String() != want {
+		t.Error(b.String())
+	}
+}
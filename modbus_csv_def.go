@@ -0,0 +1,28 @@
+package libmodbusgo
+
+// ModbusCsvColumns names of the columns of a register map in CSV, matched case insensitively against the header row
+//
+// The empty names use the defaults: address, name, type, scale, unit, access, table, order, description and value.
+// Address and name are mandatory, the other columns are optional.
+type ModbusCsvColumns struct {
+	Address     string
+	Name        string
+	Type        string
+	Scale       string
+	Unit        string
+	Access      string
+	Table       string
+	Order       string
+	Description string
+	Value       string // engineering value written by ModbusCsvExport()
+}
+
+// ModbusCsvOptions format of a register map in CSV
+type ModbusCsvOptions struct {
+	Columns ModbusCsvColumns
+	Comma   rune // field delimiter, ',' when 0
+	// Modicon addresses are references giving the table, eg. 40001, 400001 or %MW0 for the first holding register, see
+	// ModbusParseReference(), the table column is then ignored. Otherwise the addresses are zero-based offsets in the
+	// table of the table column, decimal even when zero padded or hexadecimal with the 0x prefix.
+	Modicon bool
+}
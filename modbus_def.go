@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 package libmodbusgo
 
 /*
@@ -7,9 +9,8 @@ package libmodbusgo
 
 */
 import "C"
-import (
-	"fmt"
-)
+
+import "sync"
 
 // Modbus function codes
 const (
@@ -74,9 +75,7 @@ const MODBUS_MAX_ADU_LENGTH = C.MODBUS_MAX_ADU_LENGTH
 // MODBUS_ENOBASE Random number to avoid errno conflicts
 const MODBUS_ENOBASE = C.MODBUS_ENOBASE
 
-// ModbusException Protocol exceptions
-type ModbusException int
-
+// Protocol exceptions
 const (
 	MODBUS_EXCEPTION_ILLEGAL_FUNCTION        ModbusException = C.MODBUS_EXCEPTION_ILLEGAL_FUNCTION
 	MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS    ModbusException = C.MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS
@@ -92,8 +91,6 @@ const (
 	MODBUS_EXCEPTION_MAX                     ModbusException = C.MODBUS_EXCEPTION_MAX
 )
 
-type ErrorCode int
-
 func (c ErrorCode) Error() (e *Error) {
 	msg := C.modbus_strerror(C.int(c))
 	return &Error{
@@ -133,45 +130,26 @@ var (
 
 type Modbus struct {
 	ctx    *C.modbus_t
-	socket int // modbus tcp used
+	socket int            // modbus tcp used
+	be     *modbusBackend // Go backends, ctx is nil
+
+	rtuConfig *RtuConfig // serial port options of a libmodbus RTU context, see RtuSetConfig()
 }
 
 type ModbusMapping struct {
 	mb *C.modbus_mapping_t
+	mu sync.RWMutex // held by Reply() and Snapshot(), see Lock()
 }
 
-type ModbusErrorRecoveryMode byte
-
 const (
 	MODBUS_ERROR_RECOVERY_NONE     ModbusErrorRecoveryMode = C.MODBUS_ERROR_RECOVERY_NONE
 	MODBUS_ERROR_RECOVERY_LINK     ModbusErrorRecoveryMode = C.MODBUS_ERROR_RECOVERY_LINK
 	MODBUS_ERROR_RECOVERY_PROTOCOL ModbusErrorRecoveryMode = C.MODBUS_ERROR_RECOVERY_PROTOCOL
 )
 
-type ModbusQuirks byte
-
 const (
 	MODBUS_QUIRK_NONE               ModbusQuirks = C.MODBUS_QUIRK_NONE
 	MODBUS_QUIRK_MAX_SLAVE          ModbusQuirks = C.MODBUS_QUIRK_MAX_SLAVE
 	MODBUS_QUIRK_REPLY_TO_BROADCAST ModbusQuirks = C.MODBUS_QUIRK_REPLY_TO_BROADCAST
 	MODBUS_QUIRK_ALL                ModbusQuirks = C.MODBUS_QUIRK_ALL
 )
-
-type Error struct {
-	code    ErrorCode
-	message string
-}
-
-func (e *Error) Error() string {
-	return fmt.Sprintf("code: %d message: %s", e.code, e.message)
-}
-
-func (e *Error) Code() ErrorCode {
-	return e.code
-}
-
-type ReportSlaveId struct {
-	SlaveId            byte
-	RunIndicatorStatus byte
-	AdditionalData     []byte
-}
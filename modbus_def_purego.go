@@ -0,0 +1,174 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+import (
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Modbus function codes
+const (
+	MODBUS_FC_READ_COILS               = 0x01
+	MODBUS_FC_READ_DISCRETE_INPUTS     = 0x02
+	MODBUS_FC_READ_HOLDING_REGISTERS   = 0x03
+	MODBUS_FC_READ_INPUT_REGISTERS     = 0x04
+	MODBUS_FC_WRITE_SINGLE_COIL        = 0x05
+	MODBUS_FC_WRITE_SINGLE_REGISTER    = 0x06
+	MODBUS_FC_READ_EXCEPTION_STATUS    = 0x07
+	MODBUS_FC_WRITE_MULTIPLE_COILS     = 0x0F
+	MODBUS_FC_WRITE_MULTIPLE_REGISTERS = 0x10
+	MODBUS_FC_REPORT_SLAVE_ID          = 0x11
+	MODBUS_FC_MASK_WRITE_REGISTER      = 0x16
+	MODBUS_FC_WRITE_AND_READ_REGISTERS = 0x17
+)
+
+const (
+	MODBUS_BROADCAST_ADDRESS = 0
+)
+
+// Modbus_Application_Protocol_V1_1b.pdf (chapter 6 section 1 page 12)
+// Quantity of Coils to read (2 bytes): 1 to 2000 (0x7D0)
+// (chapter 6 section 11 page 29)
+// Quantity of Coils to write (2 bytes): 1 to 1968 (0x7B0)
+const (
+	MODBUS_MAX_READ_BITS  = 2000
+	MODBUS_MAX_WRITE_BITS = 1968
+)
+
+// Modbus_Application_Protocol_V1_1b.pdf (chapter 6 section 3 page 15)
+// Quantity of Registers to read (2 bytes): 1 to 125 (0x7D)
+// (chapter 6 section 12 page 31)
+// Quantity of Registers to write (2 bytes) 1 to 123 (0x7B)
+// (chapter 6 section 17 page 38)
+// Quantity of Registers to write in R/W registers (2 bytes) 1 to 121 (0x79)
+const (
+	MODBUS_MAX_READ_REGISTERS     = 125
+	MODBUS_MAX_WRITE_REGISTERS    = 123
+	MODBUS_MAX_WR_WRITE_REGISTERS = 121
+	MODBUS_MAX_WR_READ_REGISTERS  = 125
+)
+
+// MODBUS_MAX_PDU_LENGTH The size of the MODBUS PDU is limited by the size constraint inherited from
+// the first MODBUS implementation on Serial Line network (max. RS485 ADU = 256
+// bytes). Therefore, MODBUS PDU for serial line communication = 256 - Server
+// address (1 byte) - CRC (2 bytes) = 253 bytes.
+const MODBUS_MAX_PDU_LENGTH = 253
+
+// MODBUS_MAX_ADU_LENGTH
+//
+// Consequently:
+//   - RTU MODBUS ADU = 253 bytes + Server address (1 byte) + CRC (2 bytes) = 256
+//     bytes.
+//   - TCP MODBUS ADU = 253 bytes + MBAP (7 bytes) = 260 bytes.
+//
+// so the maximum of both backend in 260 bytes. This size can used to allocate
+// an array of bytes to store responses and it will be compatible with the two
+// backends.
+const MODBUS_MAX_ADU_LENGTH = 260
+
+// MODBUS_ENOBASE Random number to avoid errno conflicts
+const MODBUS_ENOBASE = 112345678
+
+// Protocol exceptions
+const (
+	MODBUS_EXCEPTION_ILLEGAL_FUNCTION ModbusException = iota + 1
+	MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS
+	MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+	MODBUS_EXCEPTION_SLAVE_OR_SERVER_FAILURE
+	MODBUS_EXCEPTION_ACKNOWLEDGE
+	MODBUS_EXCEPTION_SLAVE_OR_SERVER_BUSY
+	MODBUS_EXCEPTION_NEGATIVE_ACKNOWLEDGE
+	MODBUS_EXCEPTION_MEMORY_PARITY
+	MODBUS_EXCEPTION_NOT_DEFINED
+	MODBUS_EXCEPTION_GATEWAY_PATH
+	MODBUS_EXCEPTION_GATEWAY_TARGET
+	MODBUS_EXCEPTION_MAX
+)
+
+// lastErrno errno of the last error built, returned by ModbusStrError() as libmodbus does with errno
+var lastErrno atomic.Int64
+
+// modbusErrorMessages messages of modbus_strerror() for the libmodbus error numbers
+var modbusErrorMessages = map[ErrorCode]string{
+	EMBXILFUN:   "Illegal function",
+	EMBXILADD:   "Illegal data address",
+	EMBXILVAL:   "Illegal data value",
+	EMBXSFAIL:   "Slave device or server failure",
+	EMBXACK:     "Acknowledge",
+	EMBXSBUSY:   "Slave device or server is busy",
+	EMBXNACK:    "Negative acknowledge",
+	EMBXMEMPAR:  "Memory parity error",
+	EMBXGPATH:   "Gateway path unavailable",
+	EMBXGTAR:    "Target device failed to respond",
+	EMBBADCRC:   "Invalid CRC",
+	EMBBADDATA:  "Invalid data",
+	EMBBADEXC:   "Invalid exception code",
+	EMBMDATA:    "Too many data",
+	EMBBADSLAVE: "Response not from requested slave",
+}
+
+func (c ErrorCode) Error() (e *Error) {
+	lastErrno.Store(int64(c))
+	msg, ok := modbusErrorMessages[c]
+	if !ok {
+		msg = syscall.Errno(c).Error()
+	}
+	return &Error{
+		code:    c,
+		message: msg,
+	}
+}
+
+const (
+	EMBXILFUN ErrorCode = MODBUS_ENOBASE + iota + 1
+	EMBXILADD
+	EMBXILVAL
+	EMBXSFAIL
+	EMBXACK
+	EMBXSBUSY
+	EMBXNACK
+	EMBXMEMPAR
+	_ // MODBUS_EXCEPTION_NOT_DEFINED
+	EMBXGPATH
+	EMBXGTAR
+)
+
+// Native libmodbus error codes
+const (
+	EMBBADCRC ErrorCode = EMBXGTAR + iota + 1
+	EMBBADDATA
+	EMBBADEXC
+	EMBUNKEXC
+	EMBMDATA
+	EMBBADSLAVE
+)
+
+var (
+	LibmodbusVersionMajor uint = LIBMODBUS_VERSION_MAJOR
+	LibmodbusVersionMinor uint = LIBMODBUS_VERSION_MINOR
+	LibmodbusVersionMicro uint = LIBMODBUS_VERSION_MICRO
+)
+
+type Modbus struct {
+	be *modbusBackend
+}
+
+type ModbusMapping struct {
+	t  *modbusTabs
+	mu sync.RWMutex // held by Reply() and Snapshot(), see Lock()
+}
+
+const (
+	MODBUS_ERROR_RECOVERY_NONE     ModbusErrorRecoveryMode = 0
+	MODBUS_ERROR_RECOVERY_LINK     ModbusErrorRecoveryMode = 1 << 1
+	MODBUS_ERROR_RECOVERY_PROTOCOL ModbusErrorRecoveryMode = 1 << 2
+)
+
+const (
+	MODBUS_QUIRK_NONE               ModbusQuirks = 0
+	MODBUS_QUIRK_MAX_SLAVE          ModbusQuirks = 1 << 1
+	MODBUS_QUIRK_REPLY_TO_BROADCAST ModbusQuirks = 1 << 2
+	MODBUS_QUIRK_ALL                ModbusQuirks = 0xFF
+)
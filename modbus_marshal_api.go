@@ -0,0 +1,631 @@
+package libmodbusgo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var modbusRawTypes = map[string]modbusRawType{
+	"int16":   {name: "int16", regs: 1, signed: true},
+	"uint16":  {name: "uint16", regs: 1},
+	"int32":   {name: "int32", regs: 2, signed: true},
+	"uint32":  {name: "uint32", regs: 2},
+	"int64":   {name: "int64", regs: 4, signed: true},
+	"uint64":  {name: "uint64", regs: 4},
+	"float32": {name: "float32", regs: 2, float: true},
+	"float64": {name: "float64", regs: 4, float: true},
+	"bcd16":   {name: "bcd16", regs: 1, bcd: true},
+	"bcd32":   {name: "bcd32", regs: 2, bcd: true},
+	"bcd64":   {name: "bcd64", regs: 4, bcd: true},
+}
+
+func marshalError(format string, a ...any) error {
+	return &Error{code: ErrorCode(syscall.EINVAL), message: fmt.Sprintf(format, a...)}
+}
+
+// modbusTag options of a modbus struct tag
+type modbusTag struct {
+	addr  int // -1 when the field follows the previous one
+	raw   string
+	order string
+	scale float64
+	bit   int // -1 for a whole value
+	bits  int
+	len   int // registers of a string
+}
+
+func modbusParseTag(tag string) (t modbusTag, err error) {
+	t = modbusTag{addr: -1, bit: -1}
+	for _, opt := range strings.Split(tag, ",") {
+		if opt == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "addr":
+			t.addr, err = strconv.Atoi(value)
+			if err == nil && t.addr < 0 {
+				err = strconv.ErrRange
+			}
+		case "type":
+			t.raw = value
+		case "order":
+			t.order = value
+		case "scale":
+			t.scale, err = strconv.ParseFloat(value, 64)
+			if err == nil && t.scale == 0 {
+				err = strconv.ErrRange
+			}
+		case "bit":
+			t.bit, err = strconv.Atoi(value)
+			if err == nil && t.bit < 0 {
+				err = strconv.ErrRange
+			}
+		case "bits":
+			t.bits, err = strconv.Atoi(value)
+			if err == nil && t.bits < 1 {
+				err = strconv.ErrRange
+			}
+		case "len":
+			t.len, err = strconv.Atoi(value)
+			if err == nil && t.len < 1 {
+				err = strconv.ErrRange
+			}
+		default:
+			return t, fmt.Errorf("unknown option %q", key)
+		}
+		if err != nil {
+			return t, fmt.Errorf("option %s: %w", opt, err)
+		}
+	}
+	if t.bits > 0 && t.bit < 0 {
+		return t, fmt.Errorf("option bits without bit")
+	}
+	if t.bit >= 0 && t.bits == 0 {
+		t.bits = 1
+	}
+	return
+}
+
+// modbusLayoutOf registers layout of a struct type
+func modbusLayoutOf(t reflect.Type) (l *modbusLayout, err error) {
+	if v, ok := modbusLayouts.Load(t); ok {
+		return v.(*modbusLayout), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, marshalError("%s is not a struct", t)
+	}
+	l = &modbusLayout{}
+	if l.size, err = l.addStruct(t, 0, nil); err != nil {
+		return nil, err
+	}
+	modbusLayouts.Store(t, l)
+	return
+}
+
+// addStruct lay out the fields of a struct from the register base and return the end of its last field
+func (l *modbusLayout) addStruct(t reflect.Type, base int, index []int) (end int, err error) {
+	end = base
+	next := base
+	bitReg := -1 // register of the preceding bit field, the next ones without addr share it
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("modbus")
+		padding := sf.Name == "_"
+		if tag == "-" || (!sf.IsExported() && !padding) {
+			continue
+		}
+		opts, err := modbusParseTag(tag)
+		if err != nil {
+			return end, marshalError("field %s: %v", sf.Name, err)
+		}
+		addr := next
+		switch {
+		case opts.addr >= 0:
+			addr = base + opts.addr
+		case opts.bit >= 0 && bitReg >= 0:
+			addr = bitReg
+		}
+		path := append(slices.Clip(index), i)
+		var n int
+		if padding {
+			// reserve the registers of the type, they are read but never decoded nor written
+			n, err = (&modbusLayout{}).addValue(sf.Type, 0, nil, sf.Name, opts)
+			l.fields = append(l.fields, modbusField{name: sf.Name, addr: addr, raw: modbusRawType{regs: n}, padding: true})
+		} else {
+			n, err = l.addValue(sf.Type, addr, path, sf.Name, opts)
+		}
+		if err != nil {
+			return end, err
+		}
+		bitReg = -1
+		if opts.bit >= 0 {
+			bitReg = addr
+		}
+		next = addr + n
+		end = max(end, next)
+	}
+	return
+}
+
+// addValue lay out a value of type t at addr and return its number of registers
+func (l *modbusLayout) addValue(t reflect.Type, addr int, index []int, name string, opts modbusTag) (n int, err error) {
+	switch t.Kind() {
+	case reflect.Struct:
+		end, err := l.addStruct(t, addr, index)
+		return end - addr, err
+	case reflect.Array:
+		for i := 0; i < t.Len(); i++ {
+			m, err := l.addValue(t.Elem(), addr+n, append(slices.Clip(index), -(i+1)), fmt.Sprintf("%s[%d]", name, i), opts)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+		return
+	}
+
+	f := modbusField{index: index, name: name, addr: addr, scale: opts.scale, bit: opts.bit, bits: opts.bits}
+	if t.Kind() == reflect.String {
+		switch {
+		case opts.raw != "" && opts.raw != "string":
+			return 0, marshalError("field %s: a string field holds a string", name)
+		case opts.len == 0:
+			return 0, marshalError("field %s: a string needs len", name)
+		case opts.bits > 0 || opts.scale != 0:
+			return 0, marshalError("field %s: a string has no bits nor scale", name)
+		}
+		switch strings.ToUpper(opts.order) {
+		case "", string(MODBUS_ORDER_ABCD):
+		case string(MODBUS_ORDER_BADC):
+			f.swap = true
+		default:
+			return 0, marshalError("field %s: order %s of a string", name, opts.order)
+		}
+		f.raw = modbusRawType{name: "string", regs: opts.len, str: true}
+		l.fields = append(l.fields, f)
+		return f.raw.regs, nil
+	}
+	raw := opts.raw
+	if raw == "" {
+		switch t.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int:
+			raw = "int16"
+		case reflect.Uint8, reflect.Uint16, reflect.Uint, reflect.Bool:
+			raw = "uint16"
+		case reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			raw = t.Kind().String()
+		default:
+			return 0, marshalError("field %s: unsupported type %s", name, t)
+		}
+	}
+	if f.bits > 0 && opts.raw == "" && strings.HasPrefix(raw, "int") {
+		// the bit fields are unsigned
+		raw = "u" + raw
+	}
+	var ok bool
+	if f.raw, ok = modbusRawTypes[raw]; !ok {
+		return 0, marshalError("field %s: unknown type %q", name, raw)
+	}
+	if f.order, err = ModbusByteOrder(opts.order).permutation(2 * f.raw.regs); err != nil {
+		return 0, marshalError("field %s: %s", name, err.(*Error).message)
+	}
+
+	float := t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+	default:
+		return 0, marshalError("field %s: unsupported type %s", name, t)
+	}
+	switch {
+	case f.bits > 0 && (f.raw.float || f.raw.bcd || float || f.scale != 0):
+		return 0, marshalError("field %s: a bit field is an integer", name)
+	case f.bits > 0 && f.bit+f.bits > 16*f.raw.regs:
+		return 0, marshalError("field %s: bits %d to %d out of %s", name, f.bit, f.bit+f.bits-1, f.raw.name)
+	case f.bits > 1 && t.Kind() == reflect.Bool:
+		return 0, marshalError("field %s: a bool is a single bit", name)
+	case f.scale != 0 && !float:
+		return 0, marshalError("field %s: a scaled value needs a float field", name)
+	case f.raw.float && !float:
+		return 0, marshalError("field %s: a %s value needs a float field", name, raw)
+	}
+	if float && !f.raw.float && f.scale == 0 {
+		// integer registers converted to a float field
+		f.scale = 1
+	}
+	l.fields = append(l.fields, f)
+	return f.raw.regs, nil
+}
+
+// value field of v designated by the index of a modbusField
+func (f *modbusField) value(v reflect.Value) reflect.Value {
+	for _, i := range f.index {
+		if i >= 0 {
+			v = v.Field(i)
+		} else {
+			v = v.Index(-i - 1)
+		}
+	}
+	return v
+}
+
+func (f *modbusField) decode(regs []uint16, v reflect.Value) (err error) {
+	if f.raw.str {
+		v.SetString(DecodeString(regs[f.addr:f.addr+f.raw.regs], f.swap, true))
+		return
+	}
+	raw := modbusGetRaw(regs[f.addr:], f.order)
+	width := 16 * f.raw.regs
+	if f.raw.bcd {
+		if raw, err = modbusFromBcd(raw, f.raw.regs); err != nil {
+			return marshalError("field %s: %s", f.name, err.(*Error).message)
+		}
+	}
+	switch {
+	case f.bits > 0:
+		return f.setUint(v, raw>>f.bit&(1<<f.bits-1))
+	case f.raw.float:
+		x := math.Float64frombits(raw)
+		if f.raw.regs == 2 {
+			x = float64(math.Float32frombits(uint32(raw)))
+		}
+		if f.scale != 0 {
+			x *= f.scale
+		}
+		v.SetFloat(x)
+	case f.raw.signed:
+		i := int64(raw<<(64-width)) >> (64 - width)
+		if f.scale != 0 {
+			v.SetFloat(float64(i) * f.scale)
+			return nil
+		}
+		return f.setInt(v, i)
+	case f.scale != 0:
+		v.SetFloat(float64(raw) * f.scale)
+	default:
+		return f.setUint(v, raw)
+	}
+	return nil
+}
+
+func (f *modbusField) setInt(v reflect.Value, i int64) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(i != 0)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return marshalError("field %s: %d overflows %s", f.name, i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	default:
+		if v.OverflowInt(i) {
+			return marshalError("field %s: %d overflows %s", f.name, i, v.Type())
+		}
+		v.SetInt(i)
+	}
+	return nil
+}
+
+func (f *modbusField) setUint(v reflect.Value, u uint64) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(u != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if u > math.MaxInt64 || v.OverflowInt(int64(u)) {
+			return marshalError("field %s: %d overflows %s", f.name, u, v.Type())
+		}
+		v.SetInt(int64(u))
+	default:
+		if v.OverflowUint(u) {
+			return marshalError("field %s: %d overflows %s", f.name, u, v.Type())
+		}
+		v.SetUint(u)
+	}
+	return nil
+}
+
+func (f *modbusField) encode(regs []uint16, v reflect.Value) error {
+	if f.raw.str {
+		r, err := EncodeString(v.String(), f.raw.regs, f.swap)
+		if err != nil {
+			return marshalError("field %s: %s", f.name, err.(*Error).message)
+		}
+		copy(regs[f.addr:], r)
+		return nil
+	}
+	width := 16 * f.raw.regs
+	var raw uint64
+	switch {
+	case f.raw.float:
+		x := v.Float()
+		if f.scale != 0 {
+			x /= f.scale
+		}
+		raw = math.Float64bits(x)
+		if f.raw.regs == 2 {
+			raw = uint64(math.Float32bits(float32(x)))
+		}
+	case f.scale != 0:
+		var err error
+		if raw, err = modbusFloatRaw(f.raw, v.Float()/f.scale); err != nil {
+			return marshalError("field %s: %v %s", f.name, v.Float(), err.(*Error).message)
+		}
+	default:
+		var i int64
+		var u uint64
+		negative := false
+		switch v.Kind() {
+		case reflect.Bool:
+			if v.Bool() {
+				u = 1
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = v.Int()
+			negative = i < 0
+			u = uint64(i)
+		default:
+			u = v.Uint()
+		}
+		limit := width
+		if f.bits > 0 {
+			limit = f.bits
+		}
+		switch {
+		case negative && (!f.raw.signed || f.bits > 0 || i < -1<<(limit-1)):
+			return marshalError("field %s: %d overflows %s", f.name, i, f.describe())
+		case !negative && limit < 64 && (u >= 1<<limit || (f.raw.signed && f.bits == 0 && u >= 1<<(limit-1))):
+			return marshalError("field %s: %d overflows %s", f.name, u, f.describe())
+		case !negative && limit == 64 && f.raw.signed && u > math.MaxInt64:
+			return marshalError("field %s: %d overflows %s", f.name, u, f.describe())
+		case f.raw.bcd && (negative || u >= uint64(math.Pow10(4*f.raw.regs))):
+			return marshalError("field %s: %v overflows %s", f.name, v.Interface(), f.describe())
+		}
+		if f.bits > 0 {
+			mask := uint64(1)<<f.bits - 1
+			raw = modbusGetRaw(regs[f.addr:], f.order)&^(mask<<f.bit) | u<<f.bit
+		} else {
+			raw = u
+		}
+	}
+	if f.raw.bcd && f.scale == 0 {
+		raw = modbusToBcd(raw)
+	}
+	if width < 64 {
+		raw &= 1<<width - 1
+	}
+	modbusSetRaw(regs[f.addr:], f.order, raw)
+	return nil
+}
+
+// modbusRawFloat value of a raw type read from the registers as a float
+func modbusRawFloat(rt modbusRawType, order []int, regs []uint16) (x float64, err error) {
+	raw := modbusGetRaw(regs, order)
+	width := 16 * rt.regs
+	switch {
+	case rt.float && rt.regs == 2:
+		x = float64(math.Float32frombits(uint32(raw)))
+	case rt.float:
+		x = math.Float64frombits(raw)
+	case rt.bcd:
+		raw, err = modbusFromBcd(raw, rt.regs)
+		x = float64(raw)
+	case rt.signed:
+		x = float64(int64(raw<<(64-width)) >> (64 - width))
+	default:
+		x = float64(raw)
+	}
+	return
+}
+
+// modbusFloatRaw raw value of a float in a raw type, rounded to the nearest integer for the integer types
+func modbusFloatRaw(rt modbusRawType, x float64) (raw uint64, err error) {
+	width := 16 * rt.regs
+	switch {
+	case rt.float && rt.regs == 2:
+		return uint64(math.Float32bits(float32(x))), nil
+	case rt.float:
+		return math.Float64bits(x), nil
+	}
+	x = math.Round(x)
+	switch {
+	case math.IsNaN(x) || math.IsInf(x, 0):
+		return 0, marshalError("is not a number")
+	case rt.bcd:
+		if x < 0 || x >= math.Pow10(4*rt.regs) {
+			return 0, marshalError("overflows %s", rt.name)
+		}
+		return modbusToBcd(uint64(x)), nil
+	case rt.signed:
+		if x < -math.Ldexp(1, width-1) || x >= math.Ldexp(1, width-1) {
+			return 0, marshalError("overflows %s", rt.name)
+		}
+		raw = uint64(int64(x))
+	default:
+		if x < 0 || x >= math.Ldexp(1, width) {
+			return 0, marshalError("overflows %s", rt.name)
+		}
+		raw = uint64(x)
+	}
+	if width < 64 {
+		raw &= 1<<width - 1
+	}
+	return
+}
+
+func (f *modbusField) describe() string {
+	if f.bits > 0 {
+		return fmt.Sprintf("%d bits", f.bits)
+	}
+	return f.raw.name
+}
+
+// Marshal encode a struct into registers following its modbus struct tags
+//
+// Each exported field is mapped on registers by the options of its modbus tag, separated by commas:
+//
+//   - addr=N offset of the first register of the field from the start of the enclosing struct, by default the field
+//     follows the previous one
+//   - type=T type of the value in the registers: int16, uint16, int32, uint32, int64, uint64, float32, float64, or
+//     bcd16, bcd32 and bcd64 for 4, 8 and 16 decimal digits, by default the type of the field (int and uint are 16
+//     bits, bool is a uint16 register)
+//   - len=N number of registers of a string field, packed two characters per register, see DecodeString(), the
+//     order badc swaps the characters of each register
+//   - order=O byte order of the value, see ModbusByteOrder: abcd (default, most significant byte first), cdab, badc,
+//     dcba or a permutation of 8 bytes such as ghefcdab for the 64 bits values
+//   - scale=S the field holds the raw value multiplied by S, the field must be a float
+//   - bit=B,bits=W bit field of W bits (1 by default) from bit B of the value, the following bit fields without addr
+//     share the same registers
+//
+// Nested structs are laid out from their own address, arrays as consecutive elements. Fields tagged "-" and
+// unexported ones are ignored, blank (_) fields are padding of the size of their type. The registers not covered by
+// a field are 0.
+func Marshal(v any) (regs []uint16, err error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, marshalError("cannot marshal %T", v)
+	}
+	l, err := modbusLayoutOf(rv.Type())
+	if err != nil {
+		return
+	}
+	regs = make([]uint16, l.size)
+	for i := range l.fields {
+		f := &l.fields[i]
+		if f.padding {
+			continue
+		}
+		if err = f.encode(regs, f.value(rv)); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// Unmarshal decode registers into the struct pointed to by v following its modbus struct tags, see Marshal()
+//
+// The first register of regs is the start of the struct, regs must cover all its fields.
+func Unmarshal(regs []uint16, v any) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return marshalError("cannot unmarshal into %T", v)
+	}
+	rv = rv.Elem()
+	l, err := modbusLayoutOf(rv.Type())
+	if err != nil {
+		return
+	}
+	if len(regs) < l.size {
+		return marshalError("%d registers needed by %s, got %d", l.size, rv.Type(), len(regs))
+	}
+	for i := range l.fields {
+		f := &l.fields[i]
+		if f.padding {
+			continue
+		}
+		if err = f.decode(regs, f.value(rv)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// modbusSpan registers range
+type modbusSpan struct {
+	addr int
+	nb   int
+}
+
+// spans ranges of registers covered by the fields, merged when adjacent and split to limit registers
+func (l *modbusLayout) spans(padding bool, limit int) []modbusSpan {
+	var all []modbusSpan
+	for _, f := range l.fields {
+		if f.raw.regs > 0 && (padding || !f.padding) {
+			all = append(all, modbusSpan{f.addr, f.raw.regs})
+		}
+	}
+	return modbusCoalesce(all, limit)
+}
+
+// modbusCoalesce merge the overlapping and adjacent ranges of registers, split to limit registers
+func modbusCoalesce(all []modbusSpan, limit int) (spans []modbusSpan) {
+	all = slices.Clone(all)
+	slices.SortFunc(all, func(a, b modbusSpan) int { return a.addr - b.addr })
+	var merged []modbusSpan
+	for _, s := range all {
+		if n := len(merged); n > 0 && s.addr <= merged[n-1].addr+merged[n-1].nb {
+			merged[n-1].nb = max(merged[n-1].nb, s.addr+s.nb-merged[n-1].addr)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	for _, s := range merged {
+		for s.nb > limit {
+			spans = append(spans, modbusSpan{s.addr, limit})
+			s.addr += limit
+			s.nb -= limit
+		}
+		spans = append(spans, s)
+	}
+	return
+}
+
+// readStruct read the registers covered by the struct pointed to by v from addr and decode them
+func (x *Modbus) readStruct(addr int, v any, read func(addr int, nb int) ([]uint16, error)) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return marshalError("cannot unmarshal into %T", v)
+	}
+	l, err := modbusLayoutOf(rv.Elem().Type())
+	if err != nil {
+		return
+	}
+	regs := make([]uint16, l.size)
+	for _, s := range l.spans(true, MODBUS_MAX_READ_REGISTERS) {
+		values, err := read(addr+s.addr, s.nb)
+		if err != nil {
+			return err
+		}
+		copy(regs[s.addr:], values)
+	}
+	return Unmarshal(regs, v)
+}
+
+// ReadStruct read the holding registers of a struct and decode them, see Unmarshal()
+//
+// The struct starts at the register addr. The registers covered by its fields and padding are read with as few
+// requests as possible, the gaps between them are not read.
+func (x *Modbus) ReadStruct(addr int, v any) (err error) {
+	return x.readStruct(addr, v, x.ReadRegisters)
+}
+
+// ReadInputStruct read the input registers of a struct and decode them, see ReadStruct()
+func (x *Modbus) ReadInputStruct(addr int, v any) (err error) {
+	return x.readStruct(addr, v, x.ReadInputRegisters)
+}
+
+// WriteStruct encode a struct and write it to the holding registers from addr, see Marshal()
+//
+// The registers covered by the fields are written with as few requests as possible, the padding and the gaps between
+// fields are not written. The bits of a register that no bit field covers are written as 0.
+func (x *Modbus) WriteStruct(addr int, v any) (err error) {
+	regs, err := Marshal(v)
+	if err != nil {
+		return
+	}
+	l, err := modbusLayoutOf(reflect.Indirect(reflect.ValueOf(v)).Type())
+	if err != nil {
+		return
+	}
+	for _, s := range l.spans(false, MODBUS_MAX_WRITE_REGISTERS) {
+		if err = x.WriteRegisters(addr+s.addr, regs[s.addr:s.addr+s.nb]); err != nil {
+			return
+		}
+	}
+	return
+}
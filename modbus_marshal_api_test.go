@@ -0,0 +1,188 @@
+package libmodbusgo
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type marshalStatus struct {
+	Running bool  `modbus:"bit=0"`
+	Alarm   bool  `modbus:"bit=1"`
+	Mode    uint8 `modbus:"bit=4,bits=3"`
+}
+
+type marshalPhase struct {
+	Voltage float64 `modbus:"type=uint16,scale=0.5"`
+	Current float32 `modbus:"type=int16,scale=0.25"`
+}
+
+type marshalDevice struct {
+	Status  marshalStatus
+	Power   float32 `modbus:"order=cdab"`
+	Counter uint32
+	Offset  int16
+	_       [2]uint16
+	Phases  [3]marshalPhase
+	Energy  float64 `modbus:"order=dcba"`
+	Total   int64   `modbus:"addr=20,order=badc"`
+	Ignored int     `modbus:"-"`
+	Flags   [2]uint16
+	private int
+}
+
+func TestMarshal(t *testing.T) {
+	v := marshalDevice{
+		Status:  marshalStatus{Running: true, Mode: 5},
+		Power:   123456,
+		Counter: 0x12345678,
+		Offset:  -2,
+		Phases:  [3]marshalPhase{{230.5, -1.5}, {229, 2.25}, {0, 0}},
+		Energy:  -1.5,
+		Total:   -0x0102030405060708,
+		Ignored: 7,
+		Flags:   [2]uint16{0xAAAA, 0x5555},
+	}
+	regs, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{
+		0x0051,         // status
+		0x2000, 0x47F1, // power cdab
+		0x1234, 0x5678, // counter
+		0xFFFE, // offset
+		0, 0,   // padding
+		461, 0xFFFA, // phase 1
+		458, 9, // phase 2
+		0, 0, // phase 3
+		0x0000, 0x0000, 0x0000, 0xF8BF, // energy dcba
+		0, 0, // gap
+		0xFDFE, 0xFBFC, 0xF9FA, 0xF8F8, // total badc
+		0xAAAA, 0x5555,
+	}
+	if !slices.Equal(regs, want) {
+		t.Fatalf("% X\nwant % X", regs, want)
+	}
+	if f := ModbusGetFloatCdab(regs[1:3]); f != 123456 {
+		t.Errorf("libmodbus reads %v", f)
+	}
+
+	var w marshalDevice
+	if err = Unmarshal(regs, &w); err != nil {
+		t.Fatal(err)
+	}
+	v.Ignored = 0
+	if w != v {
+		t.Errorf("%+v\nwant %+v", w, v)
+	}
+
+	if err = Unmarshal(regs[:10], &w); err == nil {
+		t.Error("short registers")
+	}
+	for _, c := range []struct {
+		v    any
+		text string
+	}{
+		{struct{ S []uint16 }{}, "unsupported type"},
+		{struct {
+			I int32 `modbus:"type=float32"`
+		}{}, "needs a float field"},
+		{struct {
+			F float32 `modbus:"type=int16"`
+		}{F: -3}, ""},
+		{struct {
+			I int `modbus:"scale=2"`
+		}{}, "needs a float field"},
+		{struct {
+			I int `modbus:"order=xyzw"`
+		}{}, "unknown order"},
+		{struct {
+			I int `modbus:"bit=15,bits=2"`
+		}{}, "out of uint16"},
+		{struct {
+			I int `modbus:"bits=2"`
+		}{}, "bits without bit"},
+		{struct {
+			M uint8 `modbus:"bit=0,bits=3"`
+		}{M: 8}, "overflows 3 bits"},
+		{struct{ I int16 }{}, ""},
+		{struct {
+			U uint64 `modbus:"order=ghefcdab"`
+		}{}, ""},
+		{struct {
+			U uint32 `modbus:"order=ghefcdab"`
+		}{}, "does not apply"},
+		{struct {
+			I int32 `modbus:"type=uint16"`
+		}{I: -1}, "overflows uint16"},
+		{struct {
+			F float64 `modbus:"type=int16,scale=0.1"`
+		}{F: 4000}, "overflows int16"},
+	} {
+		_, err = Marshal(c.v)
+		if c.text == "" && err != nil || c.text != "" && (err == nil || !strings.Contains(err.Error(), c.text)) {
+			t.Errorf("%T: %v", c.v, err)
+		}
+	}
+	var i16 struct{ U uint8 }
+	if err = Unmarshal([]uint16{300}, &i16); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestModbus_ReadStruct(t *testing.T) {
+	mm := ModbusMappingNew(0, 0, 200, 200)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+
+	v := marshalDevice{Power: 1.5, Total: 42, Flags: [2]uint16{1, 2}, Status: marshalStatus{Alarm: true}}
+	if err := ctx.WriteStruct(100, &v); err != nil {
+		t.Fatal(err)
+	}
+	var w marshalDevice
+	if err := ctx.ReadStruct(100, &w); err != nil {
+		t.Fatal(err)
+	}
+	if w != v {
+		t.Errorf("%+v", w)
+	}
+	var big struct {
+		A uint16
+		_ [130]uint16
+	}
+	if err := ctx.ReadInputStruct(0, &big); err != nil {
+		t.Fatal(err)
+	}
+	// the fields are coalesced, the gap before Total is skipped, the padding is read but never written
+	want := []string{
+		"FF 10 00 64 00 06 0C 00 02 00 00 3F C0 00 00 00 00 00 00",
+		"FF 10 00 6C 00 0A 14 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00",
+		"FF 10 00 78 00 06 0C 00 00 00 00 00 00 2A 00 00 01 00 02",
+		"FF 03 00 64 00 12",
+		"FF 03 00 78 00 06",
+		"FF 04 00 00 00 7D",
+		"FF 04 00 7D 00 06",
+	}
+	if got := server.close(); !slices.Equal(got, want) {
+		t.Errorf("%q", got)
+	}
+
+	var wrong struct{ S string }
+	if err := ctx.ReadStruct(0, &wrong); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Error("string field")
+	}
+}
@@ -0,0 +1,36 @@
+package libmodbusgo
+
+import "sync"
+
+// modbusRawType type of a value in the registers, see the type= option of the modbus struct tags
+type modbusRawType struct {
+	name   string
+	regs   int // number of registers
+	signed bool
+	float  bool
+	bcd    bool // 4 decimal digits per register
+	str    bool // 2 characters per register
+}
+
+// modbusField field of a struct mapped on registers by its modbus tag
+type modbusField struct {
+	index   []int // path of the field from the struct, a field number or -(i+1) for the element i of an array
+	name    string
+	addr    int // offset of the first register from the start of the struct
+	raw     modbusRawType
+	order   []int   // permutation of the bytes, see ModbusByteOrder
+	swap    bool    // strings with the bytes of each register swapped
+	scale   float64 // 0 when the value is not scaled
+	bit     int     // first bit of a bit field, -1 for a whole value
+	bits    int     // width of a bit field
+	padding bool
+}
+
+// modbusLayout registers layout of a struct type
+type modbusLayout struct {
+	fields []modbusField
+	size   int // number of registers from the start of the struct to the end of its last field
+}
+
+// modbusLayouts cache of the layouts by struct type
+var modbusLayouts sync.Map
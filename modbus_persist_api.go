@@ -0,0 +1,233 @@
+package libmodbusgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+var modbusTables = []ModbusTable{
+	MODBUS_TABLE_BITS,
+	MODBUS_TABLE_INPUT_BITS,
+	MODBUS_TABLE_REGISTERS,
+	MODBUS_TABLE_INPUT_REGISTERS,
+}
+
+func (mm *ModbusMapping) getTab(t ModbusTable, addr int) uint16 {
+	switch t {
+	case MODBUS_TABLE_BITS:
+		return uint16(mm.GetTabBits(addr))
+	case MODBUS_TABLE_INPUT_BITS:
+		return uint16(mm.GetTabInputBits(addr))
+	case MODBUS_TABLE_REGISTERS:
+		return mm.GetTabRegisters(addr)
+	case MODBUS_TABLE_INPUT_REGISTERS:
+		return mm.GetTabInputRegisters(addr)
+	}
+	return 0
+}
+
+func (mm *ModbusMapping) setTab(t ModbusTable, addr int, v uint16) {
+	switch t {
+	case MODBUS_TABLE_BITS:
+		mm.SetTabBits(addr, byte(min(v, 1)))
+	case MODBUS_TABLE_INPUT_BITS:
+		mm.SetTabInputBits(addr, byte(min(v, 1)))
+	case MODBUS_TABLE_REGISTERS:
+		mm.SetTabRegisters(addr, v)
+	case MODBUS_TABLE_INPUT_REGISTERS:
+		mm.SetTabInputRegisters(addr, v)
+	}
+}
+
+func (s *ModbusMappingSnapshot) table(t ModbusTable) *ModbusTableSnapshot {
+	switch t {
+	case MODBUS_TABLE_BITS:
+		return &s.Bits
+	case MODBUS_TABLE_INPUT_BITS:
+		return &s.InputBits
+	case MODBUS_TABLE_REGISTERS:
+		return &s.Registers
+	case MODBUS_TABLE_INPUT_REGISTERS:
+		return &s.InputRegisters
+	}
+	return nil
+}
+
+// Lock lock the mapping against Reply() and Snapshot()
+//
+// The Set functions of the mapping are not synchronized, an application updating a mapping served or saved by
+// another goroutine must hold the lock while doing so.
+func (mm *ModbusMapping) Lock() {
+	mm.mu.Lock()
+}
+
+// Unlock unlock the mapping locked by Lock()
+func (mm *ModbusMapping) Unlock() {
+	mm.mu.Unlock()
+}
+
+// Snapshot copy the contents of the four tables of the mapping
+//
+// The copy holds the lock of the mapping, it is taken between two requests replied with Reply() and never sees a
+// write request half applied.
+func (mm *ModbusMapping) Snapshot() *ModbusMappingSnapshot {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	s := &ModbusMappingSnapshot{Version: MODBUS_SNAPSHOT_VERSION}
+	for _, t := range modbusTables {
+		start, nb := mm.tableRange(t)
+		ts := s.table(t)
+		ts.Start = start
+		ts.Values = make([]uint16, nb)
+		for i := range ts.Values {
+			ts.Values[i] = mm.getTab(t, start+i)
+		}
+	}
+	return s
+}
+
+// Restore copy a snapshot into the mapping
+//
+// The snapshot may come from a mapping with another layout, only the addresses present in both the snapshot and the
+// mapping are restored, the others are left untouched. The mapping is locked while restored.
+func (mm *ModbusMapping) Restore(s *ModbusMappingSnapshot) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	for _, t := range modbusTables {
+		start, nb := mm.tableRange(t)
+		ts := s.table(t)
+		from := max(start, ts.Start)
+		to := min(start+nb, ts.Start+len(ts.Values))
+		for addr := from; addr < to; addr++ {
+			mm.setTab(t, addr, ts.Values[addr-ts.Start])
+		}
+	}
+}
+
+func (s *ModbusMappingSnapshot) equal(o *ModbusMappingSnapshot) bool {
+	for _, t := range modbusTables {
+		a, b := s.table(t), o.table(t)
+		if a.Start != b.Start || !slices.Equal(a.Values, b.Values) {
+			return false
+		}
+	}
+	return true
+}
+
+// Save write a snapshot of the mapping to w as JSON
+func (mm *ModbusMapping) Save(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mm.Snapshot())
+}
+
+// Load read a snapshot written by Save() from r and restore it into the mapping
+func (mm *ModbusMapping) Load(r io.Reader) (err error) {
+	s := &ModbusMappingSnapshot{}
+	err = json.NewDecoder(r).Decode(s)
+	if err != nil {
+		return
+	}
+	if s.Version != MODBUS_SNAPSHOT_VERSION {
+		err = fmt.Errorf("unsupported snapshot version %d", s.Version)
+		return
+	}
+	mm.Restore(s)
+	return
+}
+
+// SaveFile write a snapshot of the mapping to the file at path
+//
+// The snapshot is written to a temporary file of the same directory, synced then renamed over path so a crash never
+// leaves a truncated snapshot behind.
+func (mm *ModbusMapping) SaveFile(path string) (err error) {
+	return saveSnapshotFile(path, mm.Snapshot())
+}
+
+// LoadFile restore the snapshot of the file at path into the mapping
+func (mm *ModbusMapping) LoadFile(path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	return mm.Load(f)
+}
+
+// Autosave save the mapping to the file at path every interval until ctx is done
+//
+// The file is only written when the contents changed since the last save, and once more when ctx is done. Autosave
+// returns the context error, or the first error writing the file.
+func (mm *ModbusMapping) Autosave(ctx context.Context, path string, interval time.Duration) (err error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := mm.Snapshot()
+	for {
+		select {
+		case <-ctx.Done():
+			if s := mm.Snapshot(); !s.equal(last) {
+				if err = saveSnapshotFile(path, s); err != nil {
+					return
+				}
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			if s := mm.Snapshot(); !s.equal(last) {
+				if err = saveSnapshotFile(path, s); err != nil {
+					return
+				}
+				last = s
+			}
+		}
+	}
+}
+
+func saveSnapshotFile(path string, s *ModbusMappingSnapshot) (err error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(s); err != nil {
+		return
+	}
+
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		return
+	}
+	if err = f.Chmod(0o644); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return
+	}
+	// persist the rename itself
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	return d.Sync()
+}
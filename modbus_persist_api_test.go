@@ -0,0 +1,102 @@
+package libmodbusgo
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestModbusMapping_SaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+
+	mm := ModbusMappingNewStartAddress(0, 10, 0, 0, 100, 10, 0, 5)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	mm.SetTabBits(3, 1)
+	mm.SetTabRegisters(100, 0x1234)
+	mm.SetTabRegisters(109, 0xABCD)
+	mm.SetTabInputRegisters(4, 42)
+	if err := mm.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// overlapping layout, registers 105..114
+	restored := ModbusMappingNewStartAddress(0, 5, 0, 0, 105, 10, 0, 0)
+	if restored == nil {
+		t.FailNow()
+	}
+	defer restored.Free()
+	restored.SetTabRegisters(114, 7)
+	if err := restored.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if restored.GetTabBits(3) != 1 {
+		t.Error("bit 3 not restored")
+	}
+	if restored.GetTabRegisters(109) != 0xABCD || restored.GetTabRegisters(105) != 0 {
+		t.Error("registers not restored")
+	}
+	if restored.GetTabRegisters(114) != 7 {
+		t.Error("register outside of the snapshot modified")
+	}
+}
+
+func TestModbusMapping_Autosave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+
+	mm := ModbusMappingNew(0, 0, 10, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- mm.Autosave(ctx, path, 10*time.Millisecond)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("unchanged mapping saved")
+	}
+	// written by a server and by the application while saved
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	client := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if client == nil {
+		t.FailNow()
+	}
+	defer client.Free()
+	client.SetResponseTimeout(100 * time.Millisecond)
+	for i := range 10 {
+		if err := client.WriteRegister(2, uint16(90+i)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	server.close()
+	mm.Lock()
+	mm.SetTabRegisters(3, 7)
+	mm.Unlock()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error(err)
+	}
+
+	restored := ModbusMappingNew(0, 0, 10, 0)
+	if restored == nil {
+		t.FailNow()
+	}
+	defer restored.Free()
+	if err := restored.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if restored.GetTabRegisters(2) != 99 || restored.GetTabRegisters(3) != 7 {
+		t.Error("register not saved")
+	}
+}
@@ -0,0 +1,19 @@
+package libmodbusgo
+
+// MODBUS_SNAPSHOT_VERSION version of the snapshot file format written by ModbusMapping.Save()
+const MODBUS_SNAPSHOT_VERSION = 1
+
+// ModbusTableSnapshot contents of one table of a mapping
+type ModbusTableSnapshot struct {
+	Start  int      `json:"start"`
+	Values []uint16 `json:"values"` // bits are stored as 0 or 1
+}
+
+// ModbusMappingSnapshot contents of the four tables of a mapping
+type ModbusMappingSnapshot struct {
+	Version        int                 `json:"version"`
+	Bits           ModbusTableSnapshot `json:"bits"`
+	InputBits      ModbusTableSnapshot `json:"input_bits"`
+	Registers      ModbusTableSnapshot `json:"registers"`
+	InputRegisters ModbusTableSnapshot `json:"input_registers"`
+}
@@ -0,0 +1,243 @@
+package libmodbusgo
+
+import (
+	"math"
+	"syscall"
+)
+
+func (p *ModbusPoint) table() ModbusTable {
+	if p.Table == MODBUS_TABLE_NONE {
+		return MODBUS_TABLE_REGISTERS
+	}
+	return p.Table
+}
+
+func (p *ModbusPoint) bit() bool {
+	t := p.table()
+	return t == MODBUS_TABLE_BITS || t == MODBUS_TABLE_INPUT_BITS
+}
+
+// raw type and byte order of the raw value
+func (p *ModbusPoint) raw() (rt modbusRawType, order []int, err error) {
+	switch p.table() {
+	case MODBUS_TABLE_BITS, MODBUS_TABLE_INPUT_BITS:
+		return modbusRawType{name: "bit", regs: 1}, nil, nil
+	case MODBUS_TABLE_REGISTERS, MODBUS_TABLE_INPUT_REGISTERS:
+	default:
+		return rt, nil, marshalError("point %s: unknown table %d", p.Name, p.Table)
+	}
+	name := p.Type
+	if name == "" {
+		name = "uint16"
+	}
+	rt, ok := modbusRawTypes[name]
+	if !ok {
+		return rt, nil, marshalError("point %s: unknown type %q", p.Name, p.Type)
+	}
+	if order, err = p.Order.permutation(2 * rt.regs); err != nil {
+		return rt, nil, marshalError("point %s: %s", p.Name, err.(*Error).message)
+	}
+	return
+}
+
+func (p *ModbusPoint) gain(sf int) float64 {
+	g := p.Scale
+	if g == 0 {
+		g = 1
+	}
+	return g * math.Pow10(sf)
+}
+
+// Size number of registers, or bits, of the point
+func (p *ModbusPoint) Size() int {
+	rt, _, err := p.raw()
+	if err != nil {
+		return 0
+	}
+	return rt.regs
+}
+
+// Decode engineering value of the registers of the point, regs starting at Addr and sf being the power of ten of the
+// ScaleFactor register
+//
+// The registers of a point of the bit tables are the bits read, one per register.
+func (p *ModbusPoint) Decode(regs []uint16, sf int) (value float64, err error) {
+	rt, order, err := p.raw()
+	if err != nil {
+		return
+	}
+	if len(regs) < rt.regs {
+		return 0, marshalError("point %s: %d registers needed, got %d", p.Name, rt.regs, len(regs))
+	}
+	if p.bit() {
+		if regs[0] != 0 {
+			value = 1
+		}
+		return
+	}
+	x, err := modbusRawFloat(rt, order, regs)
+	if err != nil {
+		return 0, marshalError("point %s: %s", p.Name, err.(*Error).message)
+	}
+	value = x*p.gain(sf) + p.Offset
+	if p.Min < p.Max {
+		value = min(max(value, p.Min), p.Max)
+	}
+	return
+}
+
+// Encode registers of an engineering value of the point, see Decode()
+//
+// A value out of the limits of the point or of its raw type returns EINVAL, the integer raw values are rounded.
+func (p *ModbusPoint) Encode(value float64, sf int) (regs []uint16, err error) {
+	rt, order, err := p.raw()
+	if err != nil {
+		return
+	}
+	if p.Min < p.Max && (value < p.Min || value > p.Max) {
+		return nil, marshalError("point %s: %v out of [%v, %v]", p.Name, value, p.Min, p.Max)
+	}
+	if p.bit() {
+		if value != 0 && value != 1 {
+			return nil, marshalError("point %s: %v is not a bit", p.Name, value)
+		}
+		return []uint16{uint16(value)}, nil
+	}
+	raw, err := modbusFloatRaw(rt, (value-p.Offset)/p.gain(sf))
+	if err != nil {
+		return nil, marshalError("point %s: %v %s", p.Name, value, err.(*Error).message)
+	}
+	regs = make([]uint16, rt.regs)
+	modbusSetRaw(regs, order, raw)
+	return
+}
+
+// readTable read registers or bits of a data table, the bits being returned one per register
+func (x *Modbus) readTable(table ModbusTable, addr int, nb int) (regs []uint16, err error) {
+	var bits []byte
+	switch table {
+	case MODBUS_TABLE_BITS:
+		bits, err = x.ReadBits(addr, nb)
+	case MODBUS_TABLE_INPUT_BITS:
+		bits, err = x.ReadInputBits(addr, nb)
+	case MODBUS_TABLE_REGISTERS:
+		return x.ReadRegisters(addr, nb)
+	case MODBUS_TABLE_INPUT_REGISTERS:
+		return x.ReadInputRegisters(addr, nb)
+	default:
+		return nil, ErrorCode(syscall.EINVAL).Error()
+	}
+	for _, b := range bits {
+		regs = append(regs, uint16(b))
+	}
+	return
+}
+
+// scaleFactor power of ten of the ScaleFactor register r, 0x8000 tells the scale factor is not implemented
+func (p *ModbusPoint) scaleFactor(r uint16) (sf int, err error) {
+	if r == 0x8000 {
+		return 0, marshalError("point %s: scale factor not implemented", p.Name)
+	}
+	return int(int16(r)), nil
+}
+
+// ReadPoint read the engineering value of a point, see ReadPoints()
+func (x *Modbus) ReadPoint(p *ModbusPoint) (value float64, err error) {
+	values, err := x.ReadPoints([]ModbusPoint{*p})
+	if err != nil {
+		return
+	}
+	return values[0], nil
+}
+
+// ReadPoints read the engineering values of points
+//
+// The registers of the points and of their scale factors are read with as few requests as possible per data table,
+// the gaps between them are not read. The values are in the order of the points. A scale factor register holding
+// 0x8000, not implemented, returns EINVAL.
+func (x *Modbus) ReadPoints(points []ModbusPoint) (values []float64, err error) {
+	tables := []ModbusTable{MODBUS_TABLE_BITS, MODBUS_TABLE_INPUT_BITS, MODBUS_TABLE_REGISTERS, MODBUS_TABLE_INPUT_REGISTERS}
+	spans := map[ModbusTable][]modbusSpan{}
+	for i := range points {
+		p := &points[i]
+		rt, _, err := p.raw()
+		if err != nil {
+			return nil, err
+		}
+		t := p.table()
+		spans[t] = append(spans[t], modbusSpan{p.Addr, rt.regs})
+		if p.ScaleFactor != nil && !p.bit() {
+			spans[t] = append(spans[t], modbusSpan{*p.ScaleFactor, 1})
+		}
+	}
+	data := map[ModbusTable]map[int]uint16{}
+	for _, t := range tables {
+		limit := MODBUS_MAX_READ_REGISTERS
+		if t == MODBUS_TABLE_BITS || t == MODBUS_TABLE_INPUT_BITS {
+			limit = MODBUS_MAX_READ_BITS
+		}
+		data[t] = map[int]uint16{}
+		for _, s := range modbusCoalesce(spans[t], limit) {
+			regs, err := x.readTable(t, s.addr, s.nb)
+			if err != nil {
+				return nil, err
+			}
+			for i, r := range regs {
+				data[t][s.addr+i] = r
+			}
+		}
+	}
+
+	values = make([]float64, len(points))
+	for i := range points {
+		p := &points[i]
+		d := data[p.table()]
+		regs := make([]uint16, p.Size())
+		for j := range regs {
+			regs[j] = d[p.Addr+j]
+		}
+		sf := 0
+		if p.ScaleFactor != nil {
+			if sf, err = p.scaleFactor(d[*p.ScaleFactor]); err != nil {
+				return nil, err
+			}
+		}
+		if values[i], err = p.Decode(regs, sf); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// WritePoint write the engineering value of a point, see ModbusPoint.Encode()
+//
+// The scale factor register of the point is read first, 0x8000 returns EINVAL. The points of one register are written with
+// MODBUS_FC_WRITE_SINGLE_REGISTER and the coils with MODBUS_FC_WRITE_SINGLE_COIL, the points of the input tables
+// return EINVAL.
+func (x *Modbus) WritePoint(p *ModbusPoint, value float64) (err error) {
+	t := p.table()
+	if t == MODBUS_TABLE_INPUT_BITS || t == MODBUS_TABLE_INPUT_REGISTERS {
+		return marshalError("point %s: %s are read only", p.Name, t)
+	}
+	sf := 0
+	if p.ScaleFactor != nil && !p.bit() {
+		r, err := x.ReadRegisters(*p.ScaleFactor, 1)
+		if err != nil {
+			return err
+		}
+		if sf, err = p.scaleFactor(r[0]); err != nil {
+			return err
+		}
+	}
+	regs, err := p.Encode(value, sf)
+	if err != nil {
+		return
+	}
+	switch {
+	case p.bit():
+		return x.WriteBit(p.Addr, byte(regs[0]))
+	case len(regs) == 1:
+		return x.WriteRegister(p.Addr, regs[0])
+	}
+	return x.WriteRegisters(p.Addr, regs)
+}
@@ -0,0 +1,166 @@
+package libmodbusgo
+
+import (
+	"math"
+	"net"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestModbusPoint(t *testing.T) {
+	sf := 10
+	for _, c := range []struct {
+		p     ModbusPoint
+		regs  []uint16
+		sf    int
+		value float64
+	}{
+		{ModbusPoint{Name: "voltage", Scale: 0.1}, []uint16{2305}, 0, 230.5},
+		{ModbusPoint{Name: "temperature", Type: "int16", Scale: 0.1, Offset: -40}, []uint16{0xFFF6}, 0, -41},
+		{ModbusPoint{Name: "energy", Type: "uint32", Order: MODBUS_ORDER_CDAB, Scale: 0.01}, []uint16{0x86A0, 0x0001}, 0, 1000},
+		{ModbusPoint{Name: "power", Type: "int16", ScaleFactor: &sf}, []uint16{1234}, -2, 12.34},
+		{ModbusPoint{Name: "frequency", Type: "float32"}, []uint16{0x4248, 0}, 0, 50},
+		{ModbusPoint{Name: "counter", Type: "bcd16"}, []uint16{0x1234}, 0, 1234},
+		{ModbusPoint{Name: "alarm", Table: MODBUS_TABLE_BITS}, []uint16{1}, 0, 1},
+	} {
+		v, err := c.p.Decode(c.regs, c.sf)
+		if err != nil || math.Abs(v-c.value) > 1e-9 {
+			t.Errorf("%s: %v %v", c.p.Name, v, err)
+		}
+		r, err := c.p.Encode(c.value, c.sf)
+		if err != nil || !slices.Equal(r, c.regs) {
+			t.Errorf("%s: % X %v", c.p.Name, r, err)
+		}
+	}
+
+	p := ModbusPoint{Name: "setpoint", Type: "int16", Scale: 0.5, Min: 0, Max: 100}
+	if v, _ := p.Decode([]uint16{300}, 0); v != 100 {
+		t.Errorf("clamped %v", v)
+	}
+	if _, err := p.Encode(101, 0); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Error("out of the limits")
+	}
+	p = ModbusPoint{Name: "small", Type: "int8"}
+	if _, err := p.Decode([]uint16{0}, 0); err == nil {
+		t.Error("unknown type")
+	}
+	p = ModbusPoint{Name: "small", Type: "uint16"}
+	if _, err := p.Encode(70000, 0); err == nil {
+		t.Error("overflow")
+	}
+	if _, err := p.Decode(nil, 0); err == nil {
+		t.Error("no registers")
+	}
+	p = ModbusPoint{Name: "order", Type: "uint32", Order: MODBUS_ORDER_ABCDEFGH}
+	if p.Size() != 0 {
+		t.Error("order of 8 bytes")
+	}
+}
+
+func TestModbus_ReadPoints(t *testing.T) {
+	mm := ModbusMappingNew(10, 10, 200, 200)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+
+	sf := 104
+	points := []ModbusPoint{
+		{Name: "voltage", Addr: 100, Scale: 0.1, Unit: "V"},
+		{Name: "power", Addr: 101, Type: "int32", ScaleFactor: &sf, Unit: "W"},
+		{Name: "temperature", Table: MODBUS_TABLE_INPUT_REGISTERS, Addr: 5, Type: "int16", Scale: 0.1, Unit: "°C"},
+		{Name: "run", Table: MODBUS_TABLE_BITS, Addr: 3},
+	}
+	mm.SetTabRegisters(104, 0xFFFF)
+	mm.SetTabInputRegisters(5, 215)
+	if err := ctx.WritePoint(&points[0], 230.1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.WritePoint(&points[1], -1500.3); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.WritePoint(&points[3], 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.WritePoint(&points[2], 1); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Error("read only table")
+	}
+	values, err := ctx.ReadPoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{230.1, -1500.3, 21.5, 1} {
+		if math.Abs(values[i]-want) > 1e-9 {
+			t.Errorf("%s: %v", points[i].Name, values[i])
+		}
+	}
+	if v, err := ctx.ReadPoint(&points[2]); err != nil || math.Abs(v-21.5) > 1e-9 {
+		t.Errorf("%v %v", v, err)
+	}
+
+	// scale factor not implemented
+	mm.SetTabRegisters(104, 0x8000)
+	if _, err := ctx.ReadPoint(&points[1]); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Errorf("%v", err)
+	}
+	if err := ctx.WritePoint(&points[1], 1); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Errorf("%v", err)
+	}
+
+	// the registers of the points and of the scale factor are read by table, the gap before the scale factor is not read
+	want := []string{
+		"FF 06 00 64 08 FD",
+		"FF 03 00 68 00 01",
+		"FF 10 00 65 00 02 04 FF FF C5 65",
+		"FF 05 00 03 FF 00",
+		"FF 01 00 03 00 01",
+		"FF 03 00 64 00 03",
+		"FF 03 00 68 00 01",
+		"FF 04 00 05 00 01",
+		"FF 04 00 05 00 01",
+		"FF 03 00 65 00 02",
+		"FF 03 00 68 00 01",
+		"FF 03 00 68 00 01",
+	}
+	if got := server.close(); !slices.Equal(got, want) {
+		t.Errorf("%q", got)
+	}
+}
+
+func TestModbusConvertUnit(t *testing.T) {
+	for _, c := range []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{1500, "W", "kW", 1.5},
+		{2.5, "MWh", "kWh", 2500},
+		{100, "°C", "°F", 212},
+		{32, "F", "C", 0},
+		{0, "C", "K", 273.15},
+		{1, "bar", "kPa", 100},
+		{90, "min", "h", 1.5},
+		{3, "kV", "V", 3000},
+	} {
+		v, err := ModbusConvertUnit(c.value, c.from, c.to)
+		if err != nil || math.Abs(v-c.want) > 1e-9 {
+			t.Errorf("%v %s in %s: %v %v", c.value, c.from, c.to, v, err)
+		}
+	}
+	if _, err := ModbusConvertUnit(1, "V", "A"); err == nil {
+		t.Error("different quantities")
+	}
+	if _, err := ModbusConvertUnit(1, "furlong", "m"); err == nil {
+		t.Error("unknown unit")
+	}
+}
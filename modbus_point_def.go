@@ -0,0 +1,28 @@
+package libmodbusgo
+
+// ModbusPoint engineering value stored in a data table of a device
+//
+// The value is raw*Scale*10^sf + Offset where raw is the value of the registers and sf the power of ten read from
+// the ScaleFactor register, 0 without it. The points of the bit tables are 0 or 1.
+type ModbusPoint struct {
+	Name  string
+	Table ModbusTable // MODBUS_TABLE_REGISTERS when MODBUS_TABLE_NONE
+	Addr  int
+
+	// Type type of the raw value as the type= option of the modbus struct tags (int16, uint32, float32, bcd16...),
+	// uint16 when empty
+	Type  string
+	Order ModbusByteOrder
+
+	Scale  float64 // 1 when 0
+	Offset float64
+	// ScaleFactor address of the int16 register of the same table holding the power of ten of the value, as the _SF
+	// registers of SunSpec, nil when the value has none
+	ScaleFactor *int
+
+	Unit string
+	// Min and Max limits of the value when Min < Max, the values read are clamped and the values written out of the
+	// limits are refused
+	Min float64
+	Max float64
+}
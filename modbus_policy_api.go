@@ -0,0 +1,157 @@
+package libmodbusgo
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Check evaluate the policy for a decoded request
+//
+// The Check() function shall return the action of the first rule matching the request sent by remote (an invalid
+// address on serial lines), the exception to reply when the request is denied and the name of the matching rule.
+func (p *ModbusPolicy) Check(r *ModbusRequest, remote netip.Addr) (action ModbusPolicyAction, exception ModbusException, rule string) {
+	action = p.Default
+	exception = p.Exception
+	for i := range p.Rules {
+		pr := &p.Rules[i]
+		if !pr.match(r, remote) {
+			continue
+		}
+		action = pr.Action
+		rule = pr.Name
+		if pr.Exception != 0 {
+			exception = pr.Exception
+		}
+		break
+	}
+	if exception == 0 {
+		exception = MODBUS_EXCEPTION_ILLEGAL_FUNCTION
+	}
+	return
+}
+
+func (pr *ModbusPolicyRule) match(r *ModbusRequest, remote netip.Addr) bool {
+	if len(pr.Functions) > 0 && !slices.Contains(pr.Functions, r.Function) {
+		return false
+	}
+	if len(pr.Slaves) > 0 && !slices.Contains(pr.Slaves, r.Slave) {
+		return false
+	}
+	if len(pr.Remotes) > 0 {
+		if !remote.IsValid() {
+			return false
+		}
+		if !slices.ContainsFunc(pr.Remotes, func(prefix netip.Prefix) bool {
+			return prefix.Contains(remote.Unmap())
+		}) {
+			return false
+		}
+	}
+	if pr.Table != MODBUS_TABLE_NONE || pr.Nb > 0 || pr.WriteOnly || pr.ReadOnly {
+		if pr.Table != MODBUS_TABLE_NONE && pr.Table != r.Table {
+			return false
+		}
+		if !slices.ContainsFunc(r.ranges(), func(rg modbusRange) bool {
+			if (pr.WriteOnly && !rg.write) || (pr.ReadOnly && rg.write) {
+				return false
+			}
+			return pr.Nb == 0 || (rg.addr < pr.Start+pr.Nb && pr.Start < rg.addr+rg.nb)
+		}) {
+			return false
+		}
+	}
+	if pr.Active != nil && !pr.Active() {
+		return false
+	}
+	return true
+}
+
+func (ev ModbusAuditEvent) String() string {
+	remote := "serial"
+	if ev.Remote.IsValid() {
+		remote = ev.Remote.String()
+	}
+	rule := ev.Rule
+	if rule == "" {
+		rule = "default"
+	}
+	return fmt.Sprintf("%s %s slave %d function 0x%02X %s %d+%d rule %s: %s (%s)",
+		ev.Time.Format(time.RFC3339), remote, ev.Request.Slave, ev.Request.Function, ev.Request.Table,
+		ev.Request.Addr, ev.Request.Nb, rule, ev.Action, ev.Exception)
+}
+
+// RemoteAddr return the address of the client connected to the socket of the context
+//
+// The RemoteAddr() function shall return the peer address of the current socket, it fails with ENOTSOCK on serial
+// lines.
+func (x *Modbus) RemoteAddr() (addr netip.Addr, err error) {
+	if x.be != nil {
+		conn, _ := x.be.current()
+		if c, ok := conn.(interface{ RemoteAddr() net.Addr }); ok && c.RemoteAddr() != nil {
+			ap, err := netip.ParseAddrPort(c.RemoteAddr().String())
+			if err != nil {
+				return addr, err
+			}
+			return ap.Addr().Unmap(), nil
+		}
+	}
+	s, err := x.GetSocket()
+	if err != nil {
+		return
+	}
+	return socketPeerAddr(s)
+}
+
+// ReplyPolicy send a response to the received request if the policy allows it
+//
+// The ReplyPolicy() function shall decode the request, evaluate the policy against it and the remote address of the
+// client then either reply with the mapping like Reply() or reply with the exception of the denying rule. Denials are
+// reported to the Audit function of the policy.
+func (x *Modbus) ReplyPolicy(req []byte, mm *ModbusMapping, p *ModbusPolicy) (err error) {
+	r, err := x.DecodeRequest(req)
+	if err != nil {
+		return
+	}
+	if exception := p.authorize(x, r); exception != 0 {
+		return x.ReplyException(req, uint(exception))
+	}
+	return x.Reply(req, mm)
+}
+
+// ModbusPolicyMiddleware enforce a policy in a server middleware chain
+//
+// Denied requests are reported to the Audit function of the policy and short-circuited with the exception of the
+// denying rule.
+func ModbusPolicyMiddleware(p *ModbusPolicy) ModbusMiddleware {
+	return func(next ModbusHandler) ModbusHandler {
+		return func(x *Modbus, r *ModbusRequest) error {
+			if exception := p.authorize(x, r); exception != 0 {
+				return exception
+			}
+			return next(x, r)
+		}
+	}
+}
+
+// authorize check the request against the policy and audit denials, it returns the exception to reply or 0
+func (p *ModbusPolicy) authorize(x *Modbus, r *ModbusRequest) ModbusException {
+	remote, _ := x.RemoteAddr()
+	action, exception, rule := p.Check(r, remote)
+	if action == MODBUS_POLICY_ALLOW {
+		return 0
+	}
+	if p.Audit != nil {
+		p.Audit(ModbusAuditEvent{
+			Time:      time.Now(),
+			Remote:    remote,
+			Request:   r,
+			Rule:      rule,
+			Action:    action,
+			Exception: exception,
+		})
+	}
+	return exception
+}
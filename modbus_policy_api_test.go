@@ -0,0 +1,203 @@
+package libmodbusgo
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestModbusPolicy_Check(t *testing.T) {
+	interlock := false
+	p := &ModbusPolicy{
+		Rules: []ModbusPolicyRule{
+			{
+				Name:      "read-only setpoints",
+				Action:    MODBUS_POLICY_DENY,
+				Table:     MODBUS_TABLE_REGISTERS,
+				Start:     100,
+				Nb:        10,
+				WriteOnly: true,
+				Exception: MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS,
+			},
+			{
+				Name:      "interlock",
+				Action:    MODBUS_POLICY_DENY,
+				Table:     MODBUS_TABLE_BITS,
+				WriteOnly: true,
+				Active:    func() bool { return interlock },
+			},
+			{
+				Name:      "engineering station",
+				Action:    MODBUS_POLICY_ALLOW,
+				Functions: []int{MODBUS_FC_WRITE_SINGLE_REGISTER, MODBUS_FC_WRITE_MULTIPLE_REGISTERS},
+				Remotes:   []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+			},
+			{
+				Name:   "no other writes",
+				Action: MODBUS_POLICY_DENY,
+				Functions: []int{MODBUS_FC_WRITE_SINGLE_COIL, MODBUS_FC_WRITE_SINGLE_REGISTER,
+					MODBUS_FC_WRITE_MULTIPLE_COILS, MODBUS_FC_WRITE_MULTIPLE_REGISTERS},
+				Remotes: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+			},
+		},
+	}
+	station := netip.MustParseAddr("10.0.0.5")
+	other := netip.MustParseAddr("192.168.1.7")
+
+	tests := []struct {
+		name      string
+		req       ModbusRequest
+		remote    netip.Addr
+		interlock bool
+		action    ModbusPolicyAction
+		exception ModbusException
+	}{
+		{"read setpoints", ModbusRequest{Function: MODBUS_FC_READ_HOLDING_REGISTERS, Table: MODBUS_TABLE_REGISTERS, Addr: 95, Nb: 10}, station, false, MODBUS_POLICY_ALLOW, MODBUS_EXCEPTION_ILLEGAL_FUNCTION},
+		{"write setpoint", ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_REGISTER, Table: MODBUS_TABLE_REGISTERS, Addr: 109, Nb: 1}, station, false, MODBUS_POLICY_DENY, MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS},
+		{"write and read setpoints", ModbusRequest{Function: MODBUS_FC_WRITE_AND_READ_REGISTERS, Table: MODBUS_TABLE_REGISTERS, Addr: 100, Nb: 1, WriteAddr: 0, WriteNb: 1}, station, false, MODBUS_POLICY_ALLOW, MODBUS_EXCEPTION_ILLEGAL_FUNCTION},
+		{"write from station", ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_REGISTER, Table: MODBUS_TABLE_REGISTERS, Addr: 0, Nb: 1}, station, false, MODBUS_POLICY_ALLOW, MODBUS_EXCEPTION_ILLEGAL_FUNCTION},
+		{"write from other", ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_REGISTER, Table: MODBUS_TABLE_REGISTERS, Addr: 0, Nb: 1}, other, false, MODBUS_POLICY_DENY, MODBUS_EXCEPTION_ILLEGAL_FUNCTION},
+		{"coil during interlock", ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_COIL, Table: MODBUS_TABLE_BITS, Addr: 3, Nb: 1}, netip.Addr{}, true, MODBUS_POLICY_DENY, MODBUS_EXCEPTION_ILLEGAL_FUNCTION},
+		{"coil on serial line", ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_COIL, Table: MODBUS_TABLE_BITS, Addr: 3, Nb: 1}, netip.Addr{}, false, MODBUS_POLICY_ALLOW, MODBUS_EXCEPTION_ILLEGAL_FUNCTION},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interlock = tt.interlock
+			action, exception, _ := p.Check(&tt.req, tt.remote)
+			if action != tt.action || exception != tt.exception {
+				t.Errorf("got %s (%s), want %s (%s)", action, exception, tt.action, tt.exception)
+			}
+		})
+	}
+}
+
+func TestModbus_ReplyPolicy(t *testing.T) {
+	mm := conformanceMapping(t)
+	defer mm.Free()
+	var mu sync.Mutex
+	var events []ModbusAuditEvent
+	p := &ModbusPolicy{
+		Rules: []ModbusPolicyRule{
+			{
+				Name:      "read-only setpoints",
+				Action:    MODBUS_POLICY_DENY,
+				Table:     MODBUS_TABLE_REGISTERS,
+				Start:     5,
+				Nb:        5,
+				WriteOnly: true,
+				Exception: MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS,
+			},
+			{
+				Name:      "local coils",
+				Action:    MODBUS_POLICY_DENY,
+				Functions: []int{MODBUS_FC_WRITE_SINGLE_COIL},
+				Remotes:   []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+			},
+		},
+		Exception: MODBUS_EXCEPTION_SLAVE_OR_SERVER_FAILURE,
+		Audit: func(ev ModbusAuditEvent) {
+			mu.Lock()
+			events = append(events, ev)
+			mu.Unlock()
+		},
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c2, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ModbusNewConn(c1, MODBUS_FRAMING_TCP)
+	if server == nil {
+		t.FailNow()
+	}
+	server.SetIndicationTimeout(50 * time.Millisecond)
+	stop := make(chan struct{})
+	var done sync.WaitGroup
+	done.Add(1)
+	go func() {
+		defer done.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			req, err := server.Receive()
+			if err != nil || len(req) == 0 {
+				continue
+			}
+			server.ReplyPolicy(req, mm, p)
+		}
+	}()
+	defer func() {
+		close(stop)
+		done.Wait()
+		server.Free()
+	}()
+
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+
+	// the exception of the rule, then the exception of the policy
+	setpoint, coil := mm.getTab(MODBUS_TABLE_REGISTERS, 7), mm.getTab(MODBUS_TABLE_BITS, 3)
+	if err = ctx.WriteRegister(7, 0x1234); conformanceCode(err) != EMBXILADD {
+		t.Errorf("%v", err)
+	}
+	if err = ctx.WriteBit(3, byte(coil^1)); conformanceCode(err) != EMBXSFAIL {
+		t.Errorf("%v", err)
+	}
+	// allowed requests are replied with the mapping, the denied write left it untouched
+	if err = ctx.WriteRegister(2, 0x55); err != nil {
+		t.Error(err)
+	}
+	values, err := ctx.ReadRegisters(2, 6)
+	if err != nil || values[0] != 0x55 || values[5] != setpoint {
+		t.Errorf("%04X %v", values, err)
+	}
+	if bits, err := ctx.ReadBits(3, 1); err != nil || uint16(bits[0]) != coil {
+		t.Errorf("%v %v", bits, err)
+	}
+
+	// only the denials are audited
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("%d events", len(events))
+	}
+	local := netip.MustParseAddr("127.0.0.1")
+	for i, want := range []struct {
+		rule      string
+		function  int
+		addr      int
+		exception ModbusException
+	}{
+		{"read-only setpoints", MODBUS_FC_WRITE_SINGLE_REGISTER, 7, MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS},
+		{"local coils", MODBUS_FC_WRITE_SINGLE_COIL, 3, MODBUS_EXCEPTION_SLAVE_OR_SERVER_FAILURE},
+	} {
+		ev := events[i]
+		if ev.Rule != want.rule || ev.Action != MODBUS_POLICY_DENY || ev.Exception != want.exception ||
+			ev.Remote != local || ev.Request.Function != want.function || ev.Request.Addr != want.addr ||
+			time.Since(ev.Time) > time.Minute {
+			t.Errorf("%+v", ev)
+		}
+		if s := ev.String(); !strings.Contains(s, "127.0.0.1 slave") || !strings.Contains(s, "rule "+want.rule+": deny") {
+			t.Error(s)
+		}
+	}
+}
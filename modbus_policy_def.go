@@ -0,0 +1,66 @@
+package libmodbusgo
+
+import (
+	"net/netip"
+	"time"
+)
+
+type ModbusPolicyAction int
+
+const (
+	MODBUS_POLICY_ALLOW ModbusPolicyAction = iota
+	MODBUS_POLICY_DENY
+)
+
+func (a ModbusPolicyAction) String() string {
+	if a == MODBUS_POLICY_DENY {
+		return "deny"
+	}
+	return "allow"
+}
+
+// ModbusPolicyRule access rule of a server policy
+//
+// Every non-zero criterion must match for the rule to apply, an empty criterion matches anything.
+type ModbusPolicyRule struct {
+	Name      string             // reported in audit events
+	Action    ModbusPolicyAction // MODBUS_POLICY_ALLOW or MODBUS_POLICY_DENY
+	Functions []int              // function codes, eg. MODBUS_FC_WRITE_SINGLE_COIL
+	Table     ModbusTable        // data table, MODBUS_TABLE_NONE matches every table
+	Start     int                // first address of the protected range
+	Nb        int                // size of the protected range, 0 matches every address
+	WriteOnly bool               // only match the written part of write requests
+	ReadOnly  bool               // only match the read part of read requests
+	Slaves    []int              // slave numbers or unit identifiers
+	Remotes   []netip.Prefix     // client networks, never match on serial lines
+	Active    func() bool        // condition such as an interlock, nil is always active
+
+	// Exception replied on denial, 0 uses the policy exception
+	Exception ModbusException
+}
+
+// ModbusPolicy declarative access control applied before replying to a request
+//
+// Rules are evaluated in order and the first matching rule decides, Default applies when no rule matches. Denied
+// requests are answered with an exception and reported to Audit.
+type ModbusPolicy struct {
+	Rules   []ModbusPolicyRule
+	Default ModbusPolicyAction
+
+	// Exception replied on denial when the rule has none, 0 uses MODBUS_EXCEPTION_ILLEGAL_FUNCTION as
+	// required by the Modbus/TCP Security specification for unauthorized requests.
+	Exception ModbusException
+
+	// Audit is called for every denied request, nil disables auditing
+	Audit func(ev ModbusAuditEvent)
+}
+
+// ModbusAuditEvent denied request reported by a policy
+type ModbusAuditEvent struct {
+	Time      time.Time
+	Remote    netip.Addr // invalid on serial lines
+	Request   *ModbusRequest
+	Rule      string // name of the matching rule, empty when the default action applied
+	Action    ModbusPolicyAction
+	Exception ModbusException
+}
@@ -0,0 +1,383 @@
+package libmodbusgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ModbusProfileUnmarshalers functions decoding the profile files by extension for ModbusProfileLoad()
+//
+// JSON files are decoded out of the box, the profile types carry yaml tags so a YAML package can be registered, eg.
+//
+//	ModbusProfileUnmarshalers[".yaml"] = yaml.Unmarshal
+var ModbusProfileUnmarshalers = map[string]func(data []byte, v any) error{
+	".json": modbusUnmarshalJson,
+}
+
+// modbusUnmarshalJson decode JSON refusing the unknown fields, a misspelled key is an error rather than a zero value
+func modbusUnmarshalJson(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// ModbusProfileParse decode and validate a profile
+//
+// The profile is decoded with unmarshal, JSON when nil. See Validate() for the errors.
+func ModbusProfileParse(data []byte, unmarshal func(data []byte, v any) error) (p *ModbusProfile, err error) {
+	if unmarshal == nil {
+		unmarshal = modbusUnmarshalJson
+	}
+	p = &ModbusProfile{}
+	if err = unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	if err = p.Validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// ModbusProfileLoad read, decode and validate the profile file at path, see ModbusProfileUnmarshalers
+func ModbusProfileLoad(path string) (p *ModbusProfile, err error) {
+	unmarshal := ModbusProfileUnmarshalers[strings.ToLower(filepath.Ext(path))]
+	if unmarshal == nil {
+		return nil, marshalError("%s: no unmarshaller for the %q files", path, filepath.Ext(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	return ModbusProfileParse(data, unmarshal)
+}
+
+// modbusParseTable data table of its name, either ModbusTable.String() or the name of the Modbus specification
+func modbusParseTable(s string) (t ModbusTable, ok bool) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", "_")) {
+	case "bits", "coils", "coil":
+		return MODBUS_TABLE_BITS, true
+	case "input_bits", "discrete_inputs", "discrete_input", "discrete":
+		return MODBUS_TABLE_INPUT_BITS, true
+	case "", "registers", "holding_registers", "holding_register", "holding":
+		return MODBUS_TABLE_REGISTERS, true
+	case "input_registers", "input_register", "input":
+		return MODBUS_TABLE_INPUT_REGISTERS, true
+	}
+	return
+}
+
+// Validate check the points of the profile
+//
+// The names must be unique, the tables, types, orders and access modes known, the points must fit in the 65536
+// addresses of their table without overlapping, the scale factors must be int16 points of the same table, the input
+// tables are read only and the initial values must be within the limits of the points. Errors return EINVAL.
+func (p *ModbusProfile) Validate() (err error) {
+	_, err = p.compile()
+	return
+}
+
+func (p *ModbusProfile) compile() (entries []modbusProfileEntry, err error) {
+	return p.compileFrom(nil)
+}
+
+// compileFrom validate the points imported from src, the errors locate the points in src when it is not nil
+func (p *ModbusProfile) compileFrom(src *modbusProfileSource) (entries []modbusProfileEntry, err error) {
+	fail := func(i int, err error) error {
+		if src == nil {
+			return err
+		}
+		return marshalError("line %d: %s", src.lines[i], err.(*Error).message)
+	}
+	address := func(i int) string {
+		if src == nil {
+			return strconv.Itoa(p.Points[i].Address)
+		}
+		return src.addresses[i]
+	}
+	index := map[string]int{}
+	entries = make([]modbusProfileEntry, len(p.Points))
+	for i := range p.Points {
+		pp := &p.Points[i]
+		if pp.Name == "" {
+			return nil, fail(i, marshalError("point %d: no name", i))
+		}
+		if _, ok := index[pp.Name]; ok {
+			return nil, fail(i, marshalError("point %s: duplicate name", pp.Name))
+		}
+		index[pp.Name] = i
+		table, ok := modbusParseTable(pp.Table)
+		if !ok {
+			return nil, fail(i, marshalError("point %s: unknown table %q", pp.Name, pp.Table))
+		}
+		e := &entries[i]
+		e.point = ModbusPoint{Name: pp.Name, Table: table, Addr: pp.Address, Type: pp.Type, Order: pp.Order,
+			Scale: pp.Scale, Offset: pp.Offset, Unit: pp.Unit, Min: pp.Min, Max: pp.Max}
+		e.value = pp.Value
+		if _, _, err = e.point.raw(); err != nil {
+			return nil, fail(i, err)
+		}
+		if pp.Address < 0 || pp.Address+e.point.Size() > 0x10000 {
+			return nil, fail(i, marshalError("point %s: address %s out of the table", pp.Name, address(i)))
+		}
+		if pp.Min > pp.Max {
+			return nil, fail(i, marshalError("point %s: min %v above max %v", pp.Name, pp.Min, pp.Max))
+		}
+		input := table == MODBUS_TABLE_INPUT_BITS || table == MODBUS_TABLE_INPUT_REGISTERS
+		switch strings.ToLower(pp.Access) {
+		case "":
+			e.read, e.write = true, !input
+		case "r":
+			e.read = true
+		case "w":
+			e.write = true
+		case "rw":
+			e.read, e.write = true, true
+		default:
+			return nil, fail(i, marshalError("point %s: unknown access %q", pp.Name, pp.Access))
+		}
+		if e.write && input {
+			return nil, fail(i, marshalError("point %s: %s are read only", pp.Name, table))
+		}
+	}
+
+	for i := range p.Points {
+		pp, e := &p.Points[i], &entries[i]
+		if pp.ScaleFactor != "" {
+			j, ok := index[pp.ScaleFactor]
+			if !ok {
+				return nil, fail(i, marshalError("point %s: unknown scale factor %s", pp.Name, pp.ScaleFactor))
+			}
+			sf := &entries[j].point
+			if sf.table() != e.point.table() || sf.Type != "int16" {
+				return nil, fail(i, marshalError("point %s: scale factor %s is not an int16 point of the %s", pp.Name,
+					sf.Name, e.point.table()))
+			}
+			e.point.ScaleFactor = &sf.Addr
+			e.sf = &entries[j]
+		}
+	}
+
+	// overlaps of the points sorted by table and address
+	sorted := make([]int, len(entries))
+	for i := range entries {
+		sorted[i] = i
+	}
+	slices.SortFunc(sorted, func(i, j int) int {
+		a, b := &entries[i].point, &entries[j].point
+		if a.table() != b.table() {
+			return int(a.table() - b.table())
+		}
+		return a.Addr - b.Addr
+	})
+	for k := 1; k < len(sorted); k++ {
+		a, b := &entries[sorted[k-1]].point, &entries[sorted[k]].point
+		if a.table() == b.table() && a.Addr+a.Size() > b.Addr {
+			return nil, fail(sorted[k], marshalError("point %s: address %s overlaps point %s", b.Name,
+				address(sorted[k]), a.Name))
+		}
+	}
+
+	// the initial values are checked with the initial values of their scale factors
+	for i := range entries {
+		e := &entries[i]
+		if e.value == nil {
+			continue
+		}
+		sf := 0
+		if e.sf != nil && e.sf.value != nil {
+			sf = int(*e.sf.value)
+		}
+		if _, err = e.point.Encode(*e.value, sf); err != nil {
+			return nil, fail(i, err)
+		}
+	}
+	return
+}
+
+// ModbusPoints points of the profile in the order of the profile
+func (p *ModbusProfile) ModbusPoints() (points []ModbusPoint, err error) {
+	entries, err := p.compile()
+	if err != nil {
+		return
+	}
+	points = make([]ModbusPoint, len(entries))
+	for i := range entries {
+		points[i] = entries[i].point
+	}
+	return
+}
+
+// Mapping create the mapping of a simulator of the device, see Load()
+//
+// Each table of the mapping spans from the lowest to the highest address of the points of the profile.
+func (p *ModbusProfile) Mapping() (mm *ModbusMapping, err error) {
+	entries, err := p.compile()
+	if err != nil {
+		return
+	}
+	var start, end [MODBUS_TABLE_INPUT_REGISTERS + 1]int
+	for i := range start {
+		start[i] = 0x10000
+	}
+	for i := range entries {
+		pt := &entries[i].point
+		t := pt.table()
+		start[t] = min(start[t], pt.Addr)
+		end[t] = max(end[t], pt.Addr+pt.Size())
+	}
+	span := func(t ModbusTable) (uint, uint) {
+		if end[t] == 0 {
+			return 0, 0
+		}
+		return uint(start[t]), uint(end[t] - start[t])
+	}
+	sb, nb := span(MODBUS_TABLE_BITS)
+	sib, nib := span(MODBUS_TABLE_INPUT_BITS)
+	sr, nr := span(MODBUS_TABLE_REGISTERS)
+	sir, nir := span(MODBUS_TABLE_INPUT_REGISTERS)
+	mm = ModbusMappingNewStartAddress(sb, nb, sib, nib, sr, nr, sir, nir)
+	if mm == nil {
+		return nil, marshalError("profile %s: mapping allocation failed", p.Name)
+	}
+	if err = p.Load(mm); err != nil {
+		mm.Free()
+		return nil, err
+	}
+	return
+}
+
+// Load store the initial values of the points of the profile into a mapping
+//
+// The scale factors are stored first, the points without initial values are left untouched. A point out of the
+// mapping or scaled by a scale factor of 0x8000, not implemented, returns EINVAL, the values stored before remain.
+func (p *ModbusProfile) Load(mm *ModbusMapping) (err error) {
+	entries, err := p.compile()
+	if err != nil {
+		return
+	}
+	for _, scaled := range []bool{false, true} {
+		for i := range entries {
+			e := &entries[i]
+			if e.value == nil || (e.sf != nil) != scaled {
+				continue
+			}
+			pt := &e.point
+			t := pt.table()
+			start, nb := mm.tableRange(t)
+			if pt.Addr < start || pt.Addr+pt.Size() > start+nb {
+				return marshalError("point %s: address %d out of the %s of the mapping", pt.Name, pt.Addr, t)
+			}
+			sf := 0
+			if pt.ScaleFactor != nil {
+				if sf, err = pt.scaleFactor(mm.getTab(t, *pt.ScaleFactor)); err != nil {
+					return
+				}
+			}
+			regs, err := pt.Encode(*e.value, sf)
+			if err != nil {
+				return err
+			}
+			for j, r := range regs {
+				mm.setTab(t, pt.Addr+j, r)
+			}
+		}
+	}
+	return
+}
+
+// Policy create the policy of a simulator of the device denying the writes to the read only points and the reads of
+// the write only points of the coils and holding registers, see ReplyPolicy()
+func (p *ModbusProfile) Policy() (policy *ModbusPolicy, err error) {
+	entries, err := p.compile()
+	if err != nil {
+		return
+	}
+	policy = &ModbusPolicy{Default: MODBUS_POLICY_ALLOW, Exception: MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS}
+	for i := range entries {
+		e := &entries[i]
+		t := e.point.table()
+		if t == MODBUS_TABLE_INPUT_BITS || t == MODBUS_TABLE_INPUT_REGISTERS || (e.read && e.write) {
+			continue
+		}
+		policy.Rules = append(policy.Rules, ModbusPolicyRule{Name: e.point.Name, Action: MODBUS_POLICY_DENY,
+			Table: t, Start: e.point.Addr, Nb: e.point.Size(), WriteOnly: !e.write, ReadOnly: !e.read})
+	}
+	return
+}
+
+// ModbusDeviceNew create the reader and writer of the points of a profile through a client
+func ModbusDeviceNew(x *Modbus, p *ModbusProfile) (d *ModbusDevice, err error) {
+	entries, err := p.compile()
+	if err != nil {
+		return
+	}
+	d = &ModbusDevice{x: x, profile: p, entries: entries, index: map[string]int{}}
+	for i := range entries {
+		d.index[entries[i].point.Name] = i
+	}
+	return
+}
+
+// Profile the profile of the device
+func (d *ModbusDevice) Profile() *ModbusProfile {
+	return d.profile
+}
+
+// Point the point of a name, false if the profile has none
+func (d *ModbusDevice) Point(name string) (p ModbusPoint, ok bool) {
+	i, ok := d.index[name]
+	if ok {
+		p = d.entries[i].point
+	}
+	return
+}
+
+// ReadAll read the engineering values of the readable points, see Modbus.ReadPoints()
+func (d *ModbusDevice) ReadAll() (values map[string]float64, err error) {
+	var names []string
+	for i := range d.entries {
+		if d.entries[i].read {
+			names = append(names, d.entries[i].point.Name)
+		}
+	}
+	return d.Read(names...)
+}
+
+// Read read the engineering values of points by name, see Modbus.ReadPoints()
+//
+// An unknown or write only point returns EINVAL.
+func (d *ModbusDevice) Read(names ...string) (values map[string]float64, err error) {
+	points := make([]ModbusPoint, len(names))
+	for i, name := range names {
+		j, ok := d.index[name]
+		if !ok || !d.entries[j].read {
+			return nil, marshalError("point %s: unknown or write only", name)
+		}
+		points[i] = d.entries[j].point
+	}
+	read, err := d.x.ReadPoints(points)
+	if err != nil {
+		return
+	}
+	values = make(map[string]float64, len(names))
+	for i, name := range names {
+		values[name] = read[i]
+	}
+	return
+}
+
+// Write write the engineering value of a point by name, see Modbus.WritePoint()
+//
+// An unknown or read only point returns EINVAL.
+func (d *ModbusDevice) Write(name string, value float64) (err error) {
+	j, ok := d.index[name]
+	if !ok || !d.entries[j].write {
+		return marshalError("point %s: unknown or read only", name)
+	}
+	return d.x.WritePoint(&d.entries[j].point, value)
+}
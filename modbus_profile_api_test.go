@@ -0,0 +1,168 @@
+package libmodbusgo
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const profileTest = `{
+	"name": "meter",
+	"points": [
+		{"name": "voltage", "table": "input", "address": 0, "scale": 0.1, "unit": "V", "value": 230.4},
+		{"name": "power", "table": "input", "address": 1, "type": "int32", "order": "CDAB", "scale_factor": "power_sf", "unit": "W", "value": -1520},
+		{"name": "power_sf", "table": "input", "address": 3, "type": "int16", "value": -1},
+		{"name": "serial", "address": 100, "type": "uint32", "access": "r", "value": 123456},
+		{"name": "setpoint", "address": 102, "type": "int16", "scale": 0.5, "min": -50, "max": 50, "value": 20},
+		{"name": "reset", "table": "coils", "address": 5, "access": "w"},
+		{"name": "running", "table": "discrete_inputs", "address": 7, "value": 1}
+	]
+}`
+
+func TestModbusProfile(t *testing.T) {
+	p, err := ModbusProfileParse([]byte(profileTest), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm, err := p.Mapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mm.Free()
+	if mm.StartRegisters() != 100 || mm.NbRegisters() != 3 || mm.NbInputRegisters() != 4 || mm.StartBits() != 5 {
+		t.Errorf("registers %d+%d input registers %d", mm.StartRegisters(), mm.NbRegisters(), mm.NbInputRegisters())
+	}
+	if mm.GetTabInputRegisters(2) != 0xFFFF || mm.GetTabInputRegisters(1) != 0xC4A0 || mm.GetTabRegisters(102) != 40 {
+		t.Errorf("%04X %04X %d", mm.GetTabInputRegisters(1), mm.GetTabInputRegisters(2), mm.GetTabRegisters(102))
+	}
+
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	defer server.close()
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+	d, err := ModbusDeviceNew(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Write("setpoint", -12.5); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Write("reset", 1); err != nil || mm.GetTabBits(5) != 1 {
+		t.Error(err)
+	}
+	values, err := d.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]float64{"voltage": 230.4, "power": -1520, "power_sf": -1, "serial": 123456,
+		"setpoint": -12.5, "running": 1} {
+		if math.Abs(values[name]-want) > 1e-9 {
+			t.Errorf("%s: %v", name, values[name])
+		}
+	}
+	if _, ok := values["reset"]; ok || len(values) != 6 {
+		t.Error(values)
+	}
+	for _, name := range []string{"serial", "voltage", "unknown"} {
+		if err = d.Write(name, 1); err == nil {
+			t.Errorf("write %s", name)
+		}
+	}
+	if _, err = d.Read("reset"); err == nil {
+		t.Error("read write only")
+	}
+	if err = d.Write("setpoint", 60); err == nil {
+		t.Error("out of the limits")
+	}
+
+	policy, err := p.Policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		req  ModbusRequest
+		want ModbusPolicyAction
+	}{
+		{ModbusRequest{Function: MODBUS_FC_WRITE_MULTIPLE_REGISTERS, Table: MODBUS_TABLE_REGISTERS, Addr: 101, Nb: 2}, MODBUS_POLICY_DENY},
+		{ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_REGISTER, Table: MODBUS_TABLE_REGISTERS, Addr: 102, Nb: 1}, MODBUS_POLICY_ALLOW},
+		{ModbusRequest{Function: MODBUS_FC_READ_HOLDING_REGISTERS, Table: MODBUS_TABLE_REGISTERS, Addr: 100, Nb: 3}, MODBUS_POLICY_ALLOW},
+		{ModbusRequest{Function: MODBUS_FC_READ_COILS, Table: MODBUS_TABLE_BITS, Addr: 5, Nb: 1}, MODBUS_POLICY_DENY},
+		{ModbusRequest{Function: MODBUS_FC_WRITE_SINGLE_COIL, Table: MODBUS_TABLE_BITS, Addr: 5, Nb: 1}, MODBUS_POLICY_ALLOW},
+	} {
+		if action, _, _ := policy.Check(&c.req, netip.Addr{}); action != c.want {
+			t.Errorf("%+v: %s", c.req, action)
+		}
+	}
+}
+
+func TestModbusProfile_Validate(t *testing.T) {
+	for _, c := range []struct {
+		points string
+		err    string
+	}{
+		{`{"name": "a", "address": 0}, {"name": "a", "address": 1}`, "duplicate"},
+		{`{"name": "a", "address": 0, "type": "uint32"}, {"name": "b", "address": 1}`, "overlaps"},
+		{`{"name": "a", "address": 65535, "type": "float32"}`, "out of the table"},
+		{`{"name": "a", "table": "outputs", "address": 0}`, "unknown table"},
+		{`{"name": "a", "address": 0, "type": "int24"}`, "unknown type"},
+		{`{"name": "a", "address": 0, "type": "uint32", "order": "ABCDEFGH"}`, "does not apply"},
+		{`{"name": "a", "table": "input", "address": 0, "access": "rw"}`, "read only"},
+		{`{"name": "a", "address": 0, "access": "x"}`, "unknown access"},
+		{`{"name": "a", "address": 0, "scale_factor": "b"}`, "unknown scale factor"},
+		{`{"name": "a", "address": 0, "scale_factor": "b"}, {"name": "b", "address": 1}`, "not an int16"},
+		{`{"name": "a", "address": 0, "min": 10, "max": 0}`, "above max"},
+		{`{"name": "a", "address": 0, "type": "int16", "value": 40000}`, "overflows"},
+		{`{"name": "a", "adress": 0}`, "unknown field"},
+	} {
+		_, err := ModbusProfileParse([]byte(`{"name": "test", "points": [`+c.points+`]}`), nil)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: %v", c.points, err)
+		}
+	}
+}
+
+func TestModbusProfileLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meter.json")
+	if err := os.WriteFile(path, []byte(profileTest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ModbusProfileLoad(path)
+	if err != nil || p.Name != "meter" || len(p.Points) != 7 {
+		t.Fatal(err)
+	}
+	points, err := p.ModbusPoints()
+	if err != nil || points[1].ScaleFactor == nil || *points[1].ScaleFactor != 3 {
+		t.Errorf("%+v %v", points, err)
+	}
+
+	// the decoders of the other formats are registered by extension
+	path = filepath.Join(dir, "meter.yaml")
+	if err = os.WriteFile(path, []byte(profileTest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ModbusProfileLoad(path); err == nil {
+		t.Error("no YAML unmarshaller registered")
+	}
+	ModbusProfileUnmarshalers[".yaml"] = json.Unmarshal
+	defer delete(ModbusProfileUnmarshalers, ".yaml")
+	y, err := ModbusProfileLoad(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(y, p) {
+		t.Errorf("%+v\n%+v", y, p)
+	}
+}
@@ -0,0 +1,54 @@
+package libmodbusgo
+
+// ModbusProfile register map of a device model, see ModbusProfileParse()
+type ModbusProfile struct {
+	Name        string               `json:"name" yaml:"name"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Points      []ModbusProfilePoint `json:"points" yaml:"points"`
+}
+
+// ModbusProfilePoint point of a profile, see ModbusPoint
+type ModbusProfilePoint struct {
+	Name string `json:"name" yaml:"name"`
+	// Table bits (coils), input_bits (discrete_inputs), registers (holding) or input_registers (input), registers
+	// when empty
+	Table   string          `json:"table,omitempty" yaml:"table,omitempty"`
+	Address int             `json:"address" yaml:"address"`
+	Type    string          `json:"type,omitempty" yaml:"type,omitempty"`
+	Order   ModbusByteOrder `json:"order,omitempty" yaml:"order,omitempty"`
+	Scale   float64         `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Offset  float64         `json:"offset,omitempty" yaml:"offset,omitempty"`
+	// ScaleFactor name of the int16 point of the same table holding the power of ten of the value
+	ScaleFactor string  `json:"scale_factor,omitempty" yaml:"scale_factor,omitempty"`
+	Unit        string  `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Min         float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max         float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	// Access r, w or rw, r for the input tables and rw for the others when empty
+	Access      string `json:"access,omitempty" yaml:"access,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Value initial value of the point in the mapping of a simulator, see ModbusProfile.Load()
+	Value *float64 `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// modbusProfileEntry validated point of a profile
+type modbusProfileEntry struct {
+	point ModbusPoint
+	read  bool
+	write bool
+	value *float64
+	sf    *modbusProfileEntry // scale factor of the point
+}
+
+// modbusProfileSource location of the points of a profile imported from a file, for the validation errors
+type modbusProfileSource struct {
+	lines     []int
+	addresses []string // addresses as written in the file
+}
+
+// ModbusDevice reader and writer of the points of a profile through a client, see ModbusDeviceNew()
+type ModbusDevice struct {
+	x       *Modbus
+	profile *ModbusProfile
+	entries []modbusProfileEntry
+	index   map[string]int
+}
@@ -0,0 +1,146 @@
+package libmodbusgo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var modbusModiconPrefixes = map[int]ModbusTable{
+	0: MODBUS_TABLE_BITS,
+	1: MODBUS_TABLE_INPUT_BITS,
+	3: MODBUS_TABLE_INPUT_REGISTERS,
+	4: MODBUS_TABLE_REGISTERS,
+}
+
+// IEC 61131-3 prefixes of the tables, the longest first
+var modbusIecPrefixes = []struct {
+	prefix string
+	table  ModbusTable
+}{
+	{"MW", MODBUS_TABLE_REGISTERS},
+	{"IW", MODBUS_TABLE_INPUT_REGISTERS},
+	{"MX", MODBUS_TABLE_BITS},
+	{"IX", MODBUS_TABLE_INPUT_BITS},
+	{"M", MODBUS_TABLE_BITS},
+	{"I", MODBUS_TABLE_INPUT_BITS},
+}
+
+// ModbusParseReference parse a reference to a data table
+//
+// The references are either 1-based Modicon references of 5 digits, 00001 to 49999, or 6 digits, 000001 to 465536,
+// the first digit being the table: 0 coils, 1 discrete inputs, 3 input registers and 4 holding registers; the same
+// with an x after the table digit, eg. 4x00001; or zero-based IEC 61131-3 addresses %M (or %MX) coils, %I (or %IX)
+// discrete inputs, %IW input registers and %MW holding registers. 40001, 400001, 4x1 and %MW0 are all the first
+// holding register. An invalid reference returns EINVAL.
+func ModbusParseReference(s string) (ref ModbusReference, err error) {
+	s = strings.TrimSpace(s)
+	invalid := marshalError("invalid reference %q", s)
+	if iec, ok := strings.CutPrefix(s, "%"); ok {
+		iec = strings.ToUpper(iec)
+		for _, p := range modbusIecPrefixes {
+			if digits, ok := strings.CutPrefix(iec, p.prefix); ok {
+				ref.Table = p.table
+				if ref.Addr, err = strconv.Atoi(digits); err != nil || digits[0] == '+' || ref.Addr < 0 ||
+					ref.Addr > 0xFFFF {
+					return ModbusReference{}, invalid
+				}
+				return ref, nil
+			}
+		}
+		return ModbusReference{}, invalid
+	}
+
+	var prefix, n int
+	if t, digits, ok := strings.Cut(strings.ToLower(s), "x"); ok {
+		// 4x00001 form, the table digit then the 1-based offset
+		if len(t) != 1 || len(digits) == 0 || len(digits) > 5 {
+			return ModbusReference{}, invalid
+		}
+		prefix, err = strconv.Atoi(t)
+		if err == nil {
+			n, err = strconv.Atoi(digits)
+		}
+	} else {
+		if len(s) != 5 && len(s) != 6 {
+			return ModbusReference{}, invalid
+		}
+		prefix, err = strconv.Atoi(s[:1])
+		if err == nil {
+			n, err = strconv.Atoi(s[1:])
+		}
+	}
+	table, ok := modbusModiconPrefixes[prefix]
+	if err != nil || !ok || n < 1 || n > 0x10000 || strings.ContainsAny(s, "+-") {
+		return ModbusReference{}, invalid
+	}
+	return ModbusReference{Table: table, Addr: n - 1}, nil
+}
+
+// Format write the reference in a notation, the addresses beyond 9998 are written with 6 digits in
+// MODBUS_REFERENCE_MODICON
+func (ref ModbusReference) Format(style ModbusReferenceStyle) string {
+	if style == MODBUS_REFERENCE_IEC {
+		for _, p := range modbusIecPrefixes {
+			if p.table == ref.Table && len(p.prefix) == 2 {
+				return fmt.Sprintf("%%%s%d", p.prefix, ref.Addr)
+			}
+		}
+		return fmt.Sprintf("%%?%d", ref.Addr)
+	}
+	prefix := 9
+	for p, t := range modbusModiconPrefixes {
+		if t == ref.Table {
+			prefix = p
+		}
+	}
+	if style == MODBUS_REFERENCE_MODICON && ref.Addr < 9999 {
+		return fmt.Sprintf("%d%04d", prefix, ref.Addr+1)
+	}
+	return fmt.Sprintf("%d%05d", prefix, ref.Addr+1)
+}
+
+// String the reference in MODBUS_REFERENCE_MODICON
+func (ref ModbusReference) String() string {
+	return ref.Format(MODBUS_REFERENCE_MODICON)
+}
+
+// ReadReference read nb bits or registers from a reference, see ModbusParseReference()
+//
+// The bits are returned one per register, the table of the reference selects the function code.
+func (x *Modbus) ReadReference(ref string, nb int) (values []uint16, err error) {
+	r, err := ModbusParseReference(ref)
+	if err != nil {
+		return
+	}
+	return x.readTable(r.Table, r.Addr, nb)
+}
+
+// WriteReference write bits or registers to a reference, see ModbusParseReference()
+//
+// A single value is written with MODBUS_FC_WRITE_SINGLE_COIL or MODBUS_FC_WRITE_SINGLE_REGISTER, several with
+// MODBUS_FC_WRITE_MULTIPLE_COILS or MODBUS_FC_WRITE_MULTIPLE_REGISTERS. The references to the input tables return
+// EINVAL.
+func (x *Modbus) WriteReference(ref string, values ...uint16) (err error) {
+	r, err := ModbusParseReference(ref)
+	if err != nil {
+		return
+	}
+	switch {
+	case len(values) == 0:
+		return marshalError("no value to write to %s", ref)
+	case r.Table == MODBUS_TABLE_BITS && len(values) == 1:
+		return x.WriteBit(r.Addr, byte(min(values[0], 1)))
+	case r.Table == MODBUS_TABLE_BITS:
+		bits := make([]byte, len(values))
+		for i, v := range values {
+			bits[i] = byte(min(v, 1))
+		}
+		return x.WriteBits(r.Addr, bits)
+	case r.Table == MODBUS_TABLE_REGISTERS && len(values) == 1:
+		return x.WriteRegister(r.Addr, values[0])
+	case r.Table == MODBUS_TABLE_REGISTERS:
+		return x.WriteRegisters(r.Addr, values)
+	}
+	return marshalError("%s: %s are read only", ref, r.Table)
+}
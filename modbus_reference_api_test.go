@@ -0,0 +1,124 @@
+package libmodbusgo
+
+import (
+	"net"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestModbusParseReference(t *testing.T) {
+	for _, c := range []struct {
+		s   string
+		ref ModbusReference
+	}{
+		{"40001", ModbusReference{MODBUS_TABLE_REGISTERS, 0}},
+		{"400101", ModbusReference{MODBUS_TABLE_REGISTERS, 100}},
+		{"465536", ModbusReference{MODBUS_TABLE_REGISTERS, 65535}},
+		{"30010", ModbusReference{MODBUS_TABLE_INPUT_REGISTERS, 9}},
+		{"10001", ModbusReference{MODBUS_TABLE_INPUT_BITS, 0}},
+		{"00017", ModbusReference{MODBUS_TABLE_BITS, 16}},
+		{"4x1", ModbusReference{MODBUS_TABLE_REGISTERS, 0}},
+		{"3X00010", ModbusReference{MODBUS_TABLE_INPUT_REGISTERS, 9}},
+		{"%MW100", ModbusReference{MODBUS_TABLE_REGISTERS, 100}},
+		{"%iw0", ModbusReference{MODBUS_TABLE_INPUT_REGISTERS, 0}},
+		{"%M5", ModbusReference{MODBUS_TABLE_BITS, 5}},
+		{"%IX7", ModbusReference{MODBUS_TABLE_INPUT_BITS, 7}},
+		{" 40002 ", ModbusReference{MODBUS_TABLE_REGISTERS, 1}},
+	} {
+		ref, err := ModbusParseReference(c.s)
+		if err != nil || ref != c.ref {
+			t.Errorf("%q: %+v %v", c.s, ref, err)
+		}
+	}
+	for _, s := range []string{"", "40000", "400000", "465537", "50001", "20001", "4001", "4000001", "%QW1", "%MW",
+		"%MW-1", "%MW+1", "%MW65536", "4x", "4x0", "44x1", "4x+1", "+4001", "abcde"} {
+		if _, err := ModbusParseReference(s); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+
+	for _, c := range []struct {
+		ref   ModbusReference
+		style ModbusReferenceStyle
+		s     string
+	}{
+		{ModbusReference{MODBUS_TABLE_REGISTERS, 0}, MODBUS_REFERENCE_MODICON, "40001"},
+		{ModbusReference{MODBUS_TABLE_REGISTERS, 9998}, MODBUS_REFERENCE_MODICON, "49999"},
+		{ModbusReference{MODBUS_TABLE_REGISTERS, 9999}, MODBUS_REFERENCE_MODICON, "410000"},
+		{ModbusReference{MODBUS_TABLE_BITS, 2}, MODBUS_REFERENCE_MODICON, "00003"},
+		{ModbusReference{MODBUS_TABLE_INPUT_REGISTERS, 9}, MODBUS_REFERENCE_MODICON6, "300010"},
+		{ModbusReference{MODBUS_TABLE_REGISTERS, 100}, MODBUS_REFERENCE_IEC, "%MW100"},
+		{ModbusReference{MODBUS_TABLE_INPUT_BITS, 1}, MODBUS_REFERENCE_IEC, "%IX1"},
+	} {
+		if s := c.ref.Format(c.style); s != c.s {
+			t.Errorf("%+v: %s", c.ref, s)
+		}
+		if ref, _ := ModbusParseReference(c.s); ref != c.ref {
+			t.Errorf("%s: %+v", c.s, ref)
+		}
+	}
+}
+
+func TestModbus_ReadReference(t *testing.T) {
+	mm := ModbusMappingNew(10, 10, 200, 200)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+
+	mm.SetTabInputRegisters(9, 1234)
+	mm.SetTabInputBits(0, 1)
+	for _, c := range []struct {
+		ref    string
+		values []uint16
+	}{
+		{"400101", []uint16{7}},
+		{"%MW101", []uint16{8, 9}},
+		{"00001", []uint16{1}},
+		{"%M2", []uint16{1, 0, 1}},
+	} {
+		if err := ctx.WriteReference(c.ref, c.values...); err != nil {
+			t.Fatal(c.ref, err)
+		}
+	}
+	if err := ctx.WriteReference("30001", 1); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Error("input registers")
+	}
+	for _, c := range []struct {
+		ref    string
+		nb     int
+		values []uint16
+	}{
+		{"40101", 3, []uint16{7, 8, 9}},
+		{"30010", 1, []uint16{1234}},
+		{"%IX0", 2, []uint16{1, 0}},
+		{"00001", 5, []uint16{1, 0, 1, 0, 1}},
+	} {
+		if values, err := ctx.ReadReference(c.ref, c.nb); err != nil || !slices.Equal(values, c.values) {
+			t.Errorf("%s: %v %v", c.ref, values, err)
+		}
+	}
+	want := []string{
+		"FF 06 00 64 00 07",
+		"FF 10 00 65 00 02 04 00 08 00 09",
+		"FF 05 00 00 FF 00",
+		"FF 0F 00 02 00 03 01 05",
+		"FF 03 00 64 00 03",
+		"FF 04 00 09 00 01",
+		"FF 02 00 00 00 02",
+		"FF 01 00 00 00 05",
+	}
+	if got := server.close(); !slices.Equal(got, want) {
+		t.Errorf("%q", got)
+	}
+}
@@ -0,0 +1,17 @@
+package libmodbusgo
+
+// ModbusReference data table and zero-based address of a reference such as 40001 or %MW100, see
+// ModbusParseReference()
+type ModbusReference struct {
+	Table ModbusTable
+	Addr  int
+}
+
+// ModbusReferenceStyle notation of the references written by ModbusReference.Format()
+type ModbusReferenceStyle int
+
+const (
+	MODBUS_REFERENCE_MODICON  ModbusReferenceStyle = iota // 1-based Modicon reference, 5 digits up to 9999, eg. 40001
+	MODBUS_REFERENCE_MODICON6                             // 1-based Modicon reference of 6 digits, eg. 400001
+	MODBUS_REFERENCE_IEC                                  // zero-based IEC 61131-3 address, eg. %MW0
+)
@@ -0,0 +1,231 @@
+package libmodbusgo
+
+// DecodeRequest decode an indication request
+//
+// The DecodeRequest() function shall decode the request received by Receive() (or TcpReceive(), RtuReceive()) into
+// the slave number, the function code and the addressed data table and ranges. The header length of the context is
+// used to locate the PDU so the same request can be given back to Reply() afterwards.
+//
+// Function codes without data table (MODBUS_FC_REPORT_SLAVE_ID, MODBUS_FC_READ_EXCEPTION_STATUS or unknown
+// functions) are decoded with Table set to MODBUS_TABLE_NONE. A MODBUS_FC_WRITE_SINGLE_COIL value other than 0x0000
+// and 0xFF00 is decoded without Bits, the mapping handlers reply MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE. A request too
+// short for its function code returns EMBBADDATA.
+func (x *Modbus) DecodeRequest(req []byte) (r *ModbusRequest, err error) {
+	offset := x.GetHeaderLength()
+	if offset < 1 || len(req) <= offset {
+		err = EMBBADDATA.Error()
+		return
+	}
+	pdu := req[offset:]
+	r = &ModbusRequest{
+		Slave:    int(req[offset-1]),
+		Function: int(pdu[0]),
+	}
+	need := 1
+	switch r.Function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS,
+		MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_READ_INPUT_REGISTERS,
+		MODBUS_FC_WRITE_SINGLE_COIL, MODBUS_FC_WRITE_SINGLE_REGISTER:
+		need = 5
+	case MODBUS_FC_WRITE_MULTIPLE_COILS, MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		need = 6
+	case MODBUS_FC_MASK_WRITE_REGISTER:
+		need = 7
+	case MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		need = 10
+	}
+	if len(pdu) < need {
+		r = nil
+		err = EMBBADDATA.Error()
+		return
+	}
+
+	word := func(i int) int {
+		return int(pdu[i])<<8 | int(pdu[i+1])
+	}
+	data := func(i int) (b []byte, ok bool) {
+		n := i + 1 + int(pdu[i])
+		if len(pdu) < n {
+			return nil, false
+		}
+		return pdu[i+1 : n], true
+	}
+	ok := true
+	var b []byte
+	switch r.Function {
+	case MODBUS_FC_READ_COILS:
+		r.Table = MODBUS_TABLE_BITS
+		r.Addr, r.Nb = word(1), word(3)
+	case MODBUS_FC_WRITE_SINGLE_COIL:
+		r.Table = MODBUS_TABLE_BITS
+		r.Addr, r.Nb = word(1), 1
+		switch word(3) {
+		case 0xFF00:
+			r.Bits = []byte{1}
+		case 0x0000:
+			r.Bits = []byte{0}
+		}
+	case MODBUS_FC_WRITE_MULTIPLE_COILS:
+		r.Table = MODBUS_TABLE_BITS
+		r.Addr, r.Nb = word(1), word(3)
+		if b, ok = data(5); ok {
+			r.Bits = make([]byte, min(r.Nb, 8*len(b)))
+			for i := range r.Bits {
+				r.Bits[i] = (b[i/8] >> (i % 8)) & 1
+			}
+		}
+	case MODBUS_FC_READ_DISCRETE_INPUTS:
+		r.Table = MODBUS_TABLE_INPUT_BITS
+		r.Addr, r.Nb = word(1), word(3)
+	case MODBUS_FC_READ_HOLDING_REGISTERS:
+		r.Table = MODBUS_TABLE_REGISTERS
+		r.Addr, r.Nb = word(1), word(3)
+	case MODBUS_FC_WRITE_SINGLE_REGISTER:
+		r.Table = MODBUS_TABLE_REGISTERS
+		r.Addr, r.Nb = word(1), 1
+		r.Values = []uint16{uint16(word(3))}
+	case MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		r.Table = MODBUS_TABLE_REGISTERS
+		r.Addr, r.Nb = word(1), word(3)
+		if b, ok = data(5); ok {
+			r.Values = registersFromBytes(b)
+		}
+	case MODBUS_FC_MASK_WRITE_REGISTER:
+		r.Table = MODBUS_TABLE_REGISTERS
+		r.Addr, r.Nb = word(1), 1
+		r.AndMask, r.OrMask = uint16(word(3)), uint16(word(5))
+	case MODBUS_FC_READ_INPUT_REGISTERS:
+		r.Table = MODBUS_TABLE_INPUT_REGISTERS
+		r.Addr, r.Nb = word(1), word(3)
+	case MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		r.Table = MODBUS_TABLE_REGISTERS
+		r.Addr, r.Nb = word(1), word(3)
+		r.WriteAddr, r.WriteNb = word(5), word(7)
+		if b, ok = data(9); ok {
+			r.Values = registersFromBytes(b)
+		}
+	}
+	if !ok {
+		r = nil
+		err = EMBBADDATA.Error()
+		return
+	}
+	r.raw = req
+	return
+}
+
+// EncodeRequest encode a decoded request
+//
+// The EncodeRequest() function shall build the raw request of r, using the header of the request it was decoded from
+// so the transaction identifier and the slave number are kept. It is used to serve a request rewritten by a
+// middleware, the PDU of function codes without data table is kept as received.
+func (x *Modbus) EncodeRequest(r *ModbusRequest) (req []byte) {
+	offset := x.GetHeaderLength()
+	var pdu []byte
+	put := func(v ...int) {
+		for _, w := range v {
+			pdu = append(pdu, byte(w>>8), byte(w))
+		}
+	}
+	pdu = append(pdu, byte(r.Function))
+	switch r.Function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS,
+		MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_READ_INPUT_REGISTERS:
+		put(r.Addr, r.Nb)
+	case MODBUS_FC_WRITE_SINGLE_COIL:
+		value := 0
+		if len(r.Bits) > 0 && r.Bits[0] != 0 {
+			value = 0xFF00
+		}
+		put(r.Addr, value)
+	case MODBUS_FC_WRITE_MULTIPLE_COILS:
+		put(r.Addr, r.Nb)
+		b := make([]byte, (len(r.Bits)+7)/8)
+		for i, v := range r.Bits {
+			if v != 0 {
+				b[i/8] |= 1 << (i % 8)
+			}
+		}
+		pdu = append(pdu, byte(len(b)))
+		pdu = append(pdu, b...)
+	case MODBUS_FC_WRITE_SINGLE_REGISTER:
+		value := 0
+		if len(r.Values) > 0 {
+			value = int(r.Values[0])
+		}
+		put(r.Addr, value)
+	case MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		put(r.Addr, r.Nb)
+		pdu = append(pdu, byte(2*len(r.Values)))
+		for _, v := range r.Values {
+			put(int(v))
+		}
+	case MODBUS_FC_MASK_WRITE_REGISTER:
+		put(r.Addr, int(r.AndMask), int(r.OrMask))
+	case MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		put(r.Addr, r.Nb, r.WriteAddr, r.WriteNb)
+		pdu = append(pdu, byte(2*len(r.Values)))
+		for _, v := range r.Values {
+			put(int(v))
+		}
+	default:
+		if end := len(r.raw) - x.checksumLength(); end > offset {
+			pdu = r.raw[offset:end]
+		}
+	}
+
+	req = make([]byte, offset, offset+len(pdu)+2)
+	if len(r.raw) >= offset {
+		copy(req, r.raw[:offset])
+	}
+	req[offset-1] = byte(r.Slave)
+	req = append(req, pdu...)
+	if offset == modbusTcpHeaderLength {
+		req[4], req[5] = byte((len(pdu)+1)>>8), byte(len(pdu)+1)
+	}
+	switch x.checksumLength() {
+	case modbusRtuChecksumLength:
+		crc := modbusCrc16(req)
+		req = append(req, byte(crc), byte(crc>>8))
+	case modbusAsciiChecksumLength:
+		req = append(req, modbusLrc(req))
+	}
+	return
+}
+
+func registersFromBytes(b []byte) []uint16 {
+	values := make([]uint16, len(b)/2)
+	for i := range values {
+		values[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return values
+}
+
+// IsWrite report whether the request modifies the data table
+func (r *ModbusRequest) IsWrite() bool {
+	switch r.Function {
+	case MODBUS_FC_WRITE_SINGLE_COIL, MODBUS_FC_WRITE_SINGLE_REGISTER,
+		MODBUS_FC_WRITE_MULTIPLE_COILS, MODBUS_FC_WRITE_MULTIPLE_REGISTERS,
+		MODBUS_FC_MASK_WRITE_REGISTER, MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		return true
+	}
+	return false
+}
+
+// modbusRange address range of a request
+type modbusRange struct {
+	addr  int
+	nb    int
+	write bool
+}
+
+// ranges return the address ranges touched by the request
+func (r *ModbusRequest) ranges() []modbusRange {
+	if r.Table == MODBUS_TABLE_NONE {
+		return nil
+	}
+	if r.Function == MODBUS_FC_WRITE_AND_READ_REGISTERS {
+		return []modbusRange{{r.WriteAddr, r.WriteNb, true}, {r.Addr, r.Nb, false}}
+	}
+	return []modbusRange{{r.Addr, r.Nb, r.IsWrite()}}
+}
@@ -0,0 +1,51 @@
+package libmodbusgo
+
+// ModbusTable data table addressed by a request
+type ModbusTable int
+
+const (
+	MODBUS_TABLE_NONE            ModbusTable = iota // request does not address a data table
+	MODBUS_TABLE_BITS                               // coils, read/write
+	MODBUS_TABLE_INPUT_BITS                         // discrete inputs, read only
+	MODBUS_TABLE_REGISTERS                          // holding registers, read/write
+	MODBUS_TABLE_INPUT_REGISTERS                    // input registers, read only
+)
+
+func (t ModbusTable) String() string {
+	switch t {
+	case MODBUS_TABLE_BITS:
+		return "bits"
+	case MODBUS_TABLE_INPUT_BITS:
+		return "input_bits"
+	case MODBUS_TABLE_REGISTERS:
+		return "registers"
+	case MODBUS_TABLE_INPUT_REGISTERS:
+		return "input_registers"
+	}
+	return "none"
+}
+
+// ModbusRequest indication request decoded from the raw message returned by Receive
+type ModbusRequest struct {
+	Slave    int         // slave number or unit identifier
+	Function int         // function code
+	Table    ModbusTable // data table addressed by the function code
+	Addr     int         // first address read, or written for write functions
+	Nb       int         // quantity of bits or registers
+
+	// WriteAddr and WriteNb are the write part of MODBUS_FC_WRITE_AND_READ_REGISTERS,
+	// Addr and Nb being the read part.
+	WriteAddr int
+	WriteNb   int
+
+	Bits    []byte   // coils written, one byte per bit
+	Values  []uint16 // registers written
+	AndMask uint16   // MODBUS_FC_MASK_WRITE_REGISTER masks
+	OrMask  uint16
+
+	// ReadBits and ReadValues are filled by the mapping handlers once a read request has been served
+	ReadBits   []byte
+	ReadValues []uint16
+
+	raw []byte // request as received, header included
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 package libmodbusgo
 
 /*
@@ -11,23 +13,29 @@ extern void set_rts_cgo(modbus_t *ctx, int on);
 import "C"
 import (
 	"sync"
+	"syscall"
 	"time"
 	"unsafe"
 )
 
-var mapSetRtsCallback = sync.Map{}
+// rtsCallbacks custom RTS callbacks of the libmodbus contexts keyed by their C context, so that a call of libmodbus
+// reaches only the Modbus owning the context
+var rtsCallbacks = sync.Map{}
+
+type rtsCallback struct {
+	x  *Modbus
+	cb SetRtsCallback
+}
 
 //export set_rts_go
 func set_rts_go(ctx *C.modbus_t, on C.int) {
-	mapSetRtsCallback.Range(func(key, value any) bool {
-		f, ok := value.(SetRtsCallback)
-		if ok {
-			f(&Modbus{ctx: ctx}, int(on))
-		} else {
-			mapSetRtsCallback.Delete(key)
-		}
-		return true
-	})
+	if v, ok := rtsCallbacks.Load(ctx); ok {
+		r := v.(*rtsCallback)
+		r.cb(r.x, int(on))
+		return
+	}
+	// no callback anymore, toggle the RTS pin of the port like libmodbus
+	serialSetRts(int(C.modbus_get_socket(ctx)), on != 0)
 }
 
 // modbus_new_rtu modbus_new_rtu - create a libmodbus context for RTU
@@ -90,9 +98,15 @@ func ModbusNewRtu(device string, baud int, parity byte, dataBit int, stopBit int
 //
 // This function is only supported on Linux kernels 2.6.28 onwards.
 func (x *Modbus) RtuSetSerialMode(mode int) (err error) {
+	if x.be != nil {
+		return x.be.setSerialMode(mode)
+	}
 	code := C.modbus_rtu_set_serial_mode(x.ctx, C.int(mode))
 	if code < 0 {
 		err = ModbusStrError()
+		if errno := syscall.Errno(err.(*Error).code); errno != syscall.EINVAL {
+			err = serialSettingError(serialModeSetting(mode), errno)
+		}
 		return
 	}
 	return
@@ -113,6 +127,12 @@ func (x *Modbus) RtuSetSerialMode(mode int) (err error) {
 //     used effectively over long distances and in electrically noisy environments. This function is only available on
 //     Linux kernels 2.6.28 onwards and can only be used with a context using a RTU backend.
 func (x *Modbus) RtuGetSerialMode() (mode int, err error) {
+	if x.be != nil {
+		if !x.be.framing.serial() {
+			return -1, ErrorCode(syscall.EINVAL).Error()
+		}
+		return x.be.serialMode, nil
+	}
 	code := C.modbus_rtu_get_serial_mode(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
@@ -135,6 +155,9 @@ func (x *Modbus) RtuGetSerialMode() (mode int, err error) {
 //
 // This function can only be used with a context using a RTU backend.
 func (x *Modbus) RtuSetRts(mode int) (err error) {
+	if x.be != nil {
+		return x.be.setRtsMode(mode)
+	}
 	code := C.modbus_rtu_set_rts(x.ctx, C.int(mode))
 	if code < 0 {
 		err = ModbusStrError()
@@ -154,6 +177,12 @@ func (x *Modbus) RtuSetRts(mode int) (err error) {
 //
 // This function can only be used with a context using a RTU backend.
 func (x *Modbus) RtuGetRts() (mode int, err error) {
+	if x.be != nil {
+		if !x.be.framing.serial() {
+			return -1, ErrorCode(syscall.EINVAL).Error()
+		}
+		return x.be.rts, nil
+	}
 	code := C.modbus_rtu_get_rts(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
@@ -172,14 +201,27 @@ func (x *Modbus) RtuGetRts() (mode int, err error) {
 // Note that this function adheres to the RTS mode, the values MODBUS_RTU_RTS_UP or MODBUS_RTU_RTS_DOWN must be used
 // for the function to be called.
 //
+// The callback is only called for this context and receives x. A nil cb restores the toggling of the RTS pin.
+//
 // This function can only be used with a context using a RTU backend.
 func (x *Modbus) RtuSetCustomRts(cb SetRtsCallback) (err error) {
-	mapSetRtsCallback.Store(x.ctx, cb)
+	if x.be != nil {
+		if !x.be.framing.serial() {
+			return ErrorCode(syscall.EINVAL).Error()
+		}
+		x.be.setRts = backendRts(x, cb)
+		return
+	}
 	code := C.modbus_rtu_set_custom_rts(x.ctx, (C.set_rts)(C.set_rts_cgo))
 	if code < 0 {
 		err = ModbusStrError()
 		return
 	}
+	if cb == nil {
+		rtsCallbacks.Delete(x.ctx)
+	} else {
+		rtsCallbacks.Store(x.ctx, &rtsCallback{x: x, cb: cb})
+	}
 	return
 }
 
@@ -189,6 +231,9 @@ func (x *Modbus) RtuSetCustomRts(cb SetRtsCallback) (err error) {
 //
 // This function can only be used with a context using a RTU backend.
 func (x *Modbus) RtuSetRtsDelay(us time.Duration) (err error) {
+	if x.be != nil {
+		return x.be.setRtsDelay(us)
+	}
 	code := C.modbus_rtu_set_rts_delay(x.ctx, C.int(us))
 	if code < 0 {
 		err = ModbusStrError()
@@ -204,6 +249,12 @@ func (x *Modbus) RtuSetRtsDelay(us time.Duration) (err error) {
 //
 // This function can only be used with a context using a RTU backend.
 func (x *Modbus) RtuGetRtsDelay() (us time.Duration, err error) {
+	if x.be != nil {
+		if !x.be.framing.serial() {
+			return 0, ErrorCode(syscall.EINVAL).Error()
+		}
+		return x.be.rtsDelay, nil
+	}
 	code := C.modbus_rtu_get_rts_delay(x.ctx)
 	if code < 0 {
 		err = ModbusStrError()
@@ -213,6 +264,63 @@ func (x *Modbus) RtuGetRtsDelay() (us time.Duration, err error) {
 	return
 }
 
+// RtuSetConfig set the serial port options of a RTU context
+//
+// The options are applied to the port by Connect() after the line settings, or immediately when the context is
+// already connected. The Go backend applies them again when MODBUS_ERROR_RECOVERY_LINK reconnects, the libmodbus
+// backend reconnects inside libmodbus and the reopened port keeps only the line settings until the next Connect().
+// When the driver refuses one of them the error names the setting and carries the errno of the driver, Connect()
+// then closes the port.
+//
+// This function can only be used with a context using a RTU backend.
+func (x *Modbus) RtuSetConfig(cfg RtuConfig) (err error) {
+	if x.be != nil {
+		return x.be.setConfig(cfg)
+	}
+	if C.modbus_rtu_get_serial_mode(x.ctx) < 0 {
+		err = ModbusStrError()
+		return
+	}
+	x.rtuConfig = &cfg
+	if C.modbus_get_socket(x.ctx) >= 0 {
+		return x.rtuApplyConfig()
+	}
+	return
+}
+
+// RtuGetConfig get the serial port options set by RtuSetConfig()
+func (x *Modbus) RtuGetConfig() (cfg RtuConfig, err error) {
+	if x.be != nil {
+		if !x.be.framing.serial() {
+			return cfg, ErrorCode(syscall.EINVAL).Error()
+		}
+		if x.be.config != nil {
+			cfg = *x.be.config
+		}
+		return
+	}
+	if C.modbus_rtu_get_serial_mode(x.ctx) < 0 {
+		err = ModbusStrError()
+		return
+	}
+	if x.rtuConfig != nil {
+		cfg = *x.rtuConfig
+	}
+	return
+}
+
+// rtuApplyConfig apply the serial port options to the connected port of a libmodbus context
+func (x *Modbus) rtuApplyConfig() (err error) {
+	fd := int(C.modbus_get_socket(x.ctx))
+	if x.rtuConfig.Rs485 != nil {
+		// let libmodbus know the mode, the settings of the driver are completed below
+		if C.modbus_rtu_set_serial_mode(x.ctx, C.MODBUS_RTU_RS485) < 0 {
+			return serialSettingError(serialModeSetting(MODBUS_RTU_RS485), syscall.Errno(ModbusStrError().(*Error).code))
+		}
+	}
+	return serialApplyConfig(fd, x.rtuConfig)
+}
+
 // RtuReceive modbus_receive - receive an indication request
 //
 // The modbus_receive() function shall receive an indication request from the socket of the context ctx. This function
@@ -221,6 +329,9 @@ func (x *Modbus) RtuGetRtsDelay() (us time.Duration, err error) {
 // If you need to use another socket or file descriptor than the one defined in the context ctx, see the function
 // modbus_set_socket.
 func (x *Modbus) RtuReceive() (req []byte, err error) {
+	if x.be != nil {
+		return x.be.receiveIndication()
+	}
 	recv := make([]C.uint8_t, MODBUS_RTU_MAX_ADU_LENGTH)
 	code := C.modbus_receive(x.ctx, unsafe.SliceData(recv))
 	if code < 0 {
@@ -244,6 +355,9 @@ func (x *Modbus) RtuReceive() (req []byte, err error) {
 // use the constant MODBUS_MAX_ADU_LENGTH (maximum value of all libmodbus backends). Take care to allocate enough
 // memory to store responses to avoid crashes of your server.
 func (x *Modbus) RtuReceiveConfirmation() (rsp []byte, err error) {
+	if x.be != nil {
+		return x.be.receive(false)
+	}
 	recv := make([]C.uint8_t, MODBUS_RTU_MAX_ADU_LENGTH)
 	code := C.modbus_receive_confirmation(x.ctx, unsafe.SliceData(recv))
 	if code < 0 {
@@ -0,0 +1,98 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+import (
+	"io"
+	"syscall"
+	"time"
+)
+
+// ModbusNewRtu modbus_new_rtu - create a libmodbus context for RTU
+//
+// The serial port is opened by Connect(), see ModbusNewAscii() for the supported platforms.
+func ModbusNewRtu(device string, baud int, parity byte, dataBit int, stopBit int) *Modbus {
+	if serialCheck(device, baud, parity, dataBit, stopBit) != nil {
+		return nil
+	}
+	be := modbusBackendNew(modbusRtu{}, func() (io.ReadWriteCloser, error) {
+		return serialOpen(device, baud, parity, dataBit, stopBit)
+	})
+	be.oneByteTime = serialOneByteTime(baud, parity, dataBit, stopBit)
+	be.rtsDelay = be.oneByteTime
+	return &Modbus{be: be}
+}
+
+// RtuSetSerialMode modbus_rtu_set_serial_mode - set the serial mode
+func (x *Modbus) RtuSetSerialMode(mode int) (err error) {
+	return x.be.setSerialMode(mode)
+}
+
+// RtuGetSerialMode modbus_rtu_get_serial_mode - get the current serial mode
+func (x *Modbus) RtuGetSerialMode() (mode int, err error) {
+	if !x.be.framing.serial() {
+		return -1, ErrorCode(syscall.EINVAL).Error()
+	}
+	return x.be.serialMode, nil
+}
+
+// RtuSetRts modbus_rtu_set_rts - set the RTS mode in RTU
+func (x *Modbus) RtuSetRts(mode int) (err error) {
+	return x.be.setRtsMode(mode)
+}
+
+// RtuGetRts modbus_rtu_get_rts - get the current RTS mode in RTU
+func (x *Modbus) RtuGetRts() (mode int, err error) {
+	if !x.be.framing.serial() {
+		return -1, ErrorCode(syscall.EINVAL).Error()
+	}
+	return x.be.rts, nil
+}
+
+// RtuSetCustomRts modbus_rtu_set_custom_rts - set a function to be used for custom RTS implementation
+func (x *Modbus) RtuSetCustomRts(cb SetRtsCallback) (err error) {
+	if !x.be.framing.serial() {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	x.be.setRts = backendRts(x, cb)
+	return
+}
+
+// RtuSetRtsDelay modbus_rtu_set_rts_delay - set the RTS delay in RTU
+func (x *Modbus) RtuSetRtsDelay(us time.Duration) (err error) {
+	return x.be.setRtsDelay(us)
+}
+
+// RtuGetRtsDelay modbus_rtu_get_rts_delay - get the current RTS delay in RTU
+func (x *Modbus) RtuGetRtsDelay() (us time.Duration, err error) {
+	if !x.be.framing.serial() {
+		return 0, ErrorCode(syscall.EINVAL).Error()
+	}
+	return x.be.rtsDelay, nil
+}
+
+// RtuSetConfig set the serial port options of a RTU context
+func (x *Modbus) RtuSetConfig(cfg RtuConfig) (err error) {
+	return x.be.setConfig(cfg)
+}
+
+// RtuGetConfig get the serial port options set by RtuSetConfig()
+func (x *Modbus) RtuGetConfig() (cfg RtuConfig, err error) {
+	if !x.be.framing.serial() {
+		return cfg, ErrorCode(syscall.EINVAL).Error()
+	}
+	if x.be.config != nil {
+		cfg = *x.be.config
+	}
+	return
+}
+
+// RtuReceive modbus_receive - receive an indication request
+func (x *Modbus) RtuReceive() (req []byte, err error) {
+	return x.be.receiveIndication()
+}
+
+// RtuReceiveConfirmation modbus_receive_confirmation - receive a confirmation request
+func (x *Modbus) RtuReceiveConfirmation() (rsp []byte, err error) {
+	return x.be.receive(false)
+}
@@ -0,0 +1,251 @@
+package libmodbusgo
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// RtuBusNew create a RTU master scheduling the requests of many goroutines on a serial line
+//
+// The serial line parameters are those of ModbusNewRtu(). The serial context can be configured through Modbus()
+// (response timeout, serial mode, RTS...) before calling Run(), the bus must be released with Free().
+func RtuBusNew(device string, baud int, parity byte, dataBit int, stopBit int) (b *RtuBus, err error) {
+	x := ModbusNewRtu(device, baud, parity, dataBit, stopBit)
+	if x == nil {
+		err = ModbusStrError()
+		return
+	}
+	gap := 1750 * time.Microsecond
+	if baud <= 19200 {
+		gap = serialOneByteTime(baud, parity, dataBit, stopBit) * 7 / 2
+	}
+	b = &RtuBus{
+		FrameGap:        gap,
+		TurnaroundDelay: 100 * time.Millisecond,
+		x:               x,
+		devices:         map[int]*rtuBusDevice{},
+		wake:            make(chan struct{}, 1),
+	}
+	return
+}
+
+// Modbus return the serial context owned by the bus
+func (b *RtuBus) Modbus() *Modbus {
+	return b.x
+}
+
+// Free release the serial context
+func (b *RtuBus) Free() {
+	b.x.Free()
+}
+
+// SetMinInterval set the minimum time between the starts of two requests to a slave, for the slow devices dropping
+// the requests arriving too fast. Zero removes the limit.
+func (b *RtuBus) SetMinInterval(slave int, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.device(slave).minInterval = d
+}
+
+// Do queue a request to a slave and wait for its completion
+//
+// Once the bus is free and the timings of the line and of the slave allow it, fn is called by Run() with the slave
+// set on the context and its error is returned. The queued requests are served by decreasing priority, then in
+// order. Do returns the error of ctx when it is done before fn is called, and ECANCELED when Run() stops first or
+// has already returned.
+func (b *RtuBus) Do(ctx context.Context, slave int, priority ModbusPriority, fn func(x *Modbus) error) (err error) {
+	job := &rtuBusJob{
+		slave:    slave,
+		priority: priority,
+		fn:       fn,
+		done:     make(chan struct{}),
+	}
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return ErrorCode(syscall.ECANCELED).Error()
+	}
+	b.seq++
+	job.seq = b.seq
+	b.queue = append(b.queue, job)
+	b.mu.Unlock()
+	b.signal()
+
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+	}
+	b.mu.Lock()
+	queued := b.remove(job)
+	b.mu.Unlock()
+	if queued {
+		return ctx.Err()
+	}
+	// already picked by Run, wait for fn
+	<-job.done
+	return job.err
+}
+
+// Run connect the serial line and send the queued requests until ctx is done
+//
+// Run returns the context error once cancelled or the connection error, the requests still queued then fail with
+// ECANCELED.
+func (b *RtuBus) Run(ctx context.Context) (err error) {
+	b.mu.Lock()
+	b.stopped = false
+	b.mu.Unlock()
+	x := b.x
+	err = x.Connect()
+	if err != nil {
+		b.cancelQueue()
+		return
+	}
+	defer x.Close()
+	defer b.cancelQueue()
+
+	idle := time.Now()
+	for {
+		// the silent interval is kept before picking the request so that the ones queued meanwhile compete
+		if err = b.sleep(ctx, time.Until(idle), false); err != nil {
+			return
+		}
+		b.mu.Lock()
+		job, wait := b.next(time.Now())
+		b.mu.Unlock()
+		if job == nil {
+			if err = b.sleep(ctx, wait, true); err != nil {
+				return
+			}
+			continue
+		}
+
+		start := time.Now()
+		if job.err = x.SetSlave(job.slave); job.err == nil {
+			if job.slave == MODBUS_BROADCAST_ADDRESS {
+				job.err = b.broadcast(x, job.fn)
+			} else {
+				job.err = job.fn(x)
+			}
+		}
+		end := time.Now()
+		idle = end.Add(b.FrameGap)
+		if job.slave == MODBUS_BROADCAST_ADDRESS {
+			idle = end.Add(max(b.FrameGap, b.TurnaroundDelay))
+		}
+		b.mu.Lock()
+		if d := b.device(job.slave); d.minInterval > 0 {
+			d.next = start.Add(d.minInterval)
+		}
+		b.mu.Unlock()
+		close(job.done)
+	}
+}
+
+// broadcast call fn for a broadcast request, no slave answers so the response is only awaited during the turnaround
+// delay and its timeout is not an error
+func (b *RtuBus) broadcast(x *Modbus, fn func(x *Modbus) error) (err error) {
+	timeout, err := x.GetResponseTimeout()
+	if err != nil {
+		return
+	}
+	if err = x.SetResponseTimeout(max(b.FrameGap, b.TurnaroundDelay, time.Millisecond)); err != nil {
+		return
+	}
+	defer x.SetResponseTimeout(timeout)
+	err = fn(x)
+	var merr *Error
+	if errors.As(err, &merr) && merr.Code() == ErrorCode(syscall.ETIMEDOUT) {
+		err = nil
+	}
+	return
+}
+
+func (b *RtuBus) device(slave int) *rtuBusDevice {
+	d, ok := b.devices[slave]
+	if !ok {
+		d = &rtuBusDevice{}
+		b.devices[slave] = d
+	}
+	return d
+}
+
+// next remove and return the request to send, or return the time until a queued request is allowed (a negative
+// wait when the queue is empty)
+func (b *RtuBus) next(now time.Time) (job *rtuBusJob, wait time.Duration) {
+	wait = -1
+	best := -1
+	for i, j := range b.queue {
+		if d := b.devices[j.slave]; d != nil && d.next.After(now) {
+			if w := d.next.Sub(now); wait < 0 || w < wait {
+				wait = w
+			}
+			continue
+		}
+		if best < 0 || j.priority > b.queue[best].priority ||
+			(j.priority == b.queue[best].priority && j.seq < b.queue[best].seq) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return
+	}
+	job = b.queue[best]
+	b.queue = append(b.queue[:best], b.queue[best+1:]...)
+	return
+}
+
+func (b *RtuBus) remove(job *rtuBusJob) bool {
+	for i, j := range b.queue {
+		if j == job {
+			b.queue = append(b.queue[:i], b.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (b *RtuBus) cancelQueue() {
+	b.mu.Lock()
+	queue := b.queue
+	b.queue = nil
+	b.stopped = true
+	b.mu.Unlock()
+	for _, j := range queue {
+		j.err = ErrorCode(syscall.ECANCELED).Error()
+		close(j.done)
+	}
+}
+
+func (b *RtuBus) signal() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sleep wait for d, interrupted by a new request when wake is set, forever when d is negative
+func (b *RtuBus) sleep(ctx context.Context, d time.Duration, wake bool) error {
+	if d == 0 || (d < 0 && !wake) {
+		return ctx.Err()
+	}
+	var timeout <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	var wakeup <-chan struct{}
+	if wake {
+		wakeup = b.wake
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout:
+	case <-wakeup:
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package libmodbusgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRtuBus(t *testing.T) {
+	master, slave := openPty(t)
+	bus, err := RtuBusNew(slave, 115200, 'N', 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Free()
+	if bus.FrameGap != 1750*time.Microsecond {
+		t.Errorf("frame gap %v", bus.FrameGap)
+	}
+	bus.FrameGap = 20 * time.Millisecond
+	bus.TurnaroundDelay = 60 * time.Millisecond
+	bus.SetMinInterval(5, 100*time.Millisecond)
+
+	mm := conformanceMapping(t)
+	defer mm.Free()
+	mm.SetTabRegisters(3, 0x1234)
+	server := conformanceServe(ModbusNewConn(master, MODBUS_FRAMING_RTU), 17, mm)
+	defer server.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- bus.Run(ctx) }()
+
+	var values []uint16
+	err = bus.Do(context.Background(), 17, MODBUS_PRIORITY_NORMAL, func(x *Modbus) (err error) {
+		values, err = x.ReadRegisters(3, 1)
+		return
+	})
+	if err != nil || len(values) != 1 || values[0] != 0x1234 {
+		t.Fatalf("%v %v", values, err)
+	}
+
+	// hold the bus while the requests are queued
+	var release chan struct{}
+	hold := func() {
+		release = make(chan struct{})
+		held := make(chan struct{})
+		go bus.Do(context.Background(), 1, MODBUS_PRIORITY_NORMAL, func(x *Modbus) error {
+			close(held)
+			<-release
+			return nil
+		})
+		<-held
+	}
+	hold()
+	waitQueued := func(n int) {
+		for {
+			bus.mu.Lock()
+			l := len(bus.queue)
+			bus.mu.Unlock()
+			if l == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	type start struct {
+		slave int
+		at    time.Time
+	}
+	var mu sync.Mutex
+	var starts []start
+	var wg sync.WaitGroup
+	queue := func(slave int, priority ModbusPriority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := bus.Do(context.Background(), slave, priority, func(x *Modbus) error {
+				mu.Lock()
+				starts = append(starts, start{slave, time.Now()})
+				mu.Unlock()
+				if slave == MODBUS_BROADCAST_ADDRESS {
+					// unanswered, the request completes after the turnaround delay
+					return x.WriteRegister(0, 0x55)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	queue(2, MODBUS_PRIORITY_LOW)
+	waitQueued(1)
+	queue(3, MODBUS_PRIORITY_NORMAL)
+	waitQueued(2)
+	queue(MODBUS_BROADCAST_ADDRESS, MODBUS_PRIORITY_HIGH)
+	waitQueued(3)
+	queue(5, MODBUS_PRIORITY_HIGH)
+	waitQueued(4)
+	queue(5, MODBUS_PRIORITY_HIGH)
+	waitQueued(5)
+
+	cancelled, cancelDo := context.WithCancel(context.Background())
+	cancelDo()
+	if err = bus.Do(cancelled, 4, MODBUS_PRIORITY_HIGH, func(x *Modbus) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("%v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	// alarms before trends, the second request to the slow slave waits for its interval
+	want := []int{MODBUS_BROADCAST_ADDRESS, 5, 3, 2, 5}
+	if len(starts) != len(want) {
+		t.Fatalf("%v", starts)
+	}
+	for i, s := range starts {
+		if s.slave != want[i] {
+			t.Fatalf("%v, want slaves %v", starts, want)
+		}
+		if i == 0 {
+			continue
+		}
+		gap := s.at.Sub(starts[i-1].at)
+		switch {
+		case i == 1 && gap < bus.TurnaroundDelay:
+			t.Errorf("turnaround after broadcast %v", gap)
+		case gap < bus.FrameGap:
+			t.Errorf("frame gap %v before slave %d", gap, s.slave)
+		}
+	}
+	if d := starts[4].at.Sub(starts[1].at); d < 100*time.Millisecond {
+		t.Errorf("interval of slave 5 %v", d)
+	}
+	err = bus.Do(context.Background(), 17, MODBUS_PRIORITY_NORMAL, func(x *Modbus) (err error) {
+		values, err = x.ReadRegisters(0, 1)
+		return
+	})
+	if err != nil || len(values) != 1 || values[0] != 0x55 {
+		t.Errorf("broadcast write %v %v", values, err)
+	}
+
+	// the requests left when the bus stops are cancelled
+	hold()
+	pending := make(chan error, 1)
+	go func() {
+		pending <- bus.Do(context.Background(), 2, MODBUS_PRIORITY_NORMAL, func(x *Modbus) error { return nil })
+	}()
+	waitQueued(1)
+	cancel()
+	close(release)
+	if err = <-stopped; !errors.Is(err, context.Canceled) {
+		t.Errorf("%v", err)
+	}
+	var merr *Error
+	if err = <-pending; !errors.As(err, &merr) || merr.Code() != ErrorCode(syscall.ECANCELED) {
+		t.Errorf("%v", err)
+	}
+	// and the later ones fail without waiting
+	err = bus.Do(context.Background(), 2, MODBUS_PRIORITY_NORMAL, func(x *Modbus) error { return nil })
+	if !errors.As(err, &merr) || merr.Code() != ErrorCode(syscall.ECANCELED) {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package libmodbusgo
+
+import (
+	"sync"
+	"time"
+)
+
+// ModbusPriority priority of a request queued on a RtuBus, the higher first
+type ModbusPriority int
+
+const (
+	MODBUS_PRIORITY_LOW    ModbusPriority = -1 // trends, bulk reads
+	MODBUS_PRIORITY_NORMAL ModbusPriority = 0
+	MODBUS_PRIORITY_HIGH   ModbusPriority = 1 // alarms, commands
+)
+
+// RtuBus Modbus RTU master owning a multi-drop serial line shared by goroutines
+//
+// The requests queued by Do() are sent one at a time by Run() with the silent intervals of the serial line
+// (Modbus_over_serial_line_V1_02.pdf, chapter 2.5.1.1) between the frames.
+type RtuBus struct {
+	// FrameGap silent interval kept between the end of an exchange and the next request, 3.5 characters at the baud
+	// rate of the line and 1750 µs above 19200 bauds
+	FrameGap time.Duration
+	// TurnaroundDelay silent interval kept after a broadcast request so that the slaves can process it, it is also
+	// the response timeout of the broadcast requests that no slave answers. The default is 100 ms.
+	TurnaroundDelay time.Duration
+
+	x       *Modbus
+	mu      sync.Mutex
+	queue   []*rtuBusJob
+	seq     uint64
+	devices map[int]*rtuBusDevice
+	wake    chan struct{}
+	stopped bool // Run() has returned, Do() fails
+}
+
+// rtuBusDevice polling state of a slave
+type rtuBusDevice struct {
+	minInterval time.Duration
+	next        time.Time // earliest start of the next request
+}
+
+// rtuBusJob request queued on the bus
+type rtuBusJob struct {
+	slave    int
+	priority ModbusPriority
+	seq      uint64
+	fn       func(x *Modbus) error
+	err      error
+	done     chan struct{}
+}
@@ -0,0 +1,25 @@
+package libmodbusgo
+
+import "time"
+
+// RtuConfig serial port options applied by Connect() on top of the line settings given at creation
+type RtuConfig struct {
+	// Baud non-standard baud rate set with BOTHER in place of the one of the constructor, give the same rate to the
+	// constructor as the character time of the protocol is computed from it
+	Baud int
+	// LowLatency set ASYNC_LOW_LATENCY on the port, the USB adapters then forward the received bytes immediately
+	LowLatency bool
+	// Exclusive lock the tty (TIOCEXCL and flock) so that no other process can open it
+	Exclusive bool
+	// Rs485 enable the RS-485 mode of the driver with these settings
+	Rs485 *RtuRs485Config
+}
+
+// RtuRs485Config RS-485 settings of the driver, see struct serial_rs485 of linux/serial.h
+type RtuRs485Config struct {
+	RtsOnSend          bool          // SER_RS485_RTS_ON_SEND, RTS level while sending
+	RtsAfterSend       bool          // SER_RS485_RTS_AFTER_SEND, RTS level after sending
+	RxDuringTx         bool          // SER_RS485_RX_DURING_TX, receive while sending
+	DelayRtsBeforeSend time.Duration // delay between the RTS set and the send, in milliseconds for the driver
+	DelayRtsAfterSend  time.Duration // delay between the end of the send and the RTS reset, in milliseconds for the driver
+}
@@ -0,0 +1,74 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestModbus_RtuSetConfig(t *testing.T) {
+	constructors := map[string]func(device string) *Modbus{
+		"rtu":   func(device string) *Modbus { return ModbusNewRtu(device, 250000, 'N', 8, 1) },
+		"ascii": func(device string) *Modbus { return ModbusNewAscii(device, 250000, 'N', 8, 1) },
+	}
+	for name, newCtx := range constructors {
+		t.Run(name, func(t *testing.T) {
+			_, slave := openPty(t)
+
+			ctx := newCtx(slave)
+			if ctx == nil {
+				t.FailNow()
+			}
+			defer ctx.Free()
+			if err := ctx.RtuSetConfig(RtuConfig{Baud: 250000, Exclusive: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := ctx.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			defer ctx.Close()
+			fd, _ := ctx.GetSocket()
+			tios, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tios.Ospeed != 250000 || tios.Ispeed != 250000 {
+				t.Errorf("speed %d/%d", tios.Ispeed, tios.Ospeed)
+			}
+
+			// the port is locked by the first context
+			other := newCtx(slave)
+			defer other.Free()
+			other.RtuSetConfig(RtuConfig{Exclusive: true})
+			err = other.Connect()
+			var e *Error
+			if !errors.As(err, &e) || e.Code() != ErrorCode(syscall.EWOULDBLOCK) || !strings.Contains(err.Error(), "exclusive lock") {
+				t.Errorf("%v", err)
+			}
+
+			// a pseudo-terminal has no RS-485 mode, the error names the setting
+			err = ctx.RtuSetConfig(RtuConfig{Rs485: &RtuRs485Config{RtsOnSend: true, DelayRtsAfterSend: time.Millisecond}})
+			if !errors.As(err, &e) || e.Code() != ErrorCode(syscall.ENOTTY) || !strings.Contains(err.Error(), "RS-485 mode") {
+				t.Errorf("%v", err)
+			}
+			ctx.Close()
+			if err = ctx.Connect(); err == nil || !strings.Contains(err.Error(), "RS-485 mode") {
+				t.Errorf("%v", err)
+			}
+			cfg, err := ctx.RtuGetConfig()
+			if err != nil || cfg.Rs485 == nil || !cfg.Rs485.RtsOnSend {
+				t.Errorf("%+v %v", cfg, err)
+			}
+		})
+	}
+
+	tcp := ModbusNewTcp("127.0.0.1", 1502)
+	defer tcp.Free()
+	if err := tcp.RtuSetConfig(RtuConfig{LowLatency: true}); err == nil {
+		t.Error("RtuSetConfig on a TCP context")
+	}
+}
@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 package libmodbusgo
 
 /*
@@ -22,5 +24,3 @@ const (
 	MODBUS_RTU_RTS_UP   = C.MODBUS_RTU_RTS_UP
 	MODBUS_RTU_RTS_DOWN = C.MODBUS_RTU_RTS_DOWN
 )
-
-type SetRtsCallback func(ctx *Modbus, on int)
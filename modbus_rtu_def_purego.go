@@ -0,0 +1,20 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+const (
+	// MODBUS_RTU_MAX_ADU_LENGTH Modbus_Application_Protocol_V1_1b.pdf Chapter 4 Section 1 Page 5
+	// RS232 / RS485 ADU = 253 bytes + slave (1 byte) + CRC (2 bytes) = 256 bytes
+	MODBUS_RTU_MAX_ADU_LENGTH = 256
+)
+
+const (
+	MODBUS_RTU_RS232 = 0
+	MODBUS_RTU_RS485 = 1
+)
+
+const (
+	MODBUS_RTU_RTS_NONE = 0
+	MODBUS_RTU_RTS_UP   = 1
+	MODBUS_RTU_RTS_DOWN = 2
+)
@@ -0,0 +1,93 @@
+package libmodbusgo
+
+const (
+	modbusRtuHeaderLength   = 1
+	modbusRtuChecksumLength = 2
+)
+
+// modbusRtu framing of Modbus RTU for the Go backends, the binary ADU is the slave, the PDU and the CRC
+type modbusRtu struct{}
+
+func (modbusRtu) headerLength() int   { return modbusRtuHeaderLength }
+func (modbusRtu) checksumLength() int { return modbusRtuChecksumLength }
+func (modbusRtu) serial() bool        { return true }
+
+func (modbusRtu) encode(adu []byte) []byte {
+	crc := modbusCrc16(adu)
+	return append(adu[:len(adu):len(adu)], byte(crc), byte(crc>>8))
+}
+
+// metaLength number of bytes following the function code whose length is known from it, see
+// compute_meta_length_after_function()
+func rtuMetaLength(function byte, indication bool) int {
+	if indication {
+		switch function {
+		case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS, MODBUS_FC_READ_HOLDING_REGISTERS,
+			MODBUS_FC_READ_INPUT_REGISTERS, MODBUS_FC_WRITE_SINGLE_COIL, MODBUS_FC_WRITE_SINGLE_REGISTER:
+			return 4
+		case MODBUS_FC_WRITE_MULTIPLE_COILS, MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+			return 5
+		case MODBUS_FC_MASK_WRITE_REGISTER:
+			return 6
+		case MODBUS_FC_WRITE_AND_READ_REGISTERS:
+			return 9
+		}
+		// MODBUS_FC_READ_EXCEPTION_STATUS, MODBUS_FC_REPORT_SLAVE_ID
+		return 0
+	}
+	switch function {
+	case MODBUS_FC_WRITE_SINGLE_COIL, MODBUS_FC_WRITE_SINGLE_REGISTER, MODBUS_FC_WRITE_MULTIPLE_COILS,
+		MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		return 4
+	case MODBUS_FC_MASK_WRITE_REGISTER:
+		return 6
+	}
+	return 1
+}
+
+// rtuDataLength number of bytes following the meta data, given by its last byte, see compute_data_length_after_meta()
+func rtuDataLength(function byte, indication bool, meta []byte) int {
+	if indication {
+		switch function {
+		case MODBUS_FC_WRITE_MULTIPLE_COILS, MODBUS_FC_WRITE_MULTIPLE_REGISTERS, MODBUS_FC_WRITE_AND_READ_REGISTERS:
+			return int(meta[len(meta)-1])
+		}
+		return 0
+	}
+	switch function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS, MODBUS_FC_READ_HOLDING_REGISTERS,
+		MODBUS_FC_READ_INPUT_REGISTERS, MODBUS_FC_REPORT_SLAVE_ID, MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		return int(meta[0])
+	}
+	return 0
+}
+
+// read receive a frame, its length is computed from the function code as RTU has no delimiter
+func (modbusRtu) read(rd *modbusReader, indication bool) (adu []byte, err error) {
+	adu, err = rd.readFull(modbusRtuHeaderLength + 1)
+	if err != nil {
+		return
+	}
+	function := adu[modbusRtuHeaderLength]
+	meta, err := rd.readFull(rtuMetaLength(function, indication))
+	if err != nil {
+		return nil, err
+	}
+	adu = append(adu, meta...)
+	n := 0
+	if len(meta) > 0 {
+		n = rtuDataLength(function, indication, meta)
+	}
+	if len(adu)+n+modbusRtuChecksumLength > MODBUS_RTU_MAX_ADU_LENGTH {
+		return nil, EMBBADDATA.Error()
+	}
+	data, err := rd.readFull(n + modbusRtuChecksumLength)
+	if err != nil {
+		return nil, err
+	}
+	adu = append(adu, data...)
+	if modbusCrc16(adu) != 0 {
+		return nil, EMBBADCRC.Error()
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package libmodbusgo
+
+import "os"
+
+// ModbusNewGpioSysfs open the value file of an exported GPIO line, its direction must already be set to out
+func ModbusNewGpioSysfs(path string) (g *GpioSysfs, err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, modbusErrno(err)
+	}
+	return &GpioSysfs{f: f}, nil
+}
+
+// SetValue drive the line high or low
+func (g *GpioSysfs) SetValue(high bool) (err error) {
+	v := []byte("0")
+	if high {
+		v[0] = '1'
+	}
+	_, err = g.f.WriteAt(v, 0)
+	return
+}
+
+// Close close the value file of the line
+func (g *GpioSysfs) Close() error {
+	return g.f.Close()
+}
+
+// RtsGpio custom RTS callback driving a GPIO line, the line is high when RTS is on unless activeLow
+//
+// The RTS callbacks cannot fail so the errors of the line are dropped, drive it once before to check it.
+func RtsGpio(g Gpio, activeLow bool) SetRtsCallback {
+	return func(ctx *Modbus, on int) {
+		g.SetValue((on != 0) != activeLow)
+	}
+}
+
+// RtuSetRtsGpio set a GPIO line driven in place of the RTS pin, see RtuSetCustomRts() and RtsGpio()
+//
+// This function can only be used with a context using a RTU backend.
+func (x *Modbus) RtuSetRtsGpio(g Gpio, activeLow bool) (err error) {
+	return x.RtuSetCustomRts(RtsGpio(g, activeLow))
+}
@@ -0,0 +1,117 @@
+package libmodbusgo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type gpioRecorder struct {
+	values []bool
+}
+
+func (g *gpioRecorder) SetValue(high bool) error {
+	g.values = append(g.values, high)
+	return nil
+}
+
+func TestModbus_RtuSetCustomRts(t *testing.T) {
+	type call struct {
+		ctx *Modbus
+		on  int
+	}
+	var calls [2][]call
+	buses := make([]*Modbus, 2)
+	for i := range buses {
+		_, slave := openPty(t)
+		ctx := ModbusNewRtu(slave, 115200, 'N', 8, 1)
+		if ctx == nil {
+			t.FailNow()
+		}
+		defer ctx.Free()
+		if err := ctx.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		ctx.SetSlave(1)
+		ctx.SetResponseTimeout(10 * time.Millisecond)
+		if err := ctx.RtuSetCustomRts(func(ctx *Modbus, on int) {
+			calls[i] = append(calls[i], call{ctx, on})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ctx.RtuSetRts(MODBUS_RTU_RTS_UP); err != nil {
+			t.Fatal(err)
+		}
+		buses[i] = ctx
+	}
+	calls = [2][]call{}
+
+	// a request on the first bus only toggles its own RTS
+	buses[0].WriteRegister(0, 1)
+	if len(calls[0]) != 2 || calls[0][0].on != 1 || calls[0][1].on != 0 || len(calls[1]) != 0 {
+		t.Fatalf("%v", calls)
+	}
+	for _, c := range calls[0] {
+		if c.ctx != buses[0] {
+			t.Errorf("callback called with %p, want %p", c.ctx, buses[0])
+		}
+	}
+
+	// without callback the RTS pin of the port is toggled again
+	if err := buses[0].RtuSetCustomRts(nil); err != nil {
+		t.Fatal(err)
+	}
+	calls = [2][]call{}
+	buses[0].WriteRegister(0, 1)
+	if len(calls[0]) != 0 || len(calls[1]) != 0 {
+		t.Errorf("%v", calls)
+	}
+}
+
+func TestModbus_RtuSetRtsGpio(t *testing.T) {
+	_, slave := openPty(t)
+	ctx := ModbusNewRtu(slave, 115200, 'N', 8, 1)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	if err := ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.SetSlave(1)
+	ctx.SetResponseTimeout(10 * time.Millisecond)
+
+	g := &gpioRecorder{}
+	if err := ctx.RtuSetRtsGpio(g, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.RtuSetRts(MODBUS_RTU_RTS_UP); err != nil {
+		t.Fatal(err)
+	}
+	ctx.WriteRegister(0, 1)
+	// active low: idle high, low while sending
+	if len(g.values) != 3 || !g.values[0] || g.values[1] || !g.values[2] {
+		t.Errorf("%v", g.values)
+	}
+
+	path := filepath.Join(t.TempDir(), "value")
+	if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sysfs, err := ModbusNewGpioSysfs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sysfs.Close()
+	if err = ctx.RtuSetRtsGpio(sysfs, false); err != nil {
+		t.Fatal(err)
+	}
+	ctx.WriteRegister(0, 1)
+	if b, _ := os.ReadFile(path); string(b) != "0" {
+		t.Errorf("%q", b)
+	}
+	if _, err = ModbusNewGpioSysfs(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing GPIO opened")
+	}
+}
@@ -0,0 +1,14 @@
+package libmodbusgo
+
+import "os"
+
+// Gpio output line driving the transceiver of a RS-485 bus in place of the RTS pin, see RtuSetRtsGpio()
+type Gpio interface {
+	// SetValue drive the line high or low
+	SetValue(high bool) error
+}
+
+// GpioSysfs GPIO line driven through its value file of the sysfs interface, eg. /sys/class/gpio/gpio17/value
+type GpioSysfs struct {
+	f *os.File
+}
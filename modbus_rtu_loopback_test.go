@@ -0,0 +1,227 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// rtuFaults faults injected by a rtuLoopback in one direction
+type rtuFaults struct {
+	ByteDelay time.Duration            // delay before forwarding each byte
+	Noise     func(n int, b byte) byte // byte forwarded in place of the n-th byte since the faults were set
+}
+
+// rtuRandomNoise noise flipping one random bit of a byte with the given probability, the sequence is reproducible
+func rtuRandomNoise(rate float64, seed uint64) func(n int, b byte) byte {
+	r := rand.New(rand.NewPCG(seed, seed))
+	return func(n int, b byte) byte {
+		if r.Float64() < rate {
+			b ^= 1 << r.IntN(8)
+		}
+		return b
+	}
+}
+
+// rtuLoopback virtual serial line made of two pseudo-terminals bridged in-process, the RTU client opens Client and
+// the RTU server opens Server
+type rtuLoopback struct {
+	Client string
+	Server string
+
+	mu       sync.Mutex
+	toServer rtuFaults
+	toClient rtuFaults
+	n        [2]int
+}
+
+func newRtuLoopback(t *testing.T) *rtuLoopback {
+	clientMaster, client := openPty(t)
+	serverMaster, server := openPty(t)
+	l := &rtuLoopback{Client: client, Server: server}
+	go l.forward(clientMaster, serverMaster, 0)
+	go l.forward(serverMaster, clientMaster, 1)
+	return l
+}
+
+// SetFaults set the faults of both directions, the zero value forwards the bytes unchanged
+func (l *rtuLoopback) SetFaults(toServer, toClient rtuFaults) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.toServer, l.toClient = toServer, toClient
+	l.n = [2]int{}
+}
+
+func (l *rtuLoopback) forward(from, to *os.File, dir int) {
+	buf := make([]byte, MODBUS_RTU_MAX_ADU_LENGTH)
+	for {
+		n, err := from.Read(buf)
+		if err != nil {
+			// EIO until the slave side is opened, the end of the test otherwise
+			if errors.Is(err, syscall.EIO) {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			return
+		}
+		for _, b := range buf[:n] {
+			l.mu.Lock()
+			faults := l.toServer
+			if dir == 1 {
+				faults = l.toClient
+			}
+			i := l.n[dir]
+			l.n[dir]++
+			l.mu.Unlock()
+			if faults.ByteDelay > 0 {
+				time.Sleep(faults.ByteDelay)
+			}
+			if faults.Noise != nil {
+				b = faults.Noise(i, b)
+			}
+			if _, err = to.Write([]byte{b}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// rtuLoopbackPair connect a RTU client and a RTU server serving mm as slave 17 through a loopback
+func rtuLoopbackPair(t *testing.T, mm *ModbusMapping) (l *rtuLoopback, client *Modbus) {
+	l = newRtuLoopback(t)
+	server := ModbusNewRtu(l.Server, 115200, 'E', 8, 1)
+	if server == nil {
+		t.FailNow()
+	}
+	if err := server.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	server.SetSlave(17)
+	server.SetIndicationTimeout(20 * time.Millisecond)
+	server.SetByteTimeout(10 * time.Millisecond)
+	stop := make(chan struct{})
+	var done sync.WaitGroup
+	done.Add(1)
+	go func() {
+		defer done.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			req, err := server.RtuReceive()
+			if err != nil {
+				// drop the rest of a damaged frame
+				server.Flush()
+				continue
+			}
+			if len(req) > 0 {
+				server.Reply(req, mm)
+			}
+		}
+	}()
+
+	client = ModbusNewRtu(l.Client, 115200, 'E', 8, 1)
+	if client == nil {
+		t.FailNow()
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	client.SetSlave(17)
+	client.SetResponseTimeout(100 * time.Millisecond)
+	t.Cleanup(func() {
+		client.Free()
+		close(stop)
+		done.Wait()
+		server.Free()
+	})
+	return
+}
+
+func TestModbusNewRtu_Loopback(t *testing.T) {
+	mm := conformanceMapping(t)
+	defer mm.Free()
+	l, client := rtuLoopbackPair(t, mm)
+
+	if err := client.WriteRegisters(2, []uint16{0x1234, 0x5678}); err != nil {
+		t.Fatal(err)
+	}
+	values, err := client.ReadRegisters(2, 2)
+	if err != nil || len(values) != 2 || values[0] != 0x1234 || values[1] != 0x5678 {
+		t.Fatalf("%v %v", values, err)
+	}
+
+	// slow line, the bytes arrive within the byte timeout
+	l.SetFaults(rtuFaults{ByteDelay: time.Millisecond}, rtuFaults{ByteDelay: time.Millisecond})
+	if values, err = client.ReadRegisters(2, 2); err != nil || values[1] != 0x5678 {
+		t.Errorf("%v %v", values, err)
+	}
+
+	// the response stalls past the byte timeout
+	client.SetByteTimeout(5 * time.Millisecond)
+	l.SetFaults(rtuFaults{}, rtuFaults{ByteDelay: 20 * time.Millisecond})
+	if _, err = client.ReadRegisters(2, 2); conformanceCode(err) != ErrorCode(syscall.ETIMEDOUT) {
+		t.Errorf("%v", err)
+	}
+	l.SetFaults(rtuFaults{}, rtuFaults{})
+	time.Sleep(150 * time.Millisecond)
+	client.Flush()
+
+	// last CRC byte of the 9 bytes response corrupted
+	l.SetFaults(rtuFaults{}, rtuFaults{Noise: func(n int, b byte) byte {
+		if n == 8 {
+			return ^b
+		}
+		return b
+	}})
+	if _, err = client.ReadRegisters(2, 2); conformanceCode(err) != EMBBADCRC {
+		t.Errorf("%v", err)
+	}
+
+	// a corrupted request is dropped by the server, the client times out
+	l.SetFaults(rtuFaults{Noise: rtuRandomNoise(1, 1)}, rtuFaults{})
+	if _, err = client.ReadRegisters(2, 2); conformanceCode(err) != ErrorCode(syscall.ETIMEDOUT) {
+		t.Errorf("%v", err)
+	}
+
+	// the server recovers once the line is clean again
+	l.SetFaults(rtuFaults{}, rtuFaults{})
+	time.Sleep(100 * time.Millisecond)
+	client.Flush()
+	if values, err = client.ReadRegisters(2, 2); err != nil || values[0] != 0x1234 {
+		t.Errorf("%v %v", values, err)
+	}
+}
+
+func TestModbus_RtuSetSerialMode(t *testing.T) {
+	l := newRtuLoopback(t)
+	ctx := ModbusNewRtu(l.Client, 9600, 'N', 8, 1)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	if err := ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.RtuSetSerialMode(MODBUS_RTU_RS232); err != nil {
+		t.Error(err)
+	}
+	// a pseudo-terminal has no RS-485 mode
+	err := ctx.RtuSetSerialMode(MODBUS_RTU_RS485)
+	if conformanceCode(err) != ErrorCode(syscall.ENOTTY) || !strings.Contains(err.Error(), "RS-485 mode") {
+		t.Errorf("%v", err)
+	}
+	if mode, err := ctx.RtuGetSerialMode(); err != nil || mode != MODBUS_RTU_RS232 {
+		t.Errorf("%d %v", mode, err)
+	}
+	if err = ctx.RtuSetSerialMode(5); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package libmodbusgo
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// RtuServerNew create a RTU slave server on a serial line
+//
+// The serial line parameters are those of ModbusNewRtu(), slave is the slave number answered by the server and
+// requests are dispatched to server. The serial context can be configured through Modbus() (serial mode, RTS...)
+// before calling Run(), the server must be released with Free().
+func RtuServerNew(device string, baud int, parity byte, dataBit int, stopBit int, slave int, server *ModbusServer) (s *RtuServer, err error) {
+	x := ModbusNewRtu(device, baud, parity, dataBit, stopBit)
+	if x == nil {
+		err = ModbusStrError()
+		return
+	}
+	err = x.SetSlave(slave)
+	if err != nil {
+		x.Free()
+		return
+	}
+	s = &RtuServer{
+		Server:       server,
+		PollInterval: 100 * time.Millisecond,
+		x:            x,
+	}
+	return
+}
+
+// Modbus return the serial context owned by the server
+func (s *RtuServer) Modbus() *Modbus {
+	return s.x
+}
+
+// Free release the serial context
+func (s *RtuServer) Free() {
+	s.x.Free()
+}
+
+// Counters return a copy of the diagnostics counters, see RtuServerCounters
+func (s *RtuServer) Counters() RtuServerCounters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters
+}
+
+// ClearCounters reset the diagnostics counters
+func (s *RtuServer) ClearCounters() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters = RtuServerCounters{}
+}
+
+// SetListenOnly enter or leave listen only mode, requests are received and counted but never answered
+//
+// The mode is the one of the diagnostics sub-functions 0x04 and 0x01, it is only switched by the application.
+func (s *RtuServer) SetListenOnly(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listenOnly = on
+}
+
+// ListenOnly report whether the server is in listen only mode
+func (s *RtuServer) ListenOnly() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listenOnly
+}
+
+// Run connect the serial line and serve requests until ctx is done
+//
+// Frames with a bad CRC are counted and the line is flushed, frames addressed to other slaves are counted and
+// ignored, broadcast requests are served without response. Run returns the context error once cancelled or the first
+// serial error.
+func (s *RtuServer) Run(ctx context.Context) (err error) {
+	x := s.x
+	err = x.SetIndicationTimeout(s.PollInterval)
+	if err != nil {
+		return
+	}
+	err = x.Connect()
+	if err != nil {
+		return
+	}
+	defer x.Close()
+
+	server := &ModbusServer{
+		Handler:    s.Server.Handler,
+		middleware: append([]ModbusMiddleware{s.countExceptions}, s.Server.middleware...),
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var req []byte
+		req, err = x.RtuReceive()
+		if err != nil {
+			var merr *Error
+			if errors.As(err, &merr) {
+				switch merr.Code() {
+				case ErrorCode(syscall.ETIMEDOUT):
+					continue
+				case EMBBADCRC:
+					s.count(func(c *RtuServerCounters) { c.BusCommunicationError++ })
+					x.Flush()
+					continue
+				}
+			}
+			return
+		}
+		if len(req) == 0 {
+			// addressed to another slave
+			s.count(func(c *RtuServerCounters) { c.BusMessage++ })
+			continue
+		}
+		broadcast := req[0] == MODBUS_BROADCAST_ADDRESS
+		s.count(func(c *RtuServerCounters) {
+			c.BusMessage++
+			c.SlaveMessage++
+		})
+		if s.ListenOnly() {
+			s.count(func(c *RtuServerCounters) { c.SlaveNoResponse++ })
+			continue
+		}
+		if broadcast {
+			s.count(func(c *RtuServerCounters) { c.SlaveNoResponse++ })
+		}
+		err = server.Reply(x, req)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *RtuServer) count(f func(c *RtuServerCounters)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&s.counters)
+}
+
+// countExceptions outermost middleware of the server, it counts the exceptions returned by the chain
+func (s *RtuServer) countExceptions(next ModbusHandler) ModbusHandler {
+	return func(x *Modbus, r *ModbusRequest) (err error) {
+		err = next(x, r)
+		var exception ModbusException
+		if errors.As(err, &exception) && r.Slave != MODBUS_BROADCAST_ADDRESS {
+			s.count(func(c *RtuServerCounters) {
+				c.SlaveExceptionError++
+				switch exception {
+				case MODBUS_EXCEPTION_NEGATIVE_ACKNOWLEDGE:
+					c.SlaveNAK++
+				case MODBUS_EXCEPTION_SLAVE_OR_SERVER_BUSY:
+					c.SlaveBusy++
+				}
+			})
+		}
+		return
+	}
+}
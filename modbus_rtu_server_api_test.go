@@ -0,0 +1,132 @@
+package libmodbusgo
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRtuServer(t *testing.T) {
+	l := newRtuLoopback(t)
+	mm := conformanceMapping(t)
+	defer mm.Free()
+	busy := func(next ModbusHandler) ModbusHandler {
+		return func(x *Modbus, r *ModbusRequest) error {
+			if r.Function == MODBUS_FC_WRITE_SINGLE_REGISTER && r.Addr == 9 {
+				return MODBUS_EXCEPTION_SLAVE_OR_SERVER_BUSY
+			}
+			return next(x, r)
+		}
+	}
+	s, err := RtuServerNew(l.Server, 115200, 'E', 8, 1, 17, ModbusServerNew(ModbusMappingHandler(mm), busy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Free()
+	s.PollInterval = 20 * time.Millisecond
+	s.Modbus().SetByteTimeout(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-stopped; !errors.Is(err, context.Canceled) {
+			t.Errorf("%v", err)
+		}
+	}()
+
+	client := ModbusNewRtu(l.Client, 115200, 'E', 8, 1)
+	if client == nil {
+		t.FailNow()
+	}
+	defer client.Free()
+	if err = client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	client.SetSlave(17)
+	client.SetResponseTimeout(100 * time.Millisecond)
+	// unanswered request, the line is left idle until the server polls again
+	silent := func(err error) {
+		t.Helper()
+		if err != nil && conformanceCode(err) != ErrorCode(syscall.ETIMEDOUT) {
+			t.Errorf("%v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		client.Flush()
+	}
+
+	// the requests sent before Run has set up the serial line are lost or echoed
+	for deadline := time.Now().Add(time.Second); ; {
+		if err = client.WriteRegister(3, 0x1234); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		silent(nil)
+	}
+	s.ClearCounters()
+	if _, err = client.ReadRegisters(0, 100); conformanceCode(err) != EMBXILADD {
+		t.Errorf("%v", err)
+	}
+	if err = client.WriteRegister(9, 1); conformanceCode(err) != EMBXSBUSY {
+		t.Errorf("%v", err)
+	}
+
+	// addressed to another slave, its response is skipped as well
+	client.SetSlave(5)
+	_, err = client.ReadRegisters(3, 1)
+	silent(err)
+	if err == nil {
+		t.Error("slave 5 answered")
+	}
+	if err = client.SendRawRequest([]byte{5, MODBUS_FC_READ_HOLDING_REGISTERS, 2, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// served without response
+	client.SetSlave(MODBUS_BROADCAST_ADDRESS)
+	silent(client.WriteRegister(4, 0x55))
+	client.SetSlave(17)
+
+	// last CRC byte of the 8 bytes request corrupted
+	l.SetFaults(rtuFaults{Noise: func(n int, b byte) byte {
+		if n == 7 {
+			return ^b
+		}
+		return b
+	}}, rtuFaults{})
+	silent(client.WriteRegister(3, 0xDEAD))
+	l.SetFaults(rtuFaults{}, rtuFaults{})
+
+	s.SetListenOnly(true)
+	if !s.ListenOnly() {
+		t.Error("listen only mode")
+	}
+	silent(client.WriteRegister(3, 0xBEEF))
+	s.SetListenOnly(false)
+
+	values, err := client.ReadRegisters(3, 2)
+	if err != nil || values[0] != 0x1234 || values[1] != 0x55 {
+		t.Errorf("%04X %v", values, err)
+	}
+
+	want := RtuServerCounters{
+		BusMessage:            7,
+		BusCommunicationError: 1,
+		SlaveExceptionError:   2,
+		SlaveMessage:          5,
+		SlaveNoResponse:       2,
+		SlaveBusy:             1,
+	}
+	if c := s.Counters(); c != want {
+		t.Errorf("%+v, want %+v", c, want)
+	}
+	s.ClearCounters()
+	if c := s.Counters(); c != (RtuServerCounters{}) {
+		t.Errorf("%+v", c)
+	}
+}
@@ -0,0 +1,37 @@
+package libmodbusgo
+
+import (
+	"sync"
+	"time"
+)
+
+// RtuServerCounters serial line diagnostics counters
+//
+// The counters follow the definitions of the diagnostics function (0x08) sub-functions 0x0B to 0x12 of
+// Modbus_Application_Protocol_V1_1b.pdf (chapter 6 section 8 page 21) and wrap around at 65535 like the device
+// counters. They are only read through Counters(): the server does not answer the diagnostics function, whose
+// requests libmodbus cannot receive, so a master can neither read them nor switch the listen only mode.
+type RtuServerCounters struct {
+	BusMessage            uint16 // 0x0B messages detected on the bus, addressed to any slave
+	BusCommunicationError uint16 // 0x0C CRC errors
+	SlaveExceptionError   uint16 // 0x0D exception responses returned
+	SlaveMessage          uint16 // 0x0E messages addressed to this slave or broadcast
+	SlaveNoResponse       uint16 // 0x0F messages left unanswered (broadcast or listen only mode)
+	SlaveNAK              uint16 // 0x10 MODBUS_EXCEPTION_NEGATIVE_ACKNOWLEDGE responses
+	SlaveBusy             uint16 // 0x11 MODBUS_EXCEPTION_SLAVE_OR_SERVER_BUSY responses
+	BusCharacterOverrun   uint16 // 0x12 not reported by libmodbus, always 0
+}
+
+// RtuServer Modbus RTU slave owning its serial context
+type RtuServer struct {
+	Server *ModbusServer
+
+	// PollInterval bounds the time Run() takes to notice the cancellation of its context, it is used as
+	// indication timeout. The default is 100 ms.
+	PollInterval time.Duration
+
+	x          *Modbus
+	mu         sync.Mutex
+	counters   RtuServerCounters
+	listenOnly bool
+}
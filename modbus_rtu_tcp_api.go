@@ -0,0 +1,35 @@
+package libmodbusgo
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ModbusNewRtuTcp create a context for RTU over TCP
+//
+// The ModbusNewRtuTcp() function shall allocate and initialize a Modbus context sending RTU frames, slave address and
+// CRC included, over a TCP connection without MBAP header. This is the framing used by the serial to Ethernet
+// converters forwarding the bytes of an RS485 bus.
+//
+// The addr and port arguments are the address of the converter, the connection is established by Connect() within the
+// response timeout. In server mode, TcpListen() and TcpAccept() listen to addr and port, an empty addr listens to any
+// addresses, so the context can emulate a converter.
+//
+// The context has the same client and server functions as an RTU context, the slave must be set with SetSlave() and
+// broadcasts are not answered. The functions specific to the RTU backend return EINVAL.
+func ModbusNewRtuTcp(addr string, port int) *Modbus {
+	if port < 0 || port > 0xFFFF {
+		return nil
+	}
+	address := net.JoinHostPort(addr, strconv.Itoa(port))
+	var be *modbusBackend
+	be = modbusBackendNew(modbusRtu{}, func() (io.ReadWriteCloser, error) {
+		return net.DialTimeout("tcp", address, max(be.responseTimeout, time.Millisecond))
+	})
+	be.listen = func() (net.Listener, error) {
+		return net.Listen("tcp", address)
+	}
+	return &Modbus{be: be}
+}
@@ -0,0 +1,93 @@
+package libmodbusgo
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestModbusRtu_Encode(t *testing.T) {
+	frame := modbusRtu{}.encode([]byte{0x01, MODBUS_FC_READ_HOLDING_REGISTERS, 0x00, 0x00, 0x00, 0x01})
+	if !bytes.Equal(frame, []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x01, 0x84, 0x0A}) {
+		t.Errorf("% X", frame)
+	}
+}
+
+func TestModbusNewRtuTcp(t *testing.T) {
+	mm := ModbusMappingNew(0, 0, 10, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	mm.SetTabRegisters(0, 0x1234)
+
+	server := ModbusNewRtuTcp("127.0.0.1", 1505)
+	if server == nil {
+		t.FailNow()
+	}
+	defer server.Free()
+	server.SetSlave(1)
+	if _, err := server.TcpListen(1); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for server.TcpAccept() == nil {
+			for {
+				req, err := server.TcpReceive()
+				if err != nil {
+					break
+				}
+				if len(req) == 0 {
+					continue
+				}
+				server.Reply(req, mm)
+			}
+		}
+	}()
+
+	// the frames are the RTU ones, without MBAP header
+	conn, err := net.Dial("tcp", "127.0.0.1:1505")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x01, 0x84, 0x0A})
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	rsp := make([]byte, 7)
+	if _, err = conn.Read(rsp); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rsp, modbusRtu{}.encode([]byte{0x01, 0x03, 0x02, 0x12, 0x34})) {
+		t.Errorf("% X", rsp)
+	}
+	conn.Close()
+
+	ctx := ModbusNewRtuTcp("127.0.0.1", 1505)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(200 * time.Millisecond)
+	ctx.SetSlave(1)
+	if err = ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = ctx.WriteRegisters(1, []uint16{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	regs, err := ctx.ReadRegisters(0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regs[0] != 0x1234 || regs[1] != 1 || regs[3] != 3 {
+		t.Errorf("registers %X", regs)
+	}
+	var merr *Error
+	if _, err = ctx.ReadRegisters(9, 2); !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+		t.Error("expected illegal data address, got", err)
+	}
+	if err = ctx.RtuSetSerialMode(MODBUS_RTU_RS485); err == nil {
+		t.Error("serial mode set on a TCP connection")
+	}
+}
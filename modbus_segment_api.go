@@ -0,0 +1,288 @@
+package libmodbusgo
+
+import (
+	"iter"
+	"slices"
+)
+
+// ModbusSegmentedMappingNew allocate an empty segmented mapping
+//
+// Blocks are added per data table with AddBits(), AddInputBits(), AddRegisters() and AddInputRegisters(), adjacent
+// blocks are merged. The mapping must be released with Free().
+func ModbusSegmentedMappingNew() *ModbusSegmentedMapping {
+	empty := ModbusMappingNew(0, 0, 0, 0)
+	if empty == nil {
+		return nil
+	}
+	return &ModbusSegmentedMapping{
+		segments: map[ModbusTable][]*ModbusMapping{},
+		empty:    empty,
+	}
+}
+
+// AddBits add a block of nb coils starting at address start
+func (sm *ModbusSegmentedMapping) AddBits(start uint, nb uint) (err error) {
+	return sm.add(MODBUS_TABLE_BITS, start, nb)
+}
+
+// AddInputBits add a block of nb discrete inputs starting at address start
+func (sm *ModbusSegmentedMapping) AddInputBits(start uint, nb uint) (err error) {
+	return sm.add(MODBUS_TABLE_INPUT_BITS, start, nb)
+}
+
+// AddRegisters add a block of nb holding registers starting at address start
+func (sm *ModbusSegmentedMapping) AddRegisters(start uint, nb uint) (err error) {
+	return sm.add(MODBUS_TABLE_REGISTERS, start, nb)
+}
+
+// AddInputRegisters add a block of nb input registers starting at address start
+func (sm *ModbusSegmentedMapping) AddInputRegisters(start uint, nb uint) (err error) {
+	return sm.add(MODBUS_TABLE_INPUT_REGISTERS, start, nb)
+}
+
+// add allocate the block start..start+nb-1, it is merged with the blocks it touches so a range without hole always
+// lies in a single block
+func (sm *ModbusSegmentedMapping) add(t ModbusTable, start uint, nb uint) (err error) {
+	if nb == 0 || start+nb > 0x10000 {
+		return marshalError("invalid %s block %d+%d", t, start, nb)
+	}
+	from, to := int(start), int(start+nb)
+	var merged, kept []*ModbusMapping
+	for _, seg := range sm.segments[t] {
+		s, n := seg.tableRange(t)
+		if from < s+n && s < to {
+			return marshalError("%s block %d+%d overlaps block %d+%d", t, start, nb, s, n)
+		}
+		if s+n == int(start) || s == int(start+nb) {
+			merged = append(merged, seg)
+			from, to = min(from, s), max(to, s+n)
+		} else {
+			kept = append(kept, seg)
+		}
+	}
+	var sizes [8]uint
+	i := 2 * (int(t) - 1)
+	sizes[i], sizes[i+1] = uint(from), uint(to-from)
+	seg := ModbusMappingNewStartAddress(sizes[0], sizes[1], sizes[2], sizes[3], sizes[4], sizes[5], sizes[6], sizes[7])
+	if seg == nil {
+		err = ModbusStrError()
+		return
+	}
+	for _, m := range merged {
+		s, n := m.tableRange(t)
+		for addr := s; addr < s+n; addr++ {
+			seg.setTab(t, addr, m.getTab(t, addr))
+		}
+		m.Free()
+	}
+	segs := append(kept, seg)
+	slices.SortFunc(segs, func(a, b *ModbusMapping) int {
+		sa, _ := a.tableRange(t)
+		sb, _ := b.tableRange(t)
+		return sa - sb
+	})
+	sm.segments[t] = segs
+	return
+}
+
+// segment return the block of the table holding the whole range addr..addr+nb-1
+func (sm *ModbusSegmentedMapping) segment(t ModbusTable, addr int, nb int) *ModbusMapping {
+	for _, seg := range sm.segments[t] {
+		s, n := seg.tableRange(t)
+		if addr >= s && addr+nb <= s+n {
+			return seg
+		}
+	}
+	return nil
+}
+
+func (mm *ModbusMapping) tableRange(t ModbusTable) (start int, nb int) {
+	switch t {
+	case MODBUS_TABLE_BITS:
+		return mm.StartBits(), mm.NbBits()
+	case MODBUS_TABLE_INPUT_BITS:
+		return mm.StartInputBits(), mm.NbInputBits()
+	case MODBUS_TABLE_REGISTERS:
+		return mm.StartRegisters(), mm.NbRegisters()
+	case MODBUS_TABLE_INPUT_REGISTERS:
+		return mm.StartInputRegisters(), mm.NbInputRegisters()
+	}
+	return 0, 0
+}
+
+func (sm *ModbusSegmentedMapping) GetTabBits(addr int) (v byte, err error) {
+	seg := sm.segment(MODBUS_TABLE_BITS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	v = seg.GetTabBits(addr)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) SetTabBits(addr int, v byte) (err error) {
+	seg := sm.segment(MODBUS_TABLE_BITS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	seg.SetTabBits(addr, v)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) GetTabInputBits(addr int) (v byte, err error) {
+	seg := sm.segment(MODBUS_TABLE_INPUT_BITS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	v = seg.GetTabInputBits(addr)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) SetTabInputBits(addr int, v byte) (err error) {
+	seg := sm.segment(MODBUS_TABLE_INPUT_BITS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	seg.SetTabInputBits(addr, v)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) GetTabRegisters(addr int) (v uint16, err error) {
+	seg := sm.segment(MODBUS_TABLE_REGISTERS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	v = seg.GetTabRegisters(addr)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) SetTabRegisters(addr int, v uint16) (err error) {
+	seg := sm.segment(MODBUS_TABLE_REGISTERS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	seg.SetTabRegisters(addr, v)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) GetTabInputRegisters(addr int) (v uint16, err error) {
+	seg := sm.segment(MODBUS_TABLE_INPUT_REGISTERS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	v = seg.GetTabInputRegisters(addr)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) SetTabInputRegisters(addr int, v uint16) (err error) {
+	seg := sm.segment(MODBUS_TABLE_INPUT_REGISTERS, addr, 1)
+	if seg == nil {
+		err = EMBXILADD.Error()
+		return
+	}
+	seg.SetTabInputRegisters(addr, v)
+	return
+}
+
+func (sm *ModbusSegmentedMapping) TabBits() iter.Seq2[int, byte] {
+	return segmentsSeq(sm.segments[MODBUS_TABLE_BITS], (*ModbusMapping).TabBits)
+}
+
+func (sm *ModbusSegmentedMapping) TabInputBits() iter.Seq2[int, byte] {
+	return segmentsSeq(sm.segments[MODBUS_TABLE_INPUT_BITS], (*ModbusMapping).TabInputBits)
+}
+
+func (sm *ModbusSegmentedMapping) TabRegisters() iter.Seq2[int, uint16] {
+	return segmentsSeq(sm.segments[MODBUS_TABLE_REGISTERS], (*ModbusMapping).TabRegisters)
+}
+
+func (sm *ModbusSegmentedMapping) TabInputRegisters() iter.Seq2[int, uint16] {
+	return segmentsSeq(sm.segments[MODBUS_TABLE_INPUT_REGISTERS], (*ModbusMapping).TabInputRegisters)
+}
+
+func segmentsSeq[V any](segs []*ModbusMapping, tab func(*ModbusMapping) iter.Seq2[int, V]) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		for _, seg := range segs {
+			for k, v := range tab(seg) {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Free release all the blocks of the mapping
+func (sm *ModbusSegmentedMapping) Free() {
+	for t, segs := range sm.segments {
+		for _, seg := range segs {
+			seg.Free()
+		}
+		delete(sm.segments, t)
+	}
+	if sm.empty != nil {
+		sm.empty.Free()
+		sm.empty = nil
+	}
+}
+
+// ReplySegmented send a response to the received request using a segmented mapping
+//
+// The ReplySegmented() function behaves like Reply() but looks up the block holding the requested range. A request
+// spanning a hole, or outside of any block, is answered with MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS (or
+// MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE when the quantity itself is invalid) exactly as libmodbus does for a single
+// mapping.
+func (x *Modbus) ReplySegmented(req []byte, sm *ModbusSegmentedMapping) (err error) {
+	r, err := x.DecodeRequest(req)
+	if err != nil {
+		return
+	}
+	if r.Table == MODBUS_TABLE_NONE {
+		return x.Reply(req, sm.empty)
+	}
+	seg := sm.segment(r.Table, r.Addr, r.Nb)
+	if seg == nil {
+		return x.Reply(req, sm.empty)
+	}
+	if r.Function != MODBUS_FC_WRITE_AND_READ_REGISTERS {
+		return x.Reply(req, seg)
+	}
+
+	wseg := sm.segment(r.Table, r.WriteAddr, r.WriteNb)
+	if wseg == nil {
+		return x.Reply(req, sm.empty)
+	}
+	if wseg == seg {
+		return x.Reply(req, seg)
+	}
+	// read and write ranges live in two blocks, reply from a temporary block covering both
+	start := min(r.Addr, r.WriteAddr)
+	nb := max(r.Addr+r.Nb, r.WriteAddr+r.WriteNb) - start
+	tmp := ModbusMappingNewStartAddress(0, 0, 0, 0, uint(start), uint(nb), 0, 0)
+	if tmp == nil {
+		err = ModbusStrError()
+		return
+	}
+	defer tmp.Free()
+	seg.mu.RLock()
+	for i := range r.Nb {
+		tmp.SetTabRegisters(r.Addr+i, seg.GetTabRegisters(r.Addr+i))
+	}
+	seg.mu.RUnlock()
+	err = x.Reply(req, tmp)
+	if err != nil {
+		return
+	}
+	wseg.mu.Lock()
+	for i := range r.WriteNb {
+		wseg.SetTabRegisters(r.WriteAddr+i, tmp.GetTabRegisters(r.WriteAddr+i))
+	}
+	wseg.mu.Unlock()
+	return
+}
@@ -0,0 +1,156 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"log"
+	"syscall"
+	"testing"
+)
+
+func setupSegmented(outChan chan struct{}) {
+	ctx := ModbusNewTcp("127.0.0.1", 1503)
+	if ctx == nil {
+		log.Println("ModbusNewTcp error")
+		return
+	}
+	defer ctx.Free()
+	defer ctx.Close()
+
+	sm := ModbusSegmentedMappingNew()
+	if sm == nil {
+		log.Println("ModbusSegmentedMappingNew error")
+		return
+	}
+	defer sm.Free()
+	if err := sm.AddRegisters(0, 100); err != nil {
+		log.Fatalln(err)
+	}
+	if err := sm.AddRegisters(1000, 50); err != nil {
+		log.Fatalln(err)
+	}
+	if err := sm.AddRegisters(100, 50); err != nil {
+		log.Fatalln(err)
+	}
+	if err := sm.AddBits(0, 10); err != nil {
+		log.Fatalln(err)
+	}
+
+	_, err := ctx.TcpListen(1)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	outChan <- struct{}{}
+	err = ctx.TcpAccept()
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	for {
+		req, err := ctx.TcpReceive()
+		if err != nil {
+			break
+		}
+		err = ctx.ReplySegmented(req, sm)
+		if err != nil {
+			break
+		}
+	}
+}
+
+func TestModbusSegmentedMapping_Add(t *testing.T) {
+	sm := ModbusSegmentedMappingNew()
+	if sm == nil {
+		t.FailNow()
+	}
+	defer sm.Free()
+	if err := sm.AddRegisters(1000, 50); err != nil {
+		t.Fatal(err)
+	}
+	var merr *Error
+	if err := sm.AddRegisters(1049, 2); !errors.As(err, &merr) || merr.Code() != ErrorCode(syscall.EINVAL) {
+		t.Errorf("overlapping block: %v", err)
+	}
+	if err := sm.AddRegisters(0, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.SetTabRegisters(1010, 0x1234); err != nil {
+		t.Error(err)
+	}
+	if _, err := sm.GetTabRegisters(500); err == nil {
+		t.Error("read from a hole succeeded")
+	}
+
+	// adjacent blocks are merged and keep their values
+	if err := sm.AddRegisters(1050, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.AddRegisters(990, 10); err != nil {
+		t.Fatal(err)
+	}
+	if segs := sm.segments[MODBUS_TABLE_REGISTERS]; len(segs) != 2 {
+		t.Errorf("%d blocks", len(segs))
+	}
+	if seg := sm.segment(MODBUS_TABLE_REGISTERS, 995, 60); seg == nil || seg.GetTabRegisters(1010) != 0x1234 {
+		t.Error("blocks not merged")
+	}
+	addrs := []int{}
+	for k := range sm.TabRegisters() {
+		addrs = append(addrs, k)
+	}
+	if len(addrs) != 170 || addrs[0] != 0 || addrs[100] != 990 {
+		t.Errorf("unexpected iteration order %v", addrs)
+	}
+}
+
+func TestModbus_ReplySegmented(t *testing.T) {
+	outChan := make(chan struct{})
+	go setupSegmented(outChan)
+	<-outChan
+	ctx := ModbusNewTcp("127.0.0.1", 1503)
+	if ctx == nil {
+		t.Error("ModbusNewTcp error")
+		t.FailNow()
+	}
+	defer ctx.Free()
+	defer ctx.Close()
+
+	err := ctx.Connect()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	err = ctx.WriteRegisters(1000, []uint16{1, 2, 3})
+	if err != nil {
+		t.Fatalf("ERROR modbus_write_registers (%s)", err)
+	}
+	out, err := ctx.ReadRegisters(999, 1)
+	var merr *Error
+	if !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+		t.Errorf("read from a hole: %v %v", out, err)
+	}
+	_, err = ctx.ReadRegisters(145, 10)
+	if !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+		t.Errorf("read spanning a hole: %v", err)
+	}
+	if _, err = ctx.ReadRegisters(95, 10); err != nil {
+		t.Errorf("read across adjacent blocks: %v", err)
+	}
+	_, err = ctx.ReadBits(0, 11)
+	if !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+		t.Errorf("read past the last block: %v", err)
+	}
+	out, err = ctx.WriteAndReadRegisters(10, []uint16{7, 8}, 1000, 3)
+	if err != nil {
+		t.Fatalf("ERROR modbus_write_and_read_registers (%s)", err)
+	}
+	if out[0] != 1 || out[1] != 2 || out[2] != 3 {
+		t.Errorf("ERROR modbus_write_and_read_registers READ %v", out)
+	}
+	out, err = ctx.ReadRegisters(10, 2)
+	if err != nil || out[0] != 7 || out[1] != 8 {
+		t.Errorf("ERROR modbus_write_and_read_registers WRITE %v %v", out, err)
+	}
+}
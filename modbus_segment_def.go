@@ -0,0 +1,10 @@
+package libmodbusgo
+
+// ModbusSegmentedMapping register map made of several blocks per data table
+//
+// Each block is backed by its own ModbusMapping so the regular libmodbus reply path is used, addresses between
+// blocks are holes answered with MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS.
+type ModbusSegmentedMapping struct {
+	segments map[ModbusTable][]*ModbusMapping // sorted by start address
+	empty    *ModbusMapping                   // used to reply to requests outside of any block
+}
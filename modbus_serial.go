@@ -0,0 +1,46 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// serialCheck validate the line settings given to a serial constructor, see modbus_new_rtu()
+func serialCheck(device string, baud int, parity byte, dataBits int, stopBits int) (err error) {
+	if device == "" || baud <= 0 || dataBits < 5 || dataBits > 8 || (stopBits != 1 && stopBits != 2) ||
+		(parity != 'N' && parity != 'E' && parity != 'O') {
+		return syscall.EINVAL
+	}
+	return
+}
+
+// serialOneByteTime time to transmit one character on the line, see modbus_new_rtu()
+func serialOneByteTime(baud int, parity byte, dataBits int, stopBits int) time.Duration {
+	bits := 1 + dataBits + stopBits
+	if parity != 'N' {
+		bits++
+	}
+	return time.Duration(bits) * time.Second / time.Duration(baud)
+}
+
+// serialSettingError error of a serial port setting refused by the driver, its code is the errno of the driver
+func serialSettingError(setting string, err error) error {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		errno = syscall.EIO
+	}
+	return &Error{
+		code:    ErrorCode(errno),
+		message: fmt.Sprintf("%s refused by the serial driver: %s", setting, errno.Error()),
+	}
+}
+
+// serialModeSetting name of the driver setting switched by modbus_rtu_set_serial_mode()
+func serialModeSetting(mode int) string {
+	if mode == MODBUS_RTU_RS485 {
+		return "RS-485 mode (TIOCSRS485)"
+	}
+	return "RS-232 mode (TIOCSRS485)"
+}
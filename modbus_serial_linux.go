@@ -0,0 +1,206 @@
+//go:build linux
+
+package libmodbusgo
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// serialOpen open a serial device in raw mode for the Go serial backends, see _modbus_rtu_connect()
+func serialOpen(device string, baud int, parity byte, dataBits int, stopBits int) (f *os.File, err error) {
+	fd, err := unix.Open(device, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			unix.Close(fd)
+		}
+	}()
+
+	tios, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+	if err != nil {
+		return
+	}
+	tios.Cflag &^= unix.CBAUD | unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS
+	tios.Cflag |= unix.BOTHER | unix.CREAD | unix.CLOCAL
+	tios.Ispeed = uint32(baud)
+	tios.Ospeed = uint32(baud)
+	switch dataBits {
+	case 5:
+		tios.Cflag |= unix.CS5
+	case 6:
+		tios.Cflag |= unix.CS6
+	case 7:
+		tios.Cflag |= unix.CS7
+	default:
+		tios.Cflag |= unix.CS8
+	}
+	if stopBits == 2 {
+		tios.Cflag |= unix.CSTOPB
+	}
+	tios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL |
+		unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	switch parity {
+	case 'E':
+		tios.Cflag |= unix.PARENB
+		tios.Iflag |= unix.INPCK
+	case 'O':
+		tios.Cflag |= unix.PARENB | unix.PARODD
+		tios.Iflag |= unix.INPCK
+	}
+	tios.Oflag &^= unix.OPOST
+	tios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	// with VMIN 0 an empty read returns 0 instead of EAGAIN and is seen as EOF by the poller
+	tios.Cc[unix.VMIN] = 1
+	tios.Cc[unix.VTIME] = 0
+	err = unix.IoctlSetTermios(fd, unix.TCSETS2, tios)
+	if err != nil {
+		return
+	}
+	return os.NewFile(uintptr(fd), device), nil
+}
+
+// serialRs485 struct serial_rs485 of linux/serial.h
+type serialRs485 struct {
+	flags              uint32
+	delayRtsBeforeSend uint32
+	delayRtsAfterSend  uint32
+	padding            [5]uint32
+}
+
+const (
+	serRs485Enabled      = 1 << 0 // SER_RS485_ENABLED
+	serRs485RtsOnSend    = 1 << 1 // SER_RS485_RTS_ON_SEND
+	serRs485RtsAfterSend = 1 << 2 // SER_RS485_RTS_AFTER_SEND
+	serRs485RxDuringTx   = 1 << 4 // SER_RS485_RX_DURING_TX
+)
+
+// serialStruct struct serial_struct of linux/serial.h
+type serialStruct struct {
+	typ           int32
+	line          int32
+	port          uint32
+	irq           int32
+	flags         int32
+	xmitFifoSize  int32
+	customDivisor int32
+	baudBase      int32
+	closeDelay    uint16
+	ioType        byte
+	reservedChar  [1]byte
+	hub6          int32
+	closingWait   uint16
+	closingWait2  uint16
+	iomemBase     uintptr
+	iomemRegShift uint16
+	portHigh      uint32
+	iomapBase     uintptr
+}
+
+const asyncLowLatency = 1 << 13 // ASYNC_LOW_LATENCY
+
+func serialGetRs485(fd int) (conf serialRs485, err error) {
+	err = serialIoctl(fd, unix.TIOCGRS485, unsafe.Pointer(&conf))
+	return
+}
+
+func serialSetRs485(fd int, conf serialRs485) (err error) {
+	return serialIoctl(fd, unix.TIOCSRS485, unsafe.Pointer(&conf))
+}
+
+// serialSetRs485Enabled switch the RS485 mode of the driver, see modbus_rtu_set_serial_mode()
+func serialSetRs485Enabled(fd int, enabled bool) (err error) {
+	conf, err := serialGetRs485(fd)
+	if err != nil {
+		return
+	}
+	if enabled {
+		conf.flags |= serRs485Enabled
+	} else {
+		conf.flags &^= serRs485Enabled
+	}
+	return serialSetRs485(fd, conf)
+}
+
+// serialSetRts set the RTS line, see _modbus_rtu_ioctl_rts()
+func serialSetRts(fd int, on bool) (err error) {
+	req := uint(unix.TIOCMBIC)
+	if on {
+		req = unix.TIOCMBIS
+	}
+	return unix.IoctlSetPointerInt(fd, req, unix.TIOCM_RTS)
+}
+
+func serialIoctl(fd int, req uint, arg unsafe.Pointer) (err error) {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg))
+	if errno != 0 {
+		err = errno
+	}
+	return
+}
+
+// serialApplyConfig apply the options of a RtuConfig to an open serial port, the error names the refused setting
+func serialApplyConfig(fd int, cfg *RtuConfig) (err error) {
+	if cfg.Exclusive {
+		if err = serialIoctl(fd, unix.TIOCEXCL, nil); err != nil {
+			return serialSettingError("exclusive access (TIOCEXCL)", err)
+		}
+		if err = unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB); err != nil {
+			return serialSettingError("exclusive lock (flock)", err)
+		}
+	}
+	if cfg.Baud != 0 {
+		setting := fmt.Sprintf("baud rate %d (TCSETS2)", cfg.Baud)
+		if cfg.Baud < 0 {
+			return serialSettingError(setting, unix.EINVAL)
+		}
+		tios, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+		if err != nil {
+			return serialSettingError(setting, err)
+		}
+		tios.Cflag &^= unix.CBAUD
+		tios.Cflag |= unix.BOTHER
+		tios.Ispeed = uint32(cfg.Baud)
+		tios.Ospeed = uint32(cfg.Baud)
+		if err = unix.IoctlSetTermios(fd, unix.TCSETS2, tios); err != nil {
+			return serialSettingError(setting, err)
+		}
+	}
+	if cfg.LowLatency {
+		var ss serialStruct
+		if err = serialIoctl(fd, unix.TIOCGSERIAL, unsafe.Pointer(&ss)); err == nil {
+			ss.flags |= asyncLowLatency
+			err = serialIoctl(fd, unix.TIOCSSERIAL, unsafe.Pointer(&ss))
+		}
+		if err != nil {
+			return serialSettingError("low latency (TIOCSSERIAL)", err)
+		}
+	}
+	if cfg.Rs485 != nil {
+		conf, err := serialGetRs485(fd)
+		if err == nil {
+			conf.flags = serRs485Enabled
+			if cfg.Rs485.RtsOnSend {
+				conf.flags |= serRs485RtsOnSend
+			}
+			if cfg.Rs485.RtsAfterSend {
+				conf.flags |= serRs485RtsAfterSend
+			}
+			if cfg.Rs485.RxDuringTx {
+				conf.flags |= serRs485RxDuringTx
+			}
+			conf.delayRtsBeforeSend = uint32(cfg.Rs485.DelayRtsBeforeSend.Milliseconds())
+			conf.delayRtsAfterSend = uint32(cfg.Rs485.DelayRtsAfterSend.Milliseconds())
+			err = serialSetRs485(fd, conf)
+		}
+		if err != nil {
+			return serialSettingError("RS-485 mode (TIOCSRS485)", err)
+		}
+	}
+	return
+}
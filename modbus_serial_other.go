@@ -0,0 +1,25 @@
+//go:build !linux
+
+package libmodbusgo
+
+import (
+	"os"
+	"syscall"
+)
+
+// serialOpen the Go serial backends are only available on Linux
+func serialOpen(device string, baud int, parity byte, dataBits int, stopBits int) (f *os.File, err error) {
+	return nil, syscall.ENOTSUP
+}
+
+func serialSetRs485Enabled(fd int, enabled bool) (err error) {
+	return syscall.ENOTSUP
+}
+
+func serialSetRts(fd int, on bool) (err error) {
+	return syscall.ENOTSUP
+}
+
+func serialApplyConfig(fd int, cfg *RtuConfig) (err error) {
+	return serialSettingError("serial configuration", syscall.ENOTSUP)
+}
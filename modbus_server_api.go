@@ -0,0 +1,163 @@
+package libmodbusgo
+
+import (
+	"errors"
+)
+
+// ModbusServerNew create a server dispatching requests to handler through the middleware chain
+//
+// Middlewares are called in order, the first one being the outermost.
+func ModbusServerNew(handler ModbusHandler, mw ...ModbusMiddleware) *ModbusServer {
+	return &ModbusServer{
+		Handler:    handler,
+		middleware: mw,
+	}
+}
+
+// Use append middlewares to the chain
+func (s *ModbusServer) Use(mw ...ModbusMiddleware) {
+	s.middleware = append(s.middleware, mw...)
+}
+
+// Reply serve a request received on the context
+//
+// The Reply() function shall decode the request, run it through the middleware chain and the handler. A
+// ModbusException returned by the chain is replied to the client and is not reported as an error.
+func (s *ModbusServer) Reply(x *Modbus, req []byte) (err error) {
+	r, err := x.DecodeRequest(req)
+	if err != nil {
+		return
+	}
+	h := s.Handler
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	err = h(x, r)
+	var exception ModbusException
+	if errors.As(err, &exception) {
+		// as modbus_reply(), never answer a broadcast on a serial line
+		if r.Slave == MODBUS_BROADCAST_ADDRESS && x.GetHeaderLength() == modbusRtuHeaderLength {
+			return nil
+		}
+		return x.ReplyException(req, uint(exception))
+	}
+	return
+}
+
+// ModbusMappingHandler serve requests from a mapping
+//
+// The handler replies with Reply() using the request encoded again, so rewrites made by middlewares apply. Invalid
+// quantities and addresses outside of the mapping are returned as exceptions so middlewares can observe them, and
+// ReadBits or ReadValues of read requests are filled from the mapping once replied.
+func ModbusMappingHandler(mm *ModbusMapping) ModbusHandler {
+	return func(x *Modbus, r *ModbusRequest) (err error) {
+		if r.Table != MODBUS_TABLE_NONE {
+			start, nb := mm.tableRange(r.Table)
+			if exception := r.check(func(addr int, n int) bool {
+				return addr >= start && addr+n <= start+nb
+			}); exception != 0 {
+				return exception
+			}
+		}
+		err = x.Reply(x.EncodeRequest(r), mm)
+		if err != nil {
+			return
+		}
+		r.readFrom(mm)
+		return
+	}
+}
+
+// ModbusSegmentedMappingHandler serve requests from a segmented mapping
+//
+// The handler behaves like the one returned by ModbusMappingHandler(), requests spanning a hole are returned as
+// MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS.
+func ModbusSegmentedMappingHandler(sm *ModbusSegmentedMapping) ModbusHandler {
+	return func(x *Modbus, r *ModbusRequest) (err error) {
+		if r.Table != MODBUS_TABLE_NONE {
+			if exception := r.check(func(addr int, n int) bool {
+				return sm.segment(r.Table, addr, n) != nil
+			}); exception != 0 {
+				return exception
+			}
+		}
+		err = x.ReplySegmented(x.EncodeRequest(r), sm)
+		if err != nil {
+			return
+		}
+		if seg := sm.segment(r.Table, r.Addr, r.Nb); seg != nil {
+			r.readFrom(seg)
+		}
+		return
+	}
+}
+
+// check validate the quantities of the request and its ranges with valid, it returns the exception libmodbus would
+// reply or 0
+func (r *ModbusRequest) check(valid func(addr int, nb int) bool) ModbusException {
+	maxNb, maxWriteNb := 0, 0
+	switch r.Function {
+	case MODBUS_FC_READ_COILS, MODBUS_FC_READ_DISCRETE_INPUTS:
+		maxNb = MODBUS_MAX_READ_BITS
+	case MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_READ_INPUT_REGISTERS:
+		maxNb = MODBUS_MAX_READ_REGISTERS
+	case MODBUS_FC_WRITE_MULTIPLE_COILS:
+		maxNb = MODBUS_MAX_WRITE_BITS
+		if len(r.Bits) < r.Nb {
+			return MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+		}
+	case MODBUS_FC_WRITE_MULTIPLE_REGISTERS:
+		maxNb = MODBUS_MAX_WRITE_REGISTERS
+		if len(r.Values) != r.Nb {
+			return MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+		}
+	case MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		maxNb, maxWriteNb = MODBUS_MAX_WR_READ_REGISTERS, MODBUS_MAX_WR_WRITE_REGISTERS
+		if r.WriteNb < 1 || r.WriteNb > maxWriteNb || len(r.Values) != r.WriteNb {
+			return MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+		}
+	default:
+		maxNb = 1
+	}
+	if r.Nb < 1 || r.Nb > maxNb {
+		return MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+	}
+	for _, rg := range r.ranges() {
+		if !valid(rg.addr, rg.nb) {
+			return MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS
+		}
+	}
+	// as libmodbus, the value of a single coil is checked after its address
+	if r.Function == MODBUS_FC_WRITE_SINGLE_COIL && len(r.Bits) != 1 {
+		return MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE
+	}
+	return 0
+}
+
+// readFrom fill the read results of the request from the mapping
+func (r *ModbusRequest) readFrom(mm *ModbusMapping) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	switch r.Function {
+	case MODBUS_FC_READ_COILS:
+		r.ReadBits = make([]byte, r.Nb)
+		for i := range r.ReadBits {
+			r.ReadBits[i] = mm.GetTabBits(r.Addr + i)
+		}
+	case MODBUS_FC_READ_DISCRETE_INPUTS:
+		r.ReadBits = make([]byte, r.Nb)
+		for i := range r.ReadBits {
+			r.ReadBits[i] = mm.GetTabInputBits(r.Addr + i)
+		}
+	case MODBUS_FC_READ_HOLDING_REGISTERS, MODBUS_FC_WRITE_AND_READ_REGISTERS:
+		r.ReadValues = make([]uint16, r.Nb)
+		for i := range r.ReadValues {
+			r.ReadValues[i] = mm.GetTabRegisters(r.Addr + i)
+		}
+	case MODBUS_FC_READ_INPUT_REGISTERS:
+		r.ReadValues = make([]uint16, r.Nb)
+		for i := range r.ReadValues {
+			r.ReadValues[i] = mm.GetTabInputRegisters(r.Addr + i)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+func setupServer(outChan chan struct{}, s *ModbusServer) {
+	ctx := ModbusNewTcp("127.0.0.1", 1504)
+	if ctx == nil {
+		log.Println("ModbusNewTcp error")
+		return
+	}
+	defer ctx.Free()
+	defer ctx.Close()
+
+	_, err := ctx.TcpListen(1)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+	outChan <- struct{}{}
+	err = ctx.TcpAccept()
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	for {
+		req, err := ctx.TcpReceive()
+		if err != nil {
+			break
+		}
+		err = s.Reply(ctx, req)
+		if err != nil {
+			break
+		}
+	}
+}
+
+func TestModbusServer_Reply(t *testing.T) {
+	mbMapping := ModbusMappingNew(100, 100, 100, 100)
+	if mbMapping == nil {
+		t.FailNow()
+	}
+	defer mbMapping.Free()
+
+	var seen []*ModbusRequest
+	record := func(next ModbusHandler) ModbusHandler {
+		return func(x *Modbus, r *ModbusRequest) error {
+			err := next(x, r)
+			seen = append(seen, r)
+			return err
+		}
+	}
+	clamp := func(next ModbusHandler) ModbusHandler {
+		return func(x *Modbus, r *ModbusRequest) error {
+			for i, v := range r.Values {
+				r.Values[i] = min(v, 1000)
+			}
+			return next(x, r)
+		}
+	}
+	busy := func(next ModbusHandler) ModbusHandler {
+		return func(x *Modbus, r *ModbusRequest) error {
+			if r.Table == MODBUS_TABLE_BITS {
+				return MODBUS_EXCEPTION_SLAVE_OR_SERVER_BUSY
+			}
+			return next(x, r)
+		}
+	}
+	s := ModbusServerNew(ModbusMappingHandler(mbMapping), record, clamp)
+	s.Use(busy)
+
+	outChan := make(chan struct{})
+	go setupServer(outChan, s)
+	<-outChan
+	ctx := ModbusNewTcp("127.0.0.1", 1504)
+	if ctx == nil {
+		t.Error("ModbusNewTcp error")
+		t.FailNow()
+	}
+	defer ctx.Free()
+	defer ctx.Close()
+
+	err := ctx.Connect()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	err = ctx.WriteRegisters(10, []uint16{5, 5000})
+	if err != nil {
+		t.Fatalf("ERROR modbus_write_registers (%s)", err)
+	}
+	out, err := ctx.ReadRegisters(10, 2)
+	if err != nil || out[0] != 5 || out[1] != 1000 {
+		t.Errorf("ERROR modbus_read_registers %v %v", out, err)
+	}
+	_, err = ctx.ReadBits(0, 1)
+	var merr *Error
+	if !errors.As(err, &merr) || merr.Code() != EMBXSBUSY {
+		t.Errorf("short-circuited request: %v", err)
+	}
+	_, err = ctx.ReadRegisters(99, 2)
+	if !errors.As(err, &merr) || merr.Code() != EMBXILADD {
+		t.Errorf("read past the mapping: %v", err)
+	}
+
+	if len(seen) != 4 {
+		t.Fatalf("middleware saw %d requests", len(seen))
+	}
+	if seen[1].Function != MODBUS_FC_READ_HOLDING_REGISTERS || len(seen[1].ReadValues) != 2 || seen[1].ReadValues[1] != 1000 {
+		t.Errorf("read request after the handler %+v", seen[1])
+	}
+}
+
+func TestModbusServer_WriteSingleCoil(t *testing.T) {
+	mm := ModbusMappingNew(10, 0, 0, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	s := ModbusServerNew(ModbusMappingHandler(mm))
+
+	c1, c2 := net.Pipe()
+	server := ModbusNewConn(c1, MODBUS_FRAMING_TCP)
+	if server == nil {
+		t.FailNow()
+	}
+	defer server.Free()
+	go func() {
+		for {
+			req, err := server.Receive()
+			if err != nil {
+				return
+			}
+			s.Reply(server, req)
+		}
+	}()
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+
+	hdr := ctx.GetHeaderLength()
+	for _, c := range []struct {
+		addr, value int
+		exception   byte
+		coil        byte
+	}{
+		{1, 0xFF00, 0, 1},
+		{1, 0x1234, byte(MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE), 1},
+		{1, 0x0001, byte(MODBUS_EXCEPTION_ILLEGAL_DATA_VALUE), 1},
+		{1, 0x0000, 0, 0},
+		{20, 0x1234, byte(MODBUS_EXCEPTION_ILLEGAL_DATA_ADDRESS), 0},
+	} {
+		err := ctx.SendRawRequest([]byte{MODBUS_TCP_SLAVE, MODBUS_FC_WRITE_SINGLE_COIL,
+			byte(c.addr >> 8), byte(c.addr), byte(c.value >> 8), byte(c.value)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp, err := ctx.ReceiveConfirmation()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.exception == 0 && rsp[hdr] != MODBUS_FC_WRITE_SINGLE_COIL ||
+			c.exception != 0 && (rsp[hdr] != MODBUS_FC_WRITE_SINGLE_COIL|0x80 || rsp[hdr+1] != c.exception) {
+			t.Errorf("%d %04X: % X", c.addr, c.value, rsp)
+		}
+		if mm.GetTabBits(1) != c.coil {
+			t.Errorf("%d %04X: coil %d", c.addr, c.value, mm.GetTabBits(1))
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package libmodbusgo
+
+// ModbusHandler serve a decoded request
+//
+// A handler replies to the request itself, usually through Reply(), or returns a ModbusException that the server
+// replies on its behalf.
+type ModbusHandler func(x *Modbus, r *ModbusRequest) error
+
+// ModbusMiddleware wrap a handler
+//
+// A middleware sees the decoded request before calling next and the served request after it returns. It may rewrite
+// the request, which is encoded again before reaching the mapping, or short-circuit the chain by returning a
+// ModbusException without calling next.
+type ModbusMiddleware func(next ModbusHandler) ModbusHandler
+
+// ModbusServer dispatch of the requests received by a server through a middleware chain
+type ModbusServer struct {
+	Handler    ModbusHandler
+	middleware []ModbusMiddleware
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package libmodbusgo
+
+import (
+	"net/netip"
+	"syscall"
+)
+
+// socketPeerAddr the peer of a descriptor is only known on Unix, the Go backends use their connection instead
+func socketPeerAddr(s int) (addr netip.Addr, err error) {
+	return addr, syscall.ENOTSUP
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package libmodbusgo
+
+import (
+	"net/netip"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketPeerAddr address of the peer of the socket s
+func socketPeerAddr(s int) (addr netip.Addr, err error) {
+	sa, err := unix.Getpeername(s)
+	if err != nil {
+		return
+	}
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		addr = netip.AddrFrom4(sa.Addr)
+	case *unix.SockaddrInet6:
+		addr = netip.AddrFrom16(sa.Addr)
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package libmodbusgo
+
+import "strings"
+
+// DecodeString decode the ASCII characters packed two per register
+//
+// The first character of a register is its high byte, or its low byte when byteSwap is set. With trimNull the string
+// ends at the first NUL character, the padding of the fixed size strings of the devices.
+func DecodeString(regs []uint16, byteSwap bool, trimNull bool) string {
+	b := make([]byte, 0, 2*len(regs))
+	for _, r := range regs {
+		if byteSwap {
+			r = r>>8 | r<<8
+		}
+		b = append(b, byte(r>>8), byte(r))
+	}
+	s := string(b)
+	if trimNull {
+		s, _, _ = strings.Cut(s, "\x00")
+	}
+	return s
+}
+
+// EncodeString encode a string packed two characters per register into nb registers, see DecodeString()
+//
+// The registers after the string are padded with NUL characters, a string longer than 2*nb characters returns
+// EINVAL. A nb of 0 uses the registers needed by the string.
+func EncodeString(s string, nb int, byteSwap bool) (regs []uint16, err error) {
+	if nb == 0 {
+		nb = (len(s) + 1) / 2
+	}
+	if len(s) > 2*nb {
+		return nil, marshalError("string of %d characters longer than %d registers", len(s), nb)
+	}
+	regs = make([]uint16, nb)
+	for i := 0; i < len(s); i++ {
+		if i%2 == 0 {
+			regs[i/2] = uint16(s[i]) << 8
+		} else {
+			regs[i/2] |= uint16(s[i])
+		}
+	}
+	if byteSwap {
+		for i, r := range regs {
+			regs[i] = r>>8 | r<<8
+		}
+	}
+	return
+}
+
+// DecodeBCD decode the binary coded decimal digits packed four per register, the most significant first
+//
+// A nibble above 9 returns EINVAL with the register and the digit in error, at most 19 digits (5 registers) fit the
+// result.
+func DecodeBCD(regs []uint16) (v uint64, err error) {
+	if len(regs) > 5 || len(regs) == 5 && regs[0] > 0x0FFF {
+		return 0, marshalError("%d BCD registers overflow 64 bits", len(regs))
+	}
+	for i, r := range regs {
+		for shift := 12; shift >= 0; shift -= 4 {
+			d := uint64(r>>shift) & 0xF
+			if d > 9 {
+				return 0, marshalError("invalid BCD digit %X in register %d (0x%04X)", d, i, r)
+			}
+			v = 10*v + d
+		}
+	}
+	return
+}
+
+// EncodeBCD encode a number in binary coded decimal over nb registers, see DecodeBCD()
+//
+// A number with more than 4*nb digits returns EINVAL.
+func EncodeBCD(v uint64, nb int) (regs []uint16, err error) {
+	regs = make([]uint16, nb)
+	rest := v
+	for i := 4*nb - 1; i >= 0 && rest > 0; i-- {
+		regs[i/4] |= uint16(rest%10) << (4 * (3 - i%4))
+		rest /= 10
+	}
+	if rest > 0 {
+		return nil, marshalError("%d does not fit %d BCD digits", v, 4*nb)
+	}
+	return
+}
+
+// modbusFromBcd value of the BCD digits of a raw value of n registers
+func modbusFromBcd(raw uint64, n int) (v uint64, err error) {
+	digits := make([]uint16, n)
+	for i := range digits {
+		digits[i] = uint16(raw >> (16 * (n - 1 - i)))
+	}
+	return DecodeBCD(digits)
+}
+
+// modbusToBcd BCD digits of a value as a raw value, the value must fit the raw type
+func modbusToBcd(v uint64) (raw uint64) {
+	for shift := 0; v > 0; shift += 4 {
+		raw |= (v % 10) << shift
+		v /= 10
+	}
+	return
+}
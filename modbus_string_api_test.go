@@ -0,0 +1,99 @@
+package libmodbusgo
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodeString(t *testing.T) {
+	regs := []uint16{0x5345, 0x5231, 0x3233, 0x0000}
+	if s := DecodeString(regs, false, true); s != "SER123" {
+		t.Errorf("%q", s)
+	}
+	if s := DecodeString(regs, false, false); s != "SER123\x00\x00" {
+		t.Errorf("%q", s)
+	}
+	if s := DecodeString(regs, true, true); s != "ES1R32" {
+		t.Errorf("%q", s)
+	}
+
+	r, err := EncodeString("SER123", 4, false)
+	if err != nil || !slices.Equal(r, regs) {
+		t.Errorf("% X %v", r, err)
+	}
+	if r, err = EncodeString("V1.2.3", 0, true); err != nil || !slices.Equal(r, []uint16{0x3156, 0x322E, 0x332E}) {
+		t.Errorf("% X %v", r, err)
+	}
+	if r, _ = EncodeString("odd", 0, false); !slices.Equal(r, []uint16{0x6F64, 0x6400}) {
+		t.Errorf("% X", r)
+	}
+	if _, err = EncodeString("too long", 3, false); err == nil {
+		t.Error("too long")
+	}
+}
+
+func TestDecodeBCD(t *testing.T) {
+	v, err := DecodeBCD([]uint16{0x0012, 0x3456})
+	if err != nil || v != 123456 {
+		t.Errorf("%d %v", v, err)
+	}
+	if _, err = DecodeBCD([]uint16{0x0012, 0x34A6}); err == nil || !strings.Contains(err.Error(), "digit A in register 1") {
+		t.Errorf("%v", err)
+	}
+	if v, err = DecodeBCD([]uint16{0x0999, 0x9999, 0x9999, 0x9999, 0x9999}); err != nil || v != 9999999999999999999 {
+		t.Errorf("%d %v", v, err)
+	}
+	if _, err = DecodeBCD([]uint16{0x1999, 0x9999, 0x9999, 0x9999, 0x9999}); err == nil {
+		t.Error("overflow")
+	}
+
+	r, err := EncodeBCD(123456, 2)
+	if err != nil || !slices.Equal(r, []uint16{0x0012, 0x3456}) {
+		t.Errorf("% X %v", r, err)
+	}
+	if _, err = EncodeBCD(12345, 1); err == nil {
+		t.Error("too many digits")
+	}
+}
+
+func TestMarshal_String(t *testing.T) {
+	type nameplate struct {
+		Serial   string  `modbus:"len=4"`
+		Firmware string  `modbus:"len=3,order=badc"`
+		Hours    uint32  `modbus:"type=bcd32"`
+		Counter  uint16  `modbus:"type=bcd16"`
+		Energy   float64 `modbus:"type=bcd32,order=cdab,scale=0.1"`
+	}
+	v := nameplate{Serial: "SER123", Firmware: "V1.2.3", Hours: 87654321, Counter: 42, Energy: 1234.5}
+	regs, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{0x5345, 0x5231, 0x3233, 0, 0x3156, 0x322E, 0x332E, 0x8765, 0x4321, 0x0042, 0x2345, 0x0001}
+	if !slices.Equal(regs, want) {
+		t.Fatalf("% X", regs)
+	}
+	var w nameplate
+	if err = Unmarshal(regs, &w); err != nil || w != v {
+		t.Errorf("%+v %v", w, err)
+	}
+
+	regs[9] = 0x004F
+	if err = Unmarshal(regs, &w); err == nil || !strings.Contains(err.Error(), "field Counter: invalid BCD digit F") {
+		t.Errorf("%v", err)
+	}
+	v.Counter = 10000
+	if _, err = Marshal(&v); err == nil || !strings.Contains(err.Error(), "overflows bcd16") {
+		t.Errorf("%v", err)
+	}
+	v.Counter = 0
+	v.Serial = "SERIAL123"
+	if _, err = Marshal(&v); err == nil || !strings.Contains(err.Error(), "field Serial") {
+		t.Errorf("%v", err)
+	}
+	var noLen struct{ S string }
+	if _, err = Marshal(noLen); err == nil || !strings.Contains(err.Error(), "needs len") {
+		t.Errorf("%v", err)
+	}
+}
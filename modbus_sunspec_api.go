@@ -0,0 +1,452 @@
+package libmodbusgo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"syscall"
+)
+
+const (
+	sunspecMarkerSu = 0x5375 // "Su"
+	sunspecMarkerNs = 0x6E53 // "nS"
+	sunspecEnd      = 0xFFFF // ID of the end of the model chain
+)
+
+// sunspecBases addresses of the SunS marker, in the order they are searched
+var sunspecBases = []int{40000, 50000, 0}
+
+// sunspecSlot point of a model at its offset, sf being the offset of its scale factor or -1
+type sunspecSlot struct {
+	sunspecPoint
+	name   string
+	offset int
+	size   int
+	sf     int
+}
+
+// sunspecSize number of registers of a SunSpec type
+func sunspecSize(typ string) int {
+	switch typ {
+	case "int32", "uint32", "acc32", "enum32", "bitfield32", "float32", "ipaddr":
+		return 2
+	case "int64", "uint64", "acc64":
+		return 4
+	}
+	var n int
+	if _, err := fmt.Sscanf(typ, "string%d", &n); err == nil {
+		return n
+	}
+	return 1
+}
+
+// sunspecRaw raw type of the numeric SunSpec types
+func sunspecRaw(typ string) modbusRawType {
+	switch typ {
+	case "int16", "sunssf", "pad":
+		return modbusRawTypes["int16"]
+	case "int32":
+		return modbusRawTypes["int32"]
+	case "int64":
+		return modbusRawTypes["int64"]
+	case "float32":
+		return modbusRawTypes["float32"]
+	}
+	switch sunspecSize(typ) {
+	case 2:
+		return modbusRawTypes["uint32"]
+	case 4:
+		return modbusRawTypes["uint64"]
+	}
+	return modbusRawTypes["uint16"]
+}
+
+// sunspecUnimplemented raw value of a type telling the point is not implemented
+func sunspecUnimplemented(typ string) uint64 {
+	switch typ {
+	case "int16", "sunssf", "pad":
+		return 0x8000
+	case "acc16", "acc32", "acc64", "ipaddr":
+		return 0
+	case "int32":
+		return 0x80000000
+	case "int64":
+		return 1 << 63
+	case "float32":
+		return 0x7FC00000
+	}
+	return 1<<(16*sunspecSize(typ)) - 1
+}
+
+// layout points of a model of registers regs, the repeating blocks filling the registers after the fixed block
+//
+// The points of the fixed block beyond the length of the model are not implemented, eg. the pad of a common model
+// of 65 registers. The number of nested blocks of each repeating block is read from regs.
+func (def *sunspecModelDef) layout(regs []uint16) (slots []sunspecSlot) {
+	length := len(regs)
+	fixed := map[string]int{}
+	off := 0
+	for _, p := range def.fixed {
+		size := sunspecSize(p.typ)
+		if off+size <= length {
+			slots = append(slots, sunspecSlot{sunspecPoint: p, name: p.name, offset: off, size: size})
+			fixed[p.name] = off
+		}
+		off += size
+	}
+	nested := 0
+	if i, ok := fixed[def.nestedCount]; ok {
+		nested = int(regs[i])
+	}
+	block := def.block(nested)
+	first := len(slots)
+	for i := 0; block > 0 && off+block <= length; i++ {
+		start := len(slots)
+		local := map[string]int{}
+		add := func(p sunspecPoint, name string) {
+			size := sunspecSize(p.typ)
+			slots = append(slots, sunspecSlot{sunspecPoint: p, name: name, offset: off, size: size})
+			local[p.name] = off
+			off += size
+		}
+		for _, p := range def.repeat {
+			add(p, fmt.Sprintf("%s%d.%s", def.group, i+1, p.name))
+		}
+		for k := 0; k < nested; k++ {
+			for _, p := range def.nested {
+				add(p, fmt.Sprintf("%s%d.%s%d.%s", def.group, i+1, def.nestedGroup, k+1, p.name))
+			}
+		}
+		for j := start; j < len(slots); j++ {
+			slots[j].sf = -1
+			if sf, ok := local[slots[j].sunspecPoint.sf]; ok {
+				slots[j].sf = sf
+			} else if sf, ok := fixed[slots[j].sunspecPoint.sf]; ok {
+				slots[j].sf = sf
+			}
+		}
+	}
+	for j := range slots[:first] {
+		slots[j].sf = -1
+		if sf, ok := fixed[slots[j].sunspecPoint.sf]; ok {
+			slots[j].sf = sf
+		}
+	}
+	return
+}
+
+// block number of registers of a repeating block holding nested blocks
+func (def *sunspecModelDef) block(nested int) (block int) {
+	for _, p := range def.repeat {
+		block += sunspecSize(p.typ)
+	}
+	for _, p := range def.nested {
+		block += nested * sunspecSize(p.typ)
+	}
+	return
+}
+
+// sunspecLength number of registers of the fixed block of a definition and of one repeating block of the models
+// created by SunspecModelNew()
+func (def *sunspecModelDef) length() (fixed int, block int) {
+	for _, p := range def.fixed {
+		fixed += sunspecSize(p.typ)
+	}
+	return fixed, def.block(def.nestedNew)
+}
+
+// sunspecRead read registers with as many requests as needed
+func sunspecRead(x *Modbus, addr int, nb int) (regs []uint16, err error) {
+	for nb > 0 {
+		n := min(nb, MODBUS_MAX_READ_REGISTERS)
+		r, err := x.ReadRegisters(addr, n)
+		if err != nil {
+			return nil, err
+		}
+		regs = append(regs, r...)
+		addr += n
+		nb -= n
+	}
+	return
+}
+
+// SunspecDiscover find the SunS marker of a device at 40000, 50000 or 0 and the chain of models following it
+//
+// Only the IDs and lengths of the models are read, see Read(). A device without marker returns ENOENT, an exception
+// probing a base moves to the next one while the other errors are returned. An illegal data address exception
+// reading the header of a model ends the chain as well as the end model.
+func SunspecDiscover(x *Modbus) (d *SunspecDevice, err error) {
+	d = &SunspecDevice{x: x, Base: -1}
+	for _, base := range sunspecBases {
+		regs, err := x.ReadRegisters(base, 2)
+		var merr *Error
+		if errors.As(err, &merr) && merr.Code() >= EMBXILFUN && merr.Code() <= EMBXGTAR {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if regs[0] == sunspecMarkerSu && regs[1] == sunspecMarkerNs {
+			d.Base = base
+			break
+		}
+	}
+	if d.Base < 0 {
+		return nil, &Error{code: ErrorCode(syscall.ENOENT), message: "SunSpec marker not found"}
+	}
+	for addr := d.Base + 2; addr+2 <= 0x10000; {
+		hdr, err := x.ReadRegisters(addr, 2)
+		var merr *Error
+		if errors.As(err, &merr) && merr.Code() == EMBXILADD && len(d.Models) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr[0] == sunspecEnd {
+			break
+		}
+		m := &SunspecModel{ID: int(hdr[0]), Addr: addr + 2, Regs: make([]uint16, hdr[1]), x: x,
+			def: sunspecModels[int(hdr[0])]}
+		if m.def != nil {
+			m.Name = m.def.name
+		}
+		d.Models = append(d.Models, m)
+		addr = m.Addr + len(m.Regs)
+	}
+	return
+}
+
+// Model first model of an ID, nil if the device has none
+func (d *SunspecDevice) Model(id int) *SunspecModel {
+	for _, m := range d.Models {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// Read read the registers of all the models
+func (d *SunspecDevice) Read() (err error) {
+	for _, m := range d.Models {
+		if err = m.Read(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// SunspecModelNew create a model of a known ID for a simulator, see SunspecImage()
+//
+// length 0 is the length of the fixed block of the definition, the models with repeating blocks have as many blocks
+// as fit in length, each with 4 nested blocks, eg. the points of the curves of the volt-var model. The registers hold
+// the unimplemented values of their types but the scale factors, 0, and the counts of the repeating and nested blocks.
+func SunspecModelNew(id int, length int) (m *SunspecModel, err error) {
+	def := sunspecModels[id]
+	if def == nil {
+		return nil, marshalError("SunSpec model %d: unknown", id)
+	}
+	if length == 0 {
+		length, _ = def.length()
+	}
+	m = &SunspecModel{ID: id, Name: def.name, Regs: make([]uint16, length), def: def}
+	fixed, block := def.length()
+	// the repeating blocks are laid out with the count of their nested blocks
+	for _, s := range def.layout(m.Regs) {
+		if s.name == def.nestedCount {
+			m.Regs[s.offset] = uint16(def.nestedNew)
+		}
+	}
+	slots := def.layout(m.Regs)
+	for i := range slots {
+		s := &slots[i]
+		raw := sunspecUnimplemented(s.typ)
+		switch {
+		case strings.HasPrefix(s.typ, "string"), s.typ == "sunssf":
+			raw = 0
+		case s.typ == "count":
+			raw = uint64((length - fixed) / max(block, 1))
+		case s.name == def.nestedCount:
+			raw = uint64(def.nestedNew)
+		}
+		if !strings.HasPrefix(s.typ, "string") {
+			p, _ := MODBUS_ORDER_ABCD.permutation(2 * s.size)
+			modbusSetRaw(m.Regs[s.offset:s.offset+s.size], p, raw)
+		}
+	}
+	return
+}
+
+// SunspecImage registers of a SunSpec device from its marker to its end model for a simulator, the Addr of the models
+// being set from base, the address of the marker
+func SunspecImage(base int, models ...*SunspecModel) (regs []uint16) {
+	regs = []uint16{sunspecMarkerSu, sunspecMarkerNs}
+	for _, m := range models {
+		m.Addr = base + len(regs) + 2
+		regs = append(regs, uint16(m.ID), uint16(len(m.Regs)))
+		regs = append(regs, m.Regs...)
+	}
+	return append(regs, sunspecEnd, 0)
+}
+
+// Read read the registers of the model
+func (m *SunspecModel) Read() (err error) {
+	if m.x == nil {
+		return marshalError("SunSpec model %d: no client", m.ID)
+	}
+	regs, err := sunspecRead(m.x, m.Addr, len(m.Regs))
+	if err != nil {
+		return
+	}
+	copy(m.Regs, regs)
+	return
+}
+
+func (m *SunspecModel) slots() (slots []sunspecSlot, err error) {
+	if m.def == nil {
+		return nil, marshalError("SunSpec model %d: unknown", m.ID)
+	}
+	return m.def.layout(m.Regs), nil
+}
+
+func (m *SunspecModel) slot(name string) (s *sunspecSlot, err error) {
+	slots, err := m.slots()
+	if err != nil {
+		return
+	}
+	for i := range slots {
+		if slots[i].name == name {
+			return &slots[i], nil
+		}
+	}
+	return nil, marshalError("SunSpec model %d: unknown point %s", m.ID, name)
+}
+
+func (m *SunspecModel) value(s *sunspecSlot) (v SunspecValue) {
+	v = SunspecValue{Name: s.name, Type: s.typ, Units: s.units, Writable: s.rw}
+	regs := m.Regs[s.offset : s.offset+s.size]
+	if strings.HasPrefix(s.typ, "string") {
+		v.Text = DecodeString(regs, false, true)
+		v.Implemented = v.Text != ""
+		return
+	}
+	p, _ := MODBUS_ORDER_ABCD.permutation(2 * s.size)
+	raw := modbusGetRaw(regs, p)
+	if s.typ == "pad" || raw == sunspecUnimplemented(s.typ) || (s.typ == "float32" && math.IsNaN(float64(
+		math.Float32frombits(uint32(raw))))) {
+		return
+	}
+	v.Value, _ = modbusRawFloat(sunspecRaw(s.typ), p, regs)
+	if s.sf >= 0 {
+		if m.Regs[s.sf] == 0x8000 {
+			v.Value = 0
+			return
+		}
+		v.Value *= math.Pow10(int(int16(m.Regs[s.sf])))
+	}
+	v.Implemented = true
+	return
+}
+
+// Values values of the points of the model read by Read(), in the order of the model
+//
+// A model without definition returns EINVAL, its registers remain available in Regs.
+func (m *SunspecModel) Values() (values []SunspecValue, err error) {
+	slots, err := m.slots()
+	if err != nil {
+		return
+	}
+	values = make([]SunspecValue, len(slots))
+	for i := range slots {
+		values[i] = m.value(&slots[i])
+	}
+	return
+}
+
+// Value value of a point of the model read by Read()
+func (m *SunspecModel) Value(name string) (v SunspecValue, err error) {
+	s, err := m.slot(name)
+	if err != nil {
+		return
+	}
+	return m.value(s), nil
+}
+
+// Set store the value of a point in the registers of the model, a number or a string for the string points
+//
+// The numbers are scaled by the scale factor of the registers, rounded and range checked. The values of the
+// unimplemented sentinel of the type and unimplemented scale factors return EINVAL.
+func (m *SunspecModel) Set(name string, value any) (err error) {
+	s, err := m.slot(name)
+	if err != nil {
+		return
+	}
+	regs := m.Regs[s.offset : s.offset+s.size]
+	if strings.HasPrefix(s.typ, "string") {
+		text, ok := value.(string)
+		if !ok {
+			return marshalError("SunSpec point %s: %T is not a string", name, value)
+		}
+		r, err := EncodeString(text, s.size, false)
+		if err != nil {
+			return err
+		}
+		copy(regs, r)
+		return nil
+	}
+	var x float64
+	switch value := value.(type) {
+	case float64:
+		x = value
+	case int:
+		x = float64(value)
+	default:
+		return marshalError("SunSpec point %s: %T is not a number", name, value)
+	}
+	if s.sf >= 0 {
+		if m.Regs[s.sf] == 0x8000 {
+			return marshalError("SunSpec point %s: scale factor not implemented", name)
+		}
+		x /= math.Pow10(int(int16(m.Regs[s.sf])))
+	}
+	rt := sunspecRaw(s.typ)
+	raw, err := modbusFloatRaw(rt, x)
+	if err != nil {
+		return marshalError("SunSpec point %s: %v %s", name, value, err.(*Error).message)
+	}
+	if raw == sunspecUnimplemented(s.typ) && s.typ != "pad" {
+		return marshalError("SunSpec point %s: %v is the unimplemented value", name, value)
+	}
+	p, _ := MODBUS_ORDER_ABCD.permutation(2 * s.size)
+	modbusSetRaw(regs, p, raw)
+	return
+}
+
+// Write write the value of a writable point to the device, see Set()
+//
+// The scale factor of the point is read from the device first.
+func (m *SunspecModel) Write(name string, value float64) (err error) {
+	s, err := m.slot(name)
+	if err != nil {
+		return
+	}
+	if !s.rw {
+		return marshalError("SunSpec point %s: read only", name)
+	}
+	if m.x == nil {
+		return marshalError("SunSpec model %d: no client", m.ID)
+	}
+	if s.sf >= 0 {
+		sf, err := m.x.ReadRegisters(m.Addr+s.sf, 1)
+		if err != nil {
+			return err
+		}
+		m.Regs[s.sf] = sf[0]
+	}
+	if err = m.Set(name, value); err != nil {
+		return
+	}
+	return m.x.WriteRegisters(m.Addr+s.offset, m.Regs[s.offset:s.offset+s.size])
+}
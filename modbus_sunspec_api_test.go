@@ -0,0 +1,272 @@
+package libmodbusgo
+
+import (
+	"io"
+	"math"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSunspecModels(t *testing.T) {
+	for id, length := range map[int]int{1: 66, 101: 50, 103: 50, 111: 60, 120: 26, 121: 30, 122: 44, 123: 24, 124: 24,
+		160: 8, 201: 105, 204: 105, 701: 153, 702: 50, 703: 17, 704: 65, 705: 13} {
+		if fixed, _ := sunspecModels[id].length(); fixed != length {
+			t.Errorf("model %d: %d registers", id, fixed)
+		}
+	}
+	if _, block := sunspecMppt.length(); block != 20 {
+		t.Errorf("mppt module: %d registers", block)
+	}
+	if _, block := sunspecDerVoltVar.length(); block != 18 {
+		t.Errorf("volt-var curve: %d registers", block)
+	}
+	if _, err := SunspecModelNew(999, 4); err == nil {
+		t.Error("unknown model")
+	}
+}
+
+// sunspecClient serve a SunSpec image at base
+func sunspecClient(t *testing.T, base int, regs []uint16) (*Modbus, *ModbusMapping) {
+	mm := ModbusMappingNewStartAddress(0, 0, 0, 0, uint(base), uint(len(regs)), 0, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	t.Cleanup(mm.Free)
+	if err := mm.LoadRegisterBlock(MODBUS_TABLE_REGISTERS, RegisterBlock{Start: base, Values: regs}); err != nil {
+		t.Fatal(err)
+	}
+	c1, c2 := net.Pipe()
+	server := conformanceServe(ModbusNewConn(c1, MODBUS_FRAMING_TCP), MODBUS_TCP_SLAVE, mm)
+	t.Cleanup(func() { server.close() })
+	ctx := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if ctx == nil {
+		t.FailNow()
+	}
+	t.Cleanup(ctx.Free)
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+	return ctx, mm
+}
+
+func sunspecSet(t *testing.T, m *SunspecModel, values map[string]any) {
+	for name, v := range values {
+		if err := m.Set(name, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSunspecDiscover(t *testing.T) {
+	common, _ := SunspecModelNew(1, 0)
+	sunspecSet(t, common, map[string]any{"Mn": "Acme", "Md": "Inverter 5K", "SN": "SN-0001", "DA": 1})
+	inverter, _ := SunspecModelNew(103, 0)
+	sunspecSet(t, inverter, map[string]any{"A_SF": -1, "V_SF": -1, "W_SF": 1, "Hz_SF": -2, "WH_SF": 0})
+	sunspecSet(t, inverter, map[string]any{"A": 12.3, "PhVphA": 230.4, "W": -1500, "Hz": 49.98, "WH": 1234567, "St": 4})
+	controls, _ := SunspecModelNew(123, 0)
+	sunspecSet(t, controls, map[string]any{"WMaxLimPct_SF": -1})
+	sunspecSet(t, controls, map[string]any{"WMaxLimPct": 100})
+	mppt, _ := SunspecModelNew(160, 48)
+	sunspecSet(t, mppt, map[string]any{"DCA_SF": -2})
+	sunspecSet(t, mppt, map[string]any{"module1.ID": 1, "module1.IDStr": "String A", "module1.DCA": 8.25,
+		"module2.ID": 2, "module2.DCA": 7.5})
+	unknown := &SunspecModel{ID: 64000, Regs: []uint16{1, 2, 3}}
+	img := SunspecImage(40000, common, inverter, controls, mppt, unknown)
+	ctx, mm := sunspecClient(t, 40000, img)
+
+	d, err := SunspecDiscover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Base != 40000 || len(d.Models) != 5 {
+		t.Fatalf("%d %d models", d.Base, len(d.Models))
+	}
+	for i, id := range []int{1, 103, 123, 160, 64000} {
+		if d.Models[i].ID != id || d.Models[i].Addr != [5]*SunspecModel{common, inverter, controls, mppt, unknown}[i].Addr {
+			t.Errorf("model %d: %+v", i, d.Models[i])
+		}
+	}
+	if d.Model(103).Addr != 40072 || d.Model(124) != nil {
+		t.Error(d.Model(103).Addr)
+	}
+	if err = d.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		id          int
+		name        string
+		value       float64
+		text        string
+		implemented bool
+	}{
+		{1, "Mn", 0, "Acme", true},
+		{1, "SN", 0, "SN-0001", true},
+		{1, "Opt", 0, "", false},
+		{1, "DA", 1, "", true},
+		{103, "A", 12.3, "", true},
+		{103, "PhVphA", 230.4, "", true},
+		{103, "W", -1500, "", true},
+		{103, "Hz", 49.98, "", true},
+		{103, "WH", 1234567, "", true},
+		{103, "St", 4, "", true},
+		{103, "AphB", 0, "", false},
+		{103, "TmpCab", 0, "", false},
+		{103, "VA", 0, "", false},
+		{103, "Evt1", 0, "", false},
+		{160, "N", 2, "", true},
+		{160, "module1.IDStr", 0, "String A", true},
+		{160, "module1.DCA", 8.25, "", true},
+		{160, "module2.DCA", 7.5, "", true},
+		{160, "module2.DCV", 0, "", false},
+	} {
+		v, err := d.Model(c.id).Value(c.name)
+		if err != nil || v.Implemented != c.implemented || math.Abs(v.Value-c.value) > 1e-9 || v.Text != c.text {
+			t.Errorf("%d %s: %+v %v", c.id, c.name, v, err)
+		}
+	}
+	values, err := d.Model(123).Values()
+	if err != nil || len(values) != 24 || values[3].Name != "WMaxLimPct" || values[3].Value != 100 || !values[3].Writable {
+		t.Errorf("%+v %v", values, err)
+	}
+	if _, err = d.Model(64000).Values(); conformanceCode(err) != ErrorCode(syscall.EINVAL) {
+		t.Error("unknown model")
+	}
+	if d.Model(64000).Regs[2] != 3 {
+		t.Error(d.Model(64000).Regs)
+	}
+
+	// controls are written with the scale factor of the device
+	mm.SetTabRegisters(controls.Addr+21, 0xFFFE)
+	if err = d.Model(123).Write("WMaxLimPct", 55.5); err != nil {
+		t.Fatal(err)
+	}
+	if r := mm.GetTabRegisters(controls.Addr + 3); r != 5550 {
+		t.Error(r)
+	}
+	if err = d.Model(123).Write("WMaxLimPct", 1000); err == nil {
+		t.Error("overflow")
+	}
+	if err = d.Model(103).Write("W", 10); err == nil {
+		t.Error("read only")
+	}
+	if err = d.Model(123).Write("Unknown", 1); err == nil {
+		t.Error("unknown point")
+	}
+	if err = d.Model(1).Set("DA", 0xFFFF); err == nil {
+		t.Error("unimplemented value")
+	}
+}
+
+func TestSunspecDer(t *testing.T) {
+	capacity, _ := SunspecModelNew(702, 0)
+	sunspecSet(t, capacity, map[string]any{"W_SF": 1, "V_SF": -1})
+	sunspecSet(t, capacity, map[string]any{"WMaxRtg": 5000, "VNomRtg": 230, "WMax": 4600})
+	service, _ := SunspecModelNew(703, 0)
+	controls, _ := SunspecModelNew(704, 0)
+	sunspecSet(t, controls, map[string]any{"PF_SF": -3, "WMaxLimPct_SF": -1})
+	voltVar, _ := SunspecModelNew(705, 13+2*18)
+	sunspecSet(t, voltVar, map[string]any{"V_SF": -1})
+	img := SunspecImage(40000, capacity, service, controls, voltVar)
+	ctx, mm := sunspecClient(t, 40000, img)
+
+	d, err := SunspecDiscover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Read(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		id       int
+		name     string
+		value    float64
+		writable bool
+	}{
+		{702, "WMaxRtg", 5000, false},
+		{702, "VNomRtg", 230, false},
+		{702, "WMax", 4600, true},
+		{705, "NPt", 4, false},
+		{705, "NCrv", 2, false},
+	} {
+		v, err := d.Model(c.id).Value(c.name)
+		if err != nil || !v.Implemented || v.Value != c.value || v.Writable != c.writable {
+			t.Errorf("%d %s: %+v %v", c.id, c.name, v, err)
+		}
+	}
+	if values, err := d.Model(705).Values(); err != nil || len(values) != 11+2*(9+4*2) ||
+		values[len(values)-1].Name != "Crv2.Pt4.Var" {
+		t.Errorf("%+v %v", values, err)
+	}
+
+	for _, c := range []struct {
+		id     int
+		name   string
+		value  float64
+		offset int
+		regs   []uint16
+	}{
+		{703, "ES", 1, 0, []uint16{1}},
+		{703, "ESRmpTms", 300, 11, []uint16{0, 300}},
+		{704, "WMaxLimPct", 80, 13, []uint16{800}},
+		{704, "WSet", -2500, 22, []uint16{0xFFFF, 0xF63C}},
+		{704, "PFWInj.PF", 0.95, 57, []uint16{950}},
+		{705, "Crv2.Pt3.V", 103.5, 13 + 18 + 10 + 4, []uint16{1035}},
+		{705, "Crv1.Pt1.Var", -44, 13 + 11, []uint16{0xFFD4}},
+	} {
+		m := d.Model(c.id)
+		if err = m.Write(c.name, c.value); err != nil {
+			t.Errorf("%d %s: %v", c.id, c.name, err)
+			continue
+		}
+		for i, r := range c.regs {
+			if got := mm.GetTabRegisters(m.Addr + c.offset + i); got != r {
+				t.Errorf("%d %s: register %d %04X", c.id, c.name, i, got)
+			}
+		}
+	}
+	if err = d.Model(703).Write("ESDlyRem", 1); err == nil {
+		t.Error("read only remaining time")
+	}
+	if err = d.Model(705).Write("Crv1.ReadOnly", 1); err == nil {
+		t.Error("read only curve")
+	}
+}
+
+func TestSunspecDiscover_NoMarker(t *testing.T) {
+	ctx, _ := sunspecClient(t, 0, make([]uint16, 10))
+	if _, err := SunspecDiscover(ctx); conformanceCode(err) != ErrorCode(syscall.ENOENT) {
+		t.Error(err)
+	}
+
+	// a device not answering is not a device without marker
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	go io.Copy(io.Discard, c1)
+	silent := ModbusNewConn(c2, MODBUS_FRAMING_TCP)
+	if silent == nil {
+		t.FailNow()
+	}
+	defer silent.Free()
+	silent.SetResponseTimeout(20 * time.Millisecond)
+	if _, err := SunspecDiscover(silent); conformanceCode(err) != ErrorCode(syscall.ETIMEDOUT) {
+		t.Error(err)
+	}
+	common, _ := SunspecModelNew(1, 65)
+	sunspecSet(t, common, map[string]any{"Mn": "Old"})
+	img := SunspecImage(0, common)
+	ctx, _ = sunspecClient(t, 0, img[:len(img)-2])
+	d, err := SunspecDiscover(ctx)
+	if err != nil || len(d.Models) != 1 {
+		t.Fatal(err)
+	}
+	if err = d.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := d.Models[0].Value("Mn"); v.Text != "Old" {
+		t.Error(v)
+	}
+	if _, err = d.Models[0].Value("Pad"); err == nil {
+		t.Error("pad of a model of 65 registers")
+	}
+}
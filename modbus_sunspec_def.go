@@ -0,0 +1,570 @@
+package libmodbusgo
+
+// SunspecDevice SunSpec device discovered through a client, see SunspecDiscover()
+type SunspecDevice struct {
+	x      *Modbus
+	Base   int // address of the SunS marker
+	Models []*SunspecModel
+}
+
+// SunspecModel model of a SunSpec device, the registers are read by Read()
+type SunspecModel struct {
+	ID   int
+	Name string   // name of the model, empty when the model has no definition
+	Addr int      // address of the first register of the model, after its ID and length
+	Regs []uint16 // registers of the model, as many as the length of the model
+
+	x   *Modbus
+	def *sunspecModelDef
+}
+
+// SunspecValue value of a point of a SunSpec model
+type SunspecValue struct {
+	Name  string // name of the point, prefixed by the group and index of the repeating blocks, eg. module1.DCA
+	Type  string // SunSpec type, eg. int16, acc32, enum16, sunssf or string
+	Units string
+	// Value scaled value of the numeric points, raw value of the enums and bit fields
+	Value float64
+	Text  string // value of the string points
+	// Implemented false when the register holds the unimplemented value of its type, or its scale factor does
+	Implemented bool
+	Writable    bool
+}
+
+// sunspecPoint point of a model definition
+type sunspecPoint struct {
+	name  string
+	typ   string // SunSpec type, the strings being stringN with N registers
+	sf    string // name of the scale factor point
+	units string
+	rw    bool
+}
+
+// sunspecModelDef definition of a model, a fixed block optionally followed by repeating blocks
+type sunspecModelDef struct {
+	name   string
+	fixed  []sunspecPoint
+	group  string // name of the repeating blocks
+	repeat []sunspecPoint
+
+	// nested blocks ending each repeating block, as many as the value of the fixed point nestedCount, eg. the points
+	// of the curves
+	nestedGroup string
+	nested      []sunspecPoint
+	nestedCount string
+	// nestedNew number of nested blocks of the models created by SunspecModelNew()
+	nestedNew int
+}
+
+func sunspecR(name, typ, sf, units string) sunspecPoint {
+	return sunspecPoint{name: name, typ: typ, sf: sf, units: units}
+}
+
+func sunspecRW(name, typ, sf, units string) sunspecPoint {
+	return sunspecPoint{name: name, typ: typ, sf: sf, units: units, rw: true}
+}
+
+// sunspecPhases points of a quantity and of its phases, eg. A, AphA, AphB and AphC
+func sunspecPhases(typ, sf, units string, names ...string) (points []sunspecPoint) {
+	for _, name := range names {
+		points = append(points, sunspecR(name, typ, sf, units))
+	}
+	return
+}
+
+func sunspecJoin(blocks ...[]sunspecPoint) (points []sunspecPoint) {
+	for _, b := range blocks {
+		points = append(points, b...)
+	}
+	return
+}
+
+var sunspecCommon = &sunspecModelDef{name: "common", fixed: []sunspecPoint{
+	sunspecR("Mn", "string16", "", ""),
+	sunspecR("Md", "string16", "", ""),
+	sunspecR("Opt", "string8", "", ""),
+	sunspecR("Vr", "string8", "", ""),
+	sunspecR("SN", "string16", "", ""),
+	sunspecRW("DA", "uint16", "", ""),
+	sunspecR("Pad", "pad", "", ""),
+}}
+
+var sunspecEvents = []sunspecPoint{
+	sunspecR("St", "enum16", "", ""),
+	sunspecR("StVnd", "enum16", "", ""),
+	sunspecR("Evt1", "bitfield32", "", ""),
+	sunspecR("Evt2", "bitfield32", "", ""),
+	sunspecR("EvtVnd1", "bitfield32", "", ""),
+	sunspecR("EvtVnd2", "bitfield32", "", ""),
+	sunspecR("EvtVnd3", "bitfield32", "", ""),
+	sunspecR("EvtVnd4", "bitfield32", "", ""),
+}
+
+var sunspecInverter = &sunspecModelDef{name: "inverter", fixed: sunspecJoin(
+	sunspecPhases("uint16", "A_SF", "A", "A", "AphA", "AphB", "AphC"),
+	[]sunspecPoint{sunspecR("A_SF", "sunssf", "", "")},
+	sunspecPhases("uint16", "V_SF", "V", "PPVphAB", "PPVphBC", "PPVphCA", "PhVphA", "PhVphB", "PhVphC"),
+	[]sunspecPoint{
+		sunspecR("V_SF", "sunssf", "", ""),
+		sunspecR("W", "int16", "W_SF", "W"),
+		sunspecR("W_SF", "sunssf", "", ""),
+		sunspecR("Hz", "uint16", "Hz_SF", "Hz"),
+		sunspecR("Hz_SF", "sunssf", "", ""),
+		sunspecR("VA", "int16", "VA_SF", "VA"),
+		sunspecR("VA_SF", "sunssf", "", ""),
+		sunspecR("VAr", "int16", "VAr_SF", "var"),
+		sunspecR("VAr_SF", "sunssf", "", ""),
+		sunspecR("PF", "int16", "PF_SF", "%"),
+		sunspecR("PF_SF", "sunssf", "", ""),
+		sunspecR("WH", "acc32", "WH_SF", "Wh"),
+		sunspecR("WH_SF", "sunssf", "", ""),
+		sunspecR("DCA", "uint16", "DCA_SF", "A"),
+		sunspecR("DCA_SF", "sunssf", "", ""),
+		sunspecR("DCV", "uint16", "DCV_SF", "V"),
+		sunspecR("DCV_SF", "sunssf", "", ""),
+		sunspecR("DCW", "int16", "DCW_SF", "W"),
+		sunspecR("DCW_SF", "sunssf", "", ""),
+	},
+	sunspecPhases("int16", "Tmp_SF", "C", "TmpCab", "TmpSnk", "TmpTrns", "TmpOt"),
+	[]sunspecPoint{sunspecR("Tmp_SF", "sunssf", "", "")},
+	sunspecEvents,
+)}
+
+var sunspecInverterFloat = &sunspecModelDef{name: "inverter", fixed: sunspecJoin(
+	sunspecPhases("float32", "", "A", "A", "AphA", "AphB", "AphC"),
+	sunspecPhases("float32", "", "V", "PPVphAB", "PPVphBC", "PPVphCA", "PhVphA", "PhVphB", "PhVphC"),
+	[]sunspecPoint{
+		sunspecR("W", "float32", "", "W"),
+		sunspecR("Hz", "float32", "", "Hz"),
+		sunspecR("VA", "float32", "", "VA"),
+		sunspecR("VAr", "float32", "", "var"),
+		sunspecR("PF", "float32", "", "%"),
+		sunspecR("WH", "float32", "", "Wh"),
+		sunspecR("DCA", "float32", "", "A"),
+		sunspecR("DCV", "float32", "", "V"),
+		sunspecR("DCW", "float32", "", "W"),
+	},
+	sunspecPhases("float32", "", "C", "TmpCab", "TmpSnk", "TmpTrns", "TmpOt"),
+	sunspecEvents,
+)}
+
+var sunspecNameplate = &sunspecModelDef{name: "nameplate", fixed: sunspecJoin(
+	[]sunspecPoint{
+		sunspecR("DERTyp", "enum16", "", ""),
+		sunspecR("WRtg", "uint16", "WRtg_SF", "W"),
+		sunspecR("WRtg_SF", "sunssf", "", ""),
+		sunspecR("VARtg", "uint16", "VARtg_SF", "VA"),
+		sunspecR("VARtg_SF", "sunssf", "", ""),
+	},
+	sunspecPhases("int16", "VArRtg_SF", "var", "VArRtgQ1", "VArRtgQ2", "VArRtgQ3", "VArRtgQ4"),
+	[]sunspecPoint{
+		sunspecR("VArRtg_SF", "sunssf", "", ""),
+		sunspecR("ARtg", "uint16", "ARtg_SF", "A"),
+		sunspecR("ARtg_SF", "sunssf", "", ""),
+	},
+	sunspecPhases("int16", "PFRtg_SF", "", "PFRtgQ1", "PFRtgQ2", "PFRtgQ3", "PFRtgQ4"),
+	[]sunspecPoint{
+		sunspecR("PFRtg_SF", "sunssf", "", ""),
+		sunspecR("WHRtg", "uint16", "WHRtg_SF", "Wh"),
+		sunspecR("WHRtg_SF", "sunssf", "", ""),
+		sunspecR("AhrRtg", "uint16", "AhrRtg_SF", "Ah"),
+		sunspecR("AhrRtg_SF", "sunssf", "", ""),
+		sunspecR("MaxChaRte", "uint16", "MaxChaRte_SF", "W"),
+		sunspecR("MaxChaRte_SF", "sunssf", "", ""),
+		sunspecR("MaxDisChaRte", "uint16", "MaxDisChaRte_SF", "W"),
+		sunspecR("MaxDisChaRte_SF", "sunssf", "", ""),
+		sunspecR("Pad", "pad", "", ""),
+	},
+)}
+
+var sunspecSettings = &sunspecModelDef{name: "settings", fixed: []sunspecPoint{
+	sunspecRW("WMax", "uint16", "WMax_SF", "W"),
+	sunspecRW("VRef", "uint16", "VRef_SF", "V"),
+	sunspecRW("VRefOfs", "int16", "VRefOfs_SF", "V"),
+	sunspecRW("VMax", "uint16", "VMinMax_SF", "V"),
+	sunspecRW("VMin", "uint16", "VMinMax_SF", "V"),
+	sunspecRW("VAMax", "uint16", "VAMax_SF", "VA"),
+	sunspecRW("VArMaxQ1", "int16", "VArMax_SF", "var"),
+	sunspecRW("VArMaxQ2", "int16", "VArMax_SF", "var"),
+	sunspecRW("VArMaxQ3", "int16", "VArMax_SF", "var"),
+	sunspecRW("VArMaxQ4", "int16", "VArMax_SF", "var"),
+	sunspecRW("WGra", "uint16", "WGra_SF", "%"),
+	sunspecRW("PFMinQ1", "int16", "PFMin_SF", ""),
+	sunspecRW("PFMinQ2", "int16", "PFMin_SF", ""),
+	sunspecRW("PFMinQ3", "int16", "PFMin_SF", ""),
+	sunspecRW("PFMinQ4", "int16", "PFMin_SF", ""),
+	sunspecRW("VArAct", "enum16", "", ""),
+	sunspecRW("ClcTotVA", "enum16", "", ""),
+	sunspecRW("MaxRmpRte", "uint16", "MaxRmpRte_SF", "%"),
+	sunspecRW("ECPNomHz", "uint16", "ECPNomHz_SF", "Hz"),
+	sunspecRW("ConnPh", "enum16", "", ""),
+	sunspecR("WMax_SF", "sunssf", "", ""),
+	sunspecR("VRef_SF", "sunssf", "", ""),
+	sunspecR("VRefOfs_SF", "sunssf", "", ""),
+	sunspecR("VMinMax_SF", "sunssf", "", ""),
+	sunspecR("VAMax_SF", "sunssf", "", ""),
+	sunspecR("VArMax_SF", "sunssf", "", ""),
+	sunspecR("WGra_SF", "sunssf", "", ""),
+	sunspecR("PFMin_SF", "sunssf", "", ""),
+	sunspecR("MaxRmpRte_SF", "sunssf", "", ""),
+	sunspecR("ECPNomHz_SF", "sunssf", "", ""),
+}}
+
+var sunspecStatus = &sunspecModelDef{name: "status", fixed: []sunspecPoint{
+	sunspecR("PVConn", "bitfield16", "", ""),
+	sunspecR("StorConn", "bitfield16", "", ""),
+	sunspecR("ECPConn", "bitfield16", "", ""),
+	sunspecR("ActWh", "acc64", "", "Wh"),
+	sunspecR("ActVAh", "acc64", "", "VAh"),
+	sunspecR("ActVArhQ1", "acc64", "", "varh"),
+	sunspecR("ActVArhQ2", "acc64", "", "varh"),
+	sunspecR("ActVArhQ3", "acc64", "", "varh"),
+	sunspecR("ActVArhQ4", "acc64", "", "varh"),
+	sunspecR("VArAval", "int16", "VArAval_SF", "var"),
+	sunspecR("VArAval_SF", "sunssf", "", ""),
+	sunspecR("WAval", "uint16", "WAval_SF", "W"),
+	sunspecR("WAval_SF", "sunssf", "", ""),
+	sunspecR("StSetLimMsk", "bitfield32", "", ""),
+	sunspecR("StActCtl", "bitfield32", "", ""),
+	sunspecR("TmSrc", "string4", "", ""),
+	sunspecR("Tms", "uint32", "", "s"),
+	sunspecR("RtSt", "bitfield16", "", ""),
+	sunspecR("Ris", "uint16", "Ris_SF", "ohms"),
+	sunspecR("Ris_SF", "sunssf", "", ""),
+}}
+
+var sunspecControls = &sunspecModelDef{name: "controls", fixed: []sunspecPoint{
+	sunspecRW("Conn_WinTms", "uint16", "", "s"),
+	sunspecRW("Conn_RvrtTms", "uint16", "", "s"),
+	sunspecRW("Conn", "enum16", "", ""),
+	sunspecRW("WMaxLimPct", "uint16", "WMaxLimPct_SF", "%"),
+	sunspecRW("WMaxLimPct_WinTms", "uint16", "", "s"),
+	sunspecRW("WMaxLimPct_RvrtTms", "uint16", "", "s"),
+	sunspecRW("WMaxLimPct_RmpTms", "uint16", "", "s"),
+	sunspecRW("WMaxLim_Ena", "enum16", "", ""),
+	sunspecRW("OutPFSet", "int16", "OutPFSet_SF", ""),
+	sunspecRW("OutPFSet_WinTms", "uint16", "", "s"),
+	sunspecRW("OutPFSet_RvrtTms", "uint16", "", "s"),
+	sunspecRW("OutPFSet_RmpTms", "uint16", "", "s"),
+	sunspecRW("OutPFSet_Ena", "enum16", "", ""),
+	sunspecRW("VArWMaxPct", "int16", "VArPct_SF", "%"),
+	sunspecRW("VArMaxPct", "int16", "VArPct_SF", "%"),
+	sunspecRW("VArAvalPct", "int16", "VArPct_SF", "%"),
+	sunspecRW("VArPct_WinTms", "uint16", "", "s"),
+	sunspecRW("VArPct_RvrtTms", "uint16", "", "s"),
+	sunspecRW("VArPct_RmpTms", "uint16", "", "s"),
+	sunspecRW("VArPct_Mod", "enum16", "", ""),
+	sunspecRW("VArPct_Ena", "enum16", "", ""),
+	sunspecR("WMaxLimPct_SF", "sunssf", "", ""),
+	sunspecR("OutPFSet_SF", "sunssf", "", ""),
+	sunspecR("VArPct_SF", "sunssf", "", ""),
+}}
+
+var sunspecStorage = &sunspecModelDef{name: "storage", fixed: []sunspecPoint{
+	sunspecRW("WChaMax", "uint16", "WChaMax_SF", "W"),
+	sunspecRW("WChaGra", "uint16", "WChaDisChaGra_SF", "%"),
+	sunspecRW("WDisChaGra", "uint16", "WChaDisChaGra_SF", "%"),
+	sunspecRW("StorCtl_Mod", "bitfield16", "", ""),
+	sunspecRW("VAChaMax", "uint16", "VAChaMax_SF", "VA"),
+	sunspecRW("MinRsvPct", "uint16", "MinRsvPct_SF", "%"),
+	sunspecR("ChaState", "uint16", "ChaState_SF", "%"),
+	sunspecR("StorAval", "uint16", "StorAval_SF", "Ah"),
+	sunspecR("InBatV", "uint16", "InBatV_SF", "V"),
+	sunspecR("ChaSt", "enum16", "", ""),
+	sunspecRW("OutWRte", "int16", "InOutWRte_SF", "%"),
+	sunspecRW("InWRte", "int16", "InOutWRte_SF", "%"),
+	sunspecRW("InOutWRte_WinTms", "uint16", "", "s"),
+	sunspecRW("InOutWRte_RvrtTms", "uint16", "", "s"),
+	sunspecRW("InOutWRte_RmpTms", "uint16", "", "s"),
+	sunspecRW("ChaGriSet", "enum16", "", ""),
+	sunspecR("WChaMax_SF", "sunssf", "", ""),
+	sunspecR("WChaDisChaGra_SF", "sunssf", "", ""),
+	sunspecR("VAChaMax_SF", "sunssf", "", ""),
+	sunspecR("MinRsvPct_SF", "sunssf", "", ""),
+	sunspecR("ChaState_SF", "sunssf", "", ""),
+	sunspecR("StorAval_SF", "sunssf", "", ""),
+	sunspecR("InBatV_SF", "sunssf", "", ""),
+	sunspecR("InOutWRte_SF", "sunssf", "", ""),
+}}
+
+var sunspecMppt = &sunspecModelDef{name: "mppt", fixed: []sunspecPoint{
+	sunspecR("DCA_SF", "sunssf", "", ""),
+	sunspecR("DCV_SF", "sunssf", "", ""),
+	sunspecR("DCW_SF", "sunssf", "", ""),
+	sunspecR("DCWH_SF", "sunssf", "", ""),
+	sunspecR("Evt", "bitfield32", "", ""),
+	sunspecR("N", "count", "", ""),
+	sunspecR("TmsPer", "uint16", "", ""),
+}, group: "module", repeat: []sunspecPoint{
+	sunspecR("ID", "uint16", "", ""),
+	sunspecR("IDStr", "string8", "", ""),
+	sunspecR("DCA", "uint16", "DCA_SF", "A"),
+	sunspecR("DCV", "uint16", "DCV_SF", "V"),
+	sunspecR("DCW", "uint16", "DCW_SF", "W"),
+	sunspecR("DCWH", "acc32", "DCWH_SF", "Wh"),
+	sunspecR("Tms", "uint32", "", "s"),
+	sunspecR("Tmp", "int16", "", "C"),
+	sunspecR("DCSt", "enum16", "", ""),
+	sunspecR("DCEvt", "bitfield32", "", ""),
+}}
+
+// sunspecMeterEnergy accumulators of the total and the phases of an energy
+func sunspecMeterEnergy(sf, units string, names ...string) (points []sunspecPoint) {
+	for _, name := range names {
+		points = append(points, sunspecPhases("acc32", sf, units, name, name+"PhA", name+"PhB", name+"PhC")...)
+	}
+	return append(points, sunspecR(sf, "sunssf", "", ""))
+}
+
+var sunspecMeter = &sunspecModelDef{name: "meter", fixed: sunspecJoin(
+	sunspecPhases("int16", "A_SF", "A", "A", "AphA", "AphB", "AphC"),
+	[]sunspecPoint{sunspecR("A_SF", "sunssf", "", "")},
+	sunspecPhases("int16", "V_SF", "V", "PhV", "PhVphA", "PhVphB", "PhVphC", "PPV", "PPVphAB", "PPVphBC", "PPVphCA"),
+	[]sunspecPoint{
+		sunspecR("V_SF", "sunssf", "", ""),
+		sunspecR("Hz", "int16", "Hz_SF", "Hz"),
+		sunspecR("Hz_SF", "sunssf", "", ""),
+	},
+	sunspecPhases("int16", "W_SF", "W", "W", "WphA", "WphB", "WphC"),
+	[]sunspecPoint{sunspecR("W_SF", "sunssf", "", "")},
+	sunspecPhases("int16", "VA_SF", "VA", "VA", "VAphA", "VAphB", "VAphC"),
+	[]sunspecPoint{sunspecR("VA_SF", "sunssf", "", "")},
+	sunspecPhases("int16", "VAR_SF", "var", "VAR", "VARphA", "VARphB", "VARphC"),
+	[]sunspecPoint{sunspecR("VAR_SF", "sunssf", "", "")},
+	sunspecPhases("int16", "PF_SF", "%", "PF", "PFphA", "PFphB", "PFphC"),
+	[]sunspecPoint{sunspecR("PF_SF", "sunssf", "", "")},
+	sunspecMeterEnergy("TotWh_SF", "Wh", "TotWhExp", "TotWhImp"),
+	sunspecMeterEnergy("TotVAh_SF", "VAh", "TotVAhExp", "TotVAhImp"),
+	sunspecMeterEnergy("TotVArh_SF", "varh", "TotVArhImpQ1", "TotVArhImpQ2", "TotVArhExpQ3", "TotVArhExpQ4"),
+	[]sunspecPoint{sunspecR("Evt", "bitfield32", "", "")},
+)}
+
+// sunspecDerLine measurements of a line of the DER AC measurement model
+func sunspecDerLine(l, ll string) []sunspecPoint {
+	return []sunspecPoint{
+		sunspecR("W"+l, "int16", "W_SF", "W"),
+		sunspecR("VA"+l, "int16", "VA_SF", "VA"),
+		sunspecR("Var"+l, "int16", "Var_SF", "var"),
+		sunspecR("PF"+l, "int16", "PF_SF", ""),
+		sunspecR("A"+l, "int16", "A_SF", "A"),
+		sunspecR("V"+ll, "uint16", "V_SF", "V"),
+		sunspecR("V"+l, "uint16", "V_SF", "V"),
+		sunspecR("TotWhInj"+l, "uint64", "TotWh_SF", "Wh"),
+		sunspecR("TotWhAbs"+l, "uint64", "TotWh_SF", "Wh"),
+		sunspecR("TotVarhInj"+l, "uint64", "TotVarh_SF", "varh"),
+		sunspecR("TotVarhAbs"+l, "uint64", "TotVarh_SF", "varh"),
+	}
+}
+
+var sunspecDerMeasurement = &sunspecModelDef{name: "der_measure_ac", fixed: sunspecJoin(
+	[]sunspecPoint{
+		sunspecR("ACType", "enum16", "", ""),
+		sunspecR("St", "enum16", "", ""),
+		sunspecR("InvSt", "enum16", "", ""),
+		sunspecR("ConnSt", "enum16", "", ""),
+		sunspecR("Alrm", "bitfield32", "", ""),
+		sunspecR("DERMode", "bitfield32", "", ""),
+		sunspecR("W", "int16", "W_SF", "W"),
+		sunspecR("VA", "int16", "VA_SF", "VA"),
+		sunspecR("Var", "int16", "Var_SF", "var"),
+		sunspecR("PF", "int16", "PF_SF", ""),
+		sunspecR("A", "int16", "A_SF", "A"),
+		sunspecR("LLV", "uint16", "V_SF", "V"),
+		sunspecR("LNV", "uint16", "V_SF", "V"),
+		sunspecR("Hz", "uint32", "Hz_SF", "Hz"),
+		sunspecR("TotWhInj", "uint64", "TotWh_SF", "Wh"),
+		sunspecR("TotWhAbs", "uint64", "TotWh_SF", "Wh"),
+		sunspecR("TotVarhInj", "uint64", "TotVarh_SF", "varh"),
+		sunspecR("TotVarhAbs", "uint64", "TotVarh_SF", "varh"),
+	},
+	sunspecPhases("int16", "Tmp_SF", "C", "TmpAmb", "TmpCab", "TmpSnk", "TmpTrns", "TmpSw", "TmpOt"),
+	sunspecDerLine("L1", "L1L2"),
+	sunspecDerLine("L2", "L2L3"),
+	sunspecDerLine("L3", "L3L1"),
+	[]sunspecPoint{
+		sunspecR("ThrotPct", "uint16", "", "%"),
+		sunspecR("ThrotSrc", "bitfield32", "", ""),
+		sunspecR("A_SF", "sunssf", "", ""),
+		sunspecR("V_SF", "sunssf", "", ""),
+		sunspecR("Hz_SF", "sunssf", "", ""),
+		sunspecR("W_SF", "sunssf", "", ""),
+		sunspecR("PF_SF", "sunssf", "", ""),
+		sunspecR("VA_SF", "sunssf", "", ""),
+		sunspecR("Var_SF", "sunssf", "", ""),
+		sunspecR("TotWh_SF", "sunssf", "", ""),
+		sunspecR("TotVarh_SF", "sunssf", "", ""),
+		sunspecR("Tmp_SF", "sunssf", "", ""),
+		sunspecR("MnAlrmInfo", "string32", "", ""),
+	},
+)}
+
+// sunspecRWs writable points of a type
+func sunspecRWs(typ, sf, units string, names ...string) (points []sunspecPoint) {
+	for _, name := range names {
+		points = append(points, sunspecRW(name, typ, sf, units))
+	}
+	return
+}
+
+// sunspecSFs scale factors
+func sunspecSFs(names ...string) (points []sunspecPoint) {
+	for _, name := range names {
+		points = append(points, sunspecR(name, "sunssf", "", ""))
+	}
+	return
+}
+
+var sunspecDerCapacity = &sunspecModelDef{name: "der_capacity", fixed: sunspecJoin(
+	[]sunspecPoint{
+		sunspecR("WMaxRtg", "uint16", "W_SF", "W"),
+		sunspecR("WOvrExtRtg", "uint16", "W_SF", "W"),
+		sunspecR("WOvrExtRtgPF", "uint16", "PF_SF", ""),
+		sunspecR("WUndExtRtg", "uint16", "W_SF", "W"),
+		sunspecR("WUndExtRtgPF", "uint16", "PF_SF", ""),
+		sunspecR("VAMaxRtg", "uint16", "VA_SF", "VA"),
+		sunspecR("VarMaxInjRtg", "uint16", "Var_SF", "var"),
+		sunspecR("VarMaxAbsRtg", "uint16", "Var_SF", "var"),
+		sunspecR("WChaRteMaxRtg", "uint16", "W_SF", "W"),
+		sunspecR("WDisChaRteMaxRtg", "uint16", "W_SF", "W"),
+		sunspecR("VAChaRteMaxRtg", "uint16", "VA_SF", "VA"),
+		sunspecR("VADisChaRteMaxRtg", "uint16", "VA_SF", "VA"),
+		sunspecR("VNomRtg", "uint16", "V_SF", "V"),
+		sunspecR("VMaxRtg", "uint16", "V_SF", "V"),
+		sunspecR("VMinRtg", "uint16", "V_SF", "V"),
+		sunspecR("AMaxRtg", "uint16", "A_SF", "A"),
+		sunspecR("PFOvrExtRtg", "uint16", "PF_SF", ""),
+		sunspecR("PFUndExtRtg", "uint16", "PF_SF", ""),
+		sunspecR("ReactSusceptRtg", "uint16", "S_SF", "S"),
+		sunspecR("NorOpCatRtg", "enum16", "", ""),
+		sunspecR("AbnOpCatRtg", "enum16", "", ""),
+		sunspecR("CtrlModes", "bitfield32", "", ""),
+		sunspecR("IntIslandCatRtg", "bitfield16", "", ""),
+		sunspecRW("WMax", "uint16", "W_SF", "W"),
+		sunspecRW("WMaxOvrExt", "uint16", "W_SF", "W"),
+		sunspecRW("WOvrExtPF", "uint16", "PF_SF", ""),
+		sunspecRW("WMaxUndExt", "uint16", "W_SF", "W"),
+		sunspecRW("WUndExtPF", "uint16", "PF_SF", ""),
+		sunspecRW("VAMax", "uint16", "VA_SF", "VA"),
+		sunspecRW("VarMaxInj", "uint16", "Var_SF", "var"),
+		sunspecRW("VarMaxAbs", "uint16", "Var_SF", "var"),
+		sunspecRW("WChaRteMax", "uint16", "W_SF", "W"),
+		sunspecRW("WDisChaRteMax", "uint16", "W_SF", "W"),
+		sunspecRW("VAChaRteMax", "uint16", "VA_SF", "VA"),
+		sunspecRW("VADisChaRteMax", "uint16", "VA_SF", "VA"),
+	},
+	sunspecRWs("uint16", "V_SF", "V", "VNom", "VMax", "VMin"),
+	[]sunspecPoint{
+		sunspecRW("AMax", "uint16", "A_SF", "A"),
+		sunspecRW("PFOvrExt", "uint16", "PF_SF", ""),
+		sunspecRW("PFUndExt", "uint16", "PF_SF", ""),
+		sunspecRW("IntIslandCat", "bitfield16", "", ""),
+	},
+	sunspecSFs("W_SF", "PF_SF", "VA_SF", "Var_SF", "V_SF", "A_SF", "S_SF"),
+)}
+
+var sunspecDerEnterService = &sunspecModelDef{name: "der_enter_service", fixed: sunspecJoin(
+	[]sunspecPoint{sunspecRW("ES", "enum16", "", "")},
+	sunspecRWs("uint16", "V_SF", "%", "ESVHi", "ESVLo"),
+	sunspecRWs("uint32", "Hz_SF", "Hz", "ESHzHi", "ESHzLo"),
+	sunspecRWs("uint32", "", "s", "ESDlyTms", "ESRndTms", "ESRmpTms"),
+	[]sunspecPoint{sunspecR("ESDlyRem", "uint32", "", "s")},
+	sunspecSFs("V_SF", "Hz_SF"),
+)}
+
+// sunspecDerRevert enable, revert enable, revert timeout and remaining time of a control of the DER AC controls model
+func sunspecDerRevert(name string, setpoints ...sunspecPoint) []sunspecPoint {
+	return sunspecJoin(
+		[]sunspecPoint{sunspecRW(name+"Ena", "enum16", "", "")},
+		setpoints,
+		[]sunspecPoint{
+			sunspecRW(name+"EnaRvrt", "enum16", "", ""),
+			sunspecRW(name+"RvrtTms", "uint32", "", "s"),
+			sunspecR(name+"RvrtRem", "uint32", "", "s"),
+		},
+	)
+}
+
+// sunspecDerPF power factor setting of the DER AC controls model
+func sunspecDerPF(name string) []sunspecPoint {
+	return []sunspecPoint{sunspecRW(name+".PF", "uint16", "PF_SF", ""), sunspecRW(name+".Ext", "enum16", "", "")}
+}
+
+var sunspecDerControls = &sunspecModelDef{name: "der_ctl_ac", fixed: sunspecJoin(
+	sunspecDerRevert("PFWInj"),
+	sunspecDerRevert("PFWAbs"),
+	sunspecDerRevert("WMaxLimPct", sunspecRWs("uint16", "WMaxLimPct_SF", "%", "WMaxLimPct", "WMaxLimPctRvrt")...),
+	sunspecDerRevert("WSet", sunspecJoin(
+		[]sunspecPoint{sunspecRW("WSetMod", "enum16", "", "")},
+		sunspecRWs("int32", "WSet_SF", "W", "WSet", "WSetRvrt"),
+		sunspecRWs("int16", "WSetPct_SF", "%", "WSetPct", "WSetPctRvrt"),
+	)...),
+	sunspecDerRevert("VarSet", sunspecJoin(
+		sunspecRWs("enum16", "", "", "VarSetMod", "VarSetPri"),
+		sunspecRWs("int32", "VarSet_SF", "var", "VarSet", "VarSetRvrt"),
+		sunspecRWs("int16", "VarSetPct_SF", "%", "VarSetPct", "VarSetPctRvrt"),
+	)...),
+	[]sunspecPoint{
+		sunspecRW("WRmp", "uint16", "", "%"),
+		sunspecRW("WRmpRef", "enum16", "", ""),
+		sunspecRW("VarRmp", "uint16", "", "%"),
+		sunspecRW("AntiIslEna", "enum16", "", ""),
+	},
+	sunspecSFs("PF_SF", "WMaxLimPct_SF", "WSet_SF", "WSetPct_SF", "VarSet_SF", "VarSetPct_SF"),
+	sunspecDerPF("PFWInj"),
+	sunspecDerPF("PFWInjRvrt"),
+	sunspecDerPF("PFWAbs"),
+	sunspecDerPF("PFWAbsRvrt"),
+)}
+
+var sunspecDerVoltVar = &sunspecModelDef{name: "der_volt_var", fixed: []sunspecPoint{
+	sunspecRW("Ena", "enum16", "", ""),
+	sunspecRW("AdptCrvReq", "uint16", "", ""),
+	sunspecR("AdptCrvRslt", "enum16", "", ""),
+	sunspecR("NPt", "uint16", "", ""),
+	sunspecR("NCrv", "count", "", ""),
+	sunspecRW("RvrtTms", "uint32", "", "s"),
+	sunspecR("RvrtRem", "uint32", "", "s"),
+	sunspecRW("RvrtCrv", "uint16", "", ""),
+	sunspecR("V_SF", "sunssf", "", ""),
+	sunspecR("DeptRef_SF", "sunssf", "", ""),
+	sunspecR("RspTms_SF", "sunssf", "", ""),
+}, group: "Crv", repeat: []sunspecPoint{
+	sunspecRW("ActPt", "uint16", "", ""),
+	sunspecRW("DeptRef", "enum16", "", ""),
+	sunspecRW("Pri", "enum16", "", ""),
+	sunspecRW("VRef", "uint16", "V_SF", "%"),
+	sunspecR("VRefAuto", "uint16", "V_SF", "%"),
+	sunspecRW("VRefAutoEna", "enum16", "", ""),
+	sunspecRW("VRefAutoTms", "uint16", "", "s"),
+	sunspecRW("RspTms", "uint32", "RspTms_SF", "s"),
+	sunspecR("ReadOnly", "enum16", "", ""),
+}, nestedGroup: "Pt", nested: []sunspecPoint{
+	sunspecRW("V", "uint16", "V_SF", "%"),
+	sunspecRW("Var", "int16", "DeptRef_SF", "%"),
+}, nestedCount: "NPt", nestedNew: 4}
+
+// sunspecModels definitions of the models by ID
+var sunspecModels = map[int]*sunspecModelDef{
+	1:   sunspecCommon,
+	101: sunspecInverter,
+	102: sunspecInverter,
+	103: sunspecInverter,
+	111: sunspecInverterFloat,
+	112: sunspecInverterFloat,
+	113: sunspecInverterFloat,
+	120: sunspecNameplate,
+	121: sunspecSettings,
+	122: sunspecStatus,
+	123: sunspecControls,
+	124: sunspecStorage,
+	160: sunspecMppt,
+	201: sunspecMeter,
+	202: sunspecMeter,
+	203: sunspecMeter,
+	204: sunspecMeter,
+	701: sunspecDerMeasurement,
+	702: sunspecDerCapacity,
+	703: sunspecDerEnterService,
+	704: sunspecDerControls,
+	705: sunspecDerVoltVar,
+}
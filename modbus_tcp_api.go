@@ -1,3 +1,5 @@
+//go:build cgo && !purego
+
 package libmodbusgo
 
 /*
@@ -38,6 +40,9 @@ func ModbusNewTcp(addr string, port int) *Modbus {
 // the specified IP address. The context ctx must be allocated and initialized with modbus_new_tcp before to set the IP
 // address to listen, if IP address is set to NULL or '0.0.0.0', any addresses will be listen.
 func (x *Modbus) TcpListen(nb int) (socket int, err error) {
+	if x.be != nil {
+		return x.be.listenSocket()
+	}
 	code := C.modbus_tcp_listen(x.ctx, C.int(nb))
 	if code < 0 {
 		err = ModbusStrError()
@@ -54,6 +59,9 @@ func (x *Modbus) TcpListen(nb int) (socket int, err error) {
 // new socket and store it in libmodbus context given in argument. If available, accept4() with SOCK_CLOEXEC will be
 // called instead of accept().
 func (x *Modbus) TcpAccept() (err error) {
+	if x.be != nil {
+		return x.be.accept()
+	}
 	code := C.modbus_tcp_accept(x.ctx, (*C.int)(unsafe.Pointer(&x.socket)))
 	if code < 0 {
 		err = ModbusStrError()
@@ -125,6 +133,9 @@ func (x *Modbus) TcpPiAccept() (err error) {
 // If you need to use another socket or file descriptor than the one defined in the context ctx, see the function
 // modbus_set_socket.
 func (x *Modbus) TcpReceive() (req []byte, err error) {
+	if x.be != nil {
+		return x.be.receiveIndication()
+	}
 	recv := make([]C.uint8_t, MODBUS_TCP_MAX_ADU_LENGTH)
 	code := C.modbus_receive(x.ctx, unsafe.SliceData(recv))
 	if code < 0 {
@@ -148,6 +159,9 @@ func (x *Modbus) TcpReceive() (req []byte, err error) {
 // use the constant MODBUS_MAX_ADU_LENGTH (maximum value of all libmodbus backends). Take care to allocate enough
 // memory to store responses to avoid crashes of your server.
 func (x *Modbus) TcpReceiveConfirmation() (rsp []byte, err error) {
+	if x.be != nil {
+		return x.be.receive(false)
+	}
 	recv := make([]C.uint8_t, MODBUS_TCP_MAX_ADU_LENGTH)
 	code := C.modbus_receive_confirmation(x.ctx, unsafe.SliceData(recv))
 	if code < 0 {
@@ -0,0 +1,71 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ModbusNewTcp modbus_new_tcp - create a libmodbus context for TCP/IPv4
+//
+// An empty addr listens on any address.
+func ModbusNewTcp(addr string, port int) *Modbus {
+	if port < 0 || port > 0xFFFF {
+		return nil
+	}
+	return modbusNewTcp("tcp4", net.JoinHostPort(addr, strconv.Itoa(port)))
+}
+
+func modbusNewTcp(network string, address string) *Modbus {
+	var be *modbusBackend
+	be = modbusBackendNew(modbusMbap{}, func() (io.ReadWriteCloser, error) {
+		return net.DialTimeout(network, address, max(be.responseTimeout, time.Millisecond))
+	})
+	be.listen = func() (net.Listener, error) {
+		return net.Listen(network, address)
+	}
+	return &Modbus{be: be}
+}
+
+// TcpListen modbus_tcp_listen - create and listen a TCP Modbus socket (IPv4)
+func (x *Modbus) TcpListen(nb int) (socket int, err error) {
+	return x.be.listenSocket()
+}
+
+// TcpAccept modbus_tcp_accept - accept a new connection on a TCP Modbus socket (IPv4)
+func (x *Modbus) TcpAccept() (err error) {
+	return x.be.accept()
+}
+
+// ModbusNewTcpPi modbus_new_tcp_pi - create a libmodbus context for TCP Protocol Independent
+//
+// An empty service is the Modbus default port 502.
+func ModbusNewTcpPi(node string, service string) *Modbus {
+	if service == "" {
+		service = strconv.Itoa(MODBUS_TCP_DEFAULT_PORT)
+	}
+	return modbusNewTcp("tcp", net.JoinHostPort(node, service))
+}
+
+// TcpPiListen modbus_tcp_pi_listen - create and listen a TCP PI Modbus socket (IPv4 and IPv6)
+func (x *Modbus) TcpPiListen(nb int) (socket int, err error) {
+	return x.be.listenSocket()
+}
+
+// TcpPiAccept modbus_tcp_pi_accept - accept a new connection on a TCP PI Modbus socket (IPv4 and IPv6)
+func (x *Modbus) TcpPiAccept() (err error) {
+	return x.be.accept()
+}
+
+// TcpReceive modbus_receive - receive an indication request
+func (x *Modbus) TcpReceive() (req []byte, err error) {
+	return x.be.receiveIndication()
+}
+
+// TcpReceiveConfirmation modbus_receive_confirmation - receive a confirmation request
+func (x *Modbus) TcpReceiveConfirmation() (rsp []byte, err error) {
+	return x.be.receive(false)
+}
@@ -0,0 +1,14 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+const (
+	MODBUS_TCP_DEFAULT_PORT = 502
+	MODBUS_TCP_SLAVE        = 0xFF
+)
+
+const (
+	// MODBUS_TCP_MAX_ADU_LENGTH Modbus_Application_Protocol_V1_1b.pdf Chapter 4 Section 1 Page 5
+	// TCP MODBUS ADU = 253 bytes + MBAP (7 bytes) = 260 bytes
+	MODBUS_TCP_MAX_ADU_LENGTH = 260
+)
@@ -0,0 +1,33 @@
+package libmodbusgo
+
+// modbusTcpHeaderLength MBAP header: transaction id (2), protocol id (2), length (2), unit id (1)
+const modbusTcpHeaderLength = 7
+
+// modbusMbap framing of Modbus TCP for the Go backends, the binary ADU is the MBAP header and the PDU
+type modbusMbap struct{}
+
+func (modbusMbap) headerLength() int   { return modbusTcpHeaderLength }
+func (modbusMbap) checksumLength() int { return 0 }
+func (modbusMbap) serial() bool        { return false }
+
+func (modbusMbap) encode(adu []byte) []byte {
+	return adu
+}
+
+// read receive a frame, its length is given by the length field of the MBAP header
+func (modbusMbap) read(rd *modbusReader, indication bool) (adu []byte, err error) {
+	adu, err = rd.readFull(modbusTcpHeaderLength)
+	if err != nil {
+		return
+	}
+	// the length counts the unit identifier and the PDU
+	length := int(adu[4])<<8 | int(adu[5])
+	if length < 2 || modbusTcpHeaderLength-1+length > MODBUS_TCP_MAX_ADU_LENGTH {
+		return nil, EMBBADDATA.Error()
+	}
+	pdu, err := rd.readFull(length - 1)
+	if err != nil {
+		return nil, err
+	}
+	return append(adu, pdu...), nil
+}
@@ -0,0 +1,125 @@
+package libmodbusgo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ModbusNewTls create a context for Modbus/TCP Security
+//
+// The ModbusNewTls() function shall allocate and initialize a Modbus context exchanging Modbus TCP messages, MBAP
+// header included, over mutually authenticated TLS. The addr and port arguments are the ones of ModbusNewTcp(), use
+// MODBUS_TLS_DEFAULT_PORT for the port of the specification.
+//
+// The config argument holds the certificate of the context and the certificate authorities trusted to authenticate the
+// peer. As required by the specification, the minimum version is raised to TLS 1.2 and a server requires and verifies
+// the client certificate.
+//
+// As a client, Connect() establishes the connection and completes the handshake within the response timeout. As a
+// server, TcpListen() and TcpAccept() listen to addr and port and complete the handshake of the accepted client, the
+// role of the client is then returned by TlsRole().
+func ModbusNewTls(addr string, port int, config *tls.Config) *Modbus {
+	if port < 0 || port > 0xFFFF || config == nil {
+		return nil
+	}
+	address := net.JoinHostPort(addr, strconv.Itoa(port))
+	config = config.Clone()
+	config.MinVersion = max(config.MinVersion, tls.VersionTLS12)
+	var be *modbusBackend
+	be = modbusBackendNew(modbusMbap{}, func() (io.ReadWriteCloser, error) {
+		dialer := &net.Dialer{Timeout: max(be.responseTimeout, time.Millisecond)}
+		return tls.DialWithDialer(dialer, "tcp", address, config)
+	})
+	be.listen = func() (net.Listener, error) {
+		server := config.Clone()
+		server.ClientAuth = tls.RequireAndVerifyClientCert
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		return &modbusTlsListener{Listener: tls.NewListener(l, server), tcp: l.(*net.TCPListener)}, nil
+	}
+	return &Modbus{be: be}
+}
+
+func (l *modbusTlsListener) SyscallConn() (syscall.RawConn, error) {
+	return l.tcp.SyscallConn()
+}
+
+// ModbusCertificateRole return the role of a Modbus/TCP Security certificate
+//
+// The ModbusCertificateRole() function shall return the value of the ModbusRoleOid extension of the certificate, or an
+// empty role when the certificate has none. A certificate with several role extensions is rejected.
+func ModbusCertificateRole(cert *x509.Certificate) (role string, err error) {
+	found := false
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(ModbusRoleOid) {
+			continue
+		}
+		if found {
+			return "", errors.New("several Modbus role extensions")
+		}
+		found = true
+		rest, err := asn1.UnmarshalWithParams(ext.Value, &role, "utf8")
+		if err != nil {
+			return "", err
+		}
+		if len(rest) > 0 {
+			return "", errors.New("trailing data after the Modbus role")
+		}
+	}
+	return
+}
+
+// TlsRole return the role of the peer of a Modbus/TCP Security context
+//
+// The TlsRole() function shall return the role of the certificate presented by the peer of the current connection,
+// see ModbusCertificateRole(). It fails with EINVAL when the context is not a TLS one or not connected.
+func (x *Modbus) TlsRole() (role string, err error) {
+	if x.be == nil {
+		return "", ErrorCode(syscall.EINVAL).Error()
+	}
+	current, _ := x.be.current()
+	conn, ok := current.(*tls.Conn)
+	if !ok {
+		return "", ErrorCode(syscall.EINVAL).Error()
+	}
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", ErrorCode(syscall.EINVAL).Error()
+	}
+	return ModbusCertificateRole(certs[0])
+}
+
+// ModbusRoleMiddleware authorize the requests from the role of the client
+//
+// The middleware calls authorize with the role returned by TlsRole() for each address range of the request, the
+// request is refused with MODBUS_EXCEPTION_ILLEGAL_FUNCTION as soon as one range is denied, as required by the
+// Modbus/TCP Security specification. Requests received without role, over a context without TLS, are denied.
+func ModbusRoleMiddleware(authorize ModbusRoleAuthorizer) ModbusMiddleware {
+	return func(next ModbusHandler) ModbusHandler {
+		return func(x *Modbus, r *ModbusRequest) error {
+			role, err := x.TlsRole()
+			if err != nil {
+				return MODBUS_EXCEPTION_ILLEGAL_FUNCTION
+			}
+			ranges := r.ranges()
+			if len(ranges) == 0 {
+				ranges = []modbusRange{{}}
+			}
+			for _, rg := range ranges {
+				if !authorize(role, r.Function, r.Table, rg.addr, rg.nb) {
+					return MODBUS_EXCEPTION_ILLEGAL_FUNCTION
+				}
+			}
+			return next(x, r)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package libmodbusgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// tlsCertificate issue a certificate signed by ca, or self-signed when ca is nil
+func tlsCertificate(t *testing.T, ca *tls.Certificate, name string, role string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if role != "" {
+		value, err := asn1.MarshalWithParams(role, "utf8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl.ExtraExtensions = []pkix.Extension{{Id: ModbusRoleOid, Value: value}}
+	}
+	parent, signer := tmpl, any(key)
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		parent, signer = ca.Leaf, ca.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestModbusNewTls(t *testing.T) {
+	ca := tlsCertificate(t, nil, "ca", "")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	serverCert := tlsCertificate(t, &ca, "server", "")
+	operatorCert := tlsCertificate(t, &ca, "operator", "Operator")
+
+	mm := ModbusMappingNew(0, 0, 10, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+	mm.SetTabRegisters(0, 42)
+
+	var roles []string
+	authorize := func(role string, function int, table ModbusTable, addr int, nb int) bool {
+		roles = append(roles, role)
+		return role == "Operator" && (function == MODBUS_FC_READ_HOLDING_REGISTERS || addr >= 5)
+	}
+	s := ModbusServerNew(ModbusMappingHandler(mm), ModbusRoleMiddleware(authorize))
+
+	server := ModbusNewTls("127.0.0.1", 1508, &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientCAs: pool})
+	if server == nil {
+		t.FailNow()
+	}
+	defer server.Free()
+	if _, err := server.TcpListen(1); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			if err := server.TcpAccept(); err != nil {
+				// the listener is closed by Free, a failed handshake only drops the client
+				var merr *Error
+				if errors.As(err, &merr) && merr.Code() == ErrorCode(syscall.EBADF) {
+					return
+				}
+				continue
+			}
+			for {
+				req, err := server.Receive()
+				if err != nil {
+					break
+				}
+				s.Reply(server, req)
+			}
+		}
+	}()
+
+	ctx := ModbusNewTls("127.0.0.1", 1508, &tls.Config{Certificates: []tls.Certificate{operatorCert}, RootCAs: pool})
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	if err := ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if role, err := ctx.TlsRole(); err != nil || role != "" {
+		t.Error("server certificate role", role, err)
+	}
+	regs, err := ctx.ReadRegisters(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regs[0] != 42 {
+		t.Errorf("register %d", regs[0])
+	}
+	var merr *Error
+	if err = ctx.WriteRegister(0, 1); !errors.As(err, &merr) || merr.Code() != EMBXILFUN {
+		t.Error("expected illegal function, got", err)
+	}
+	if err = ctx.WriteRegister(5, 1); err != nil {
+		t.Error(err)
+	}
+	if len(roles) != 3 || roles[0] != "Operator" {
+		t.Errorf("roles %v", roles)
+	}
+	ctx.Close()
+
+	// a client without certificate is refused
+	anonymous := ModbusNewTls("127.0.0.1", 1508, &tls.Config{RootCAs: pool})
+	if anonymous == nil {
+		t.FailNow()
+	}
+	defer anonymous.Free()
+	anonymous.SetResponseTimeout(200 * time.Millisecond)
+	if err = anonymous.Connect(); err == nil {
+		_, err = anonymous.ReadRegisters(0, 1)
+	}
+	if err == nil {
+		t.Error("client without certificate served")
+	}
+}
+
+func TestModbusCertificateRole(t *testing.T) {
+	ca := tlsCertificate(t, nil, "ca", "")
+	cert := tlsCertificate(t, &ca, "engineer", "Engineer")
+	role, err := ModbusCertificateRole(cert.Leaf)
+	if err != nil || role != "Engineer" {
+		t.Error(role, err)
+	}
+	role, err = ModbusCertificateRole(ca.Leaf)
+	if err != nil || role != "" {
+		t.Error(role, err)
+	}
+}
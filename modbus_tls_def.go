@@ -0,0 +1,26 @@
+package libmodbusgo
+
+import (
+	"encoding/asn1"
+	"net"
+)
+
+// MODBUS_TLS_DEFAULT_PORT port of Modbus/TCP Security, MB-TCP-Security-v21_2018-07-24.pdf Chapter 3 Section 2
+const MODBUS_TLS_DEFAULT_PORT = 802
+
+// ModbusRoleOid object identifier of the X.509 v3 extension carrying the role of a Modbus/TCP Security client, its
+// value is an ASN1 UTF8String
+var ModbusRoleOid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 50316, 802, 1}
+
+// ModbusRoleAuthorizer decide whether a client with role may access nb addresses from addr of table with function
+//
+// The authorizer is called once per address range of the request, twice for MODBUS_FC_WRITE_AND_READ_REGISTERS, and
+// once with MODBUS_TABLE_NONE and a zero range for the requests without address. The role is empty when the client
+// certificate has no role extension.
+type ModbusRoleAuthorizer func(role string, function int, table ModbusTable, addr int, nb int) bool
+
+// modbusTlsListener TLS listener keeping access to the listening socket
+type modbusTlsListener struct {
+	net.Listener
+	tcp *net.TCPListener
+}
@@ -0,0 +1,109 @@
+package libmodbusgo
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+func (c *modbusUdpServerConn) Read(b []byte) (n int, err error) {
+	n, peer, err := c.UDPConn.ReadFrom(b)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.peer = peer
+	c.mu.Unlock()
+	return
+}
+
+func (c *modbusUdpServerConn) Write(b []byte) (n int, err error) {
+	c.mu.Lock()
+	peer := c.peer
+	c.mu.Unlock()
+	if peer == nil {
+		return 0, syscall.EDESTADDRREQ
+	}
+	return c.UDPConn.WriteTo(b, peer)
+}
+
+func (c *modbusUdpServerConn) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.peer
+}
+
+// ModbusNewUdp create a context for Modbus UDP
+//
+// The ModbusNewUdp() function shall allocate and initialize a Modbus context to communicate with a Modbus UDP server,
+// the messages are the ones of Modbus TCP, MBAP header included, sent as datagrams. The addr and port arguments are
+// the ones of ModbusNewTcp(), addr may also be a host name or an IPv6 address.
+//
+// As a client, Connect() sets the server address of the socket. A request without confirmation within the response
+// timeout is sent again, up to UdpSetRetries() times, and the confirmations whose transaction identifier is not the
+// one of the request, late answers to previous requests, are ignored.
+//
+// As a server, UdpListen() binds the socket to addr and port, an empty addr listens to any addresses. All the clients
+// share that socket, Receive() returns the requests of any client and Reply() answers the client of the last request
+// received.
+func ModbusNewUdp(addr string, port int) *Modbus {
+	if port < 0 || port > 0xFFFF {
+		return nil
+	}
+	address := net.JoinHostPort(addr, strconv.Itoa(port))
+	be := modbusBackendNew(modbusMbap{}, func() (io.ReadWriteCloser, error) {
+		return net.Dial("udp", address)
+	})
+	be.datagram = true
+	be.retries = MODBUS_UDP_DEFAULT_RETRIES
+	be.bind = func() (io.ReadWriteCloser, error) {
+		laddr, err := net.ResolveUDPAddr("udp", address)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := net.ListenUDP("udp", laddr)
+		if err != nil {
+			return nil, err
+		}
+		return &modbusUdpServerConn{UDPConn: conn}, nil
+	}
+	return &Modbus{be: be}
+}
+
+// UdpListen bind the socket of a Modbus UDP server
+//
+// The UdpListen() function shall create a socket bound to the address of the context and return it, the requests of
+// all the clients are then received on that socket with Receive().
+func (x *Modbus) UdpListen() (socket int, err error) {
+	if x.be == nil || x.be.bind == nil {
+		return -1, ErrorCode(syscall.EINVAL).Error()
+	}
+	x.be.close()
+	conn, err := x.be.bind()
+	if err != nil {
+		return -1, modbusErrno(err)
+	}
+	x.be.use(conn)
+	return x.be.socket()
+}
+
+// UdpSetRetries set the number of retransmissions of a request
+//
+// The UdpSetRetries() function shall set the number of times a request is sent again when no confirmation is received
+// within the response timeout, MODBUS_UDP_DEFAULT_RETRIES by default. A zero value disables the retransmissions.
+func (x *Modbus) UdpSetRetries(retries int) (err error) {
+	if x.be == nil || !x.be.datagram || retries < 0 {
+		return ErrorCode(syscall.EINVAL).Error()
+	}
+	x.be.retries = retries
+	return
+}
+
+// UdpGetRetries get the number of retransmissions of a request
+func (x *Modbus) UdpGetRetries() (retries int, err error) {
+	if x.be == nil || !x.be.datagram {
+		return 0, ErrorCode(syscall.EINVAL).Error()
+	}
+	return x.be.retries, nil
+}
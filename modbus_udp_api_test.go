@@ -0,0 +1,110 @@
+package libmodbusgo
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestModbusNewUdp(t *testing.T) {
+	mm := ModbusMappingNew(0, 0, 10, 0)
+	if mm == nil {
+		t.FailNow()
+	}
+	defer mm.Free()
+
+	server := ModbusNewUdp("127.0.0.1", 1506)
+	if server == nil {
+		t.FailNow()
+	}
+	defer server.Free()
+	if _, err := server.UdpListen(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			req, err := server.Receive()
+			if err != nil {
+				return
+			}
+			server.Reply(req, mm)
+		}
+	}()
+
+	// several clients share the server socket
+	var clients []*Modbus
+	for range 2 {
+		ctx := ModbusNewUdp("127.0.0.1", 1506)
+		if ctx == nil {
+			t.FailNow()
+		}
+		defer ctx.Free()
+		if err := ctx.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, ctx)
+	}
+	for i, ctx := range clients {
+		if err := ctx.WriteRegister(i, uint16(100+i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ctx := range clients {
+		regs, err := ctx.ReadRegisters(0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if regs[0] != 100 || regs[1] != 101 {
+			t.Errorf("registers %v", regs)
+		}
+	}
+}
+
+func TestModbusUdp_Retransmission(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:1507")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, MODBUS_TCP_MAX_ADU_LENGTH)
+		// the first request is lost
+		if _, _, err := pc.ReadFrom(buf); err != nil {
+			return
+		}
+		n, peer, err := pc.ReadFrom(buf)
+		if err != nil || n != 12 {
+			return
+		}
+		rsp := []byte{buf[0], buf[1], 0, 0, 0, 5, buf[6], MODBUS_FC_READ_HOLDING_REGISTERS, 2, 0x12, 0x34}
+		stale := append([]byte{}, rsp...)
+		stale[1]--
+		stale[10] = 0
+		pc.WriteTo(stale, peer)
+		pc.WriteTo(rsp, peer)
+	}()
+
+	ctx := ModbusNewUdp("127.0.0.1", 1507)
+	if ctx == nil {
+		t.FailNow()
+	}
+	defer ctx.Free()
+	ctx.SetResponseTimeout(100 * time.Millisecond)
+	if err = ctx.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	regs, err := ctx.ReadRegisters(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regs[0] != 0x1234 {
+		t.Errorf("register %X", regs[0])
+	}
+
+	if err = ctx.UdpSetRetries(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ctx.ReadRegisters(0, 1); err == nil {
+		t.Error("confirmation without server")
+	}
+}
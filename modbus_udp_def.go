@@ -0,0 +1,17 @@
+package libmodbusgo
+
+import (
+	"net"
+	"sync"
+)
+
+// MODBUS_UDP_DEFAULT_RETRIES default number of retransmissions of a request without confirmation
+const MODBUS_UDP_DEFAULT_RETRIES = 2
+
+// modbusUdpServerConn server side of a UDP socket shared by all the clients, the responses are sent to the client of
+// the last request received
+type modbusUdpServerConn struct {
+	*net.UDPConn
+	mu   sync.Mutex
+	peer net.Addr
+}
@@ -0,0 +1,77 @@
+package libmodbusgo
+
+import "strings"
+
+// modbusUnit linear conversion of a unit to the base unit of its quantity: base = value*gain + offset
+type modbusUnit struct {
+	quantity string
+	gain     float64
+	offset   float64
+}
+
+var modbusUnits = map[string]modbusUnit{
+	"V":    {"voltage", 1, 0},
+	"mV":   {"voltage", 1e-3, 0},
+	"kV":   {"voltage", 1e3, 0},
+	"A":    {"current", 1, 0},
+	"mA":   {"current", 1e-3, 0},
+	"kA":   {"current", 1e3, 0},
+	"W":    {"power", 1, 0},
+	"kW":   {"power", 1e3, 0},
+	"MW":   {"power", 1e6, 0},
+	"VA":   {"apparent power", 1, 0},
+	"kVA":  {"apparent power", 1e3, 0},
+	"MVA":  {"apparent power", 1e6, 0},
+	"var":  {"reactive power", 1, 0},
+	"kvar": {"reactive power", 1e3, 0},
+	"Mvar": {"reactive power", 1e6, 0},
+	"Wh":   {"energy", 1, 0},
+	"kWh":  {"energy", 1e3, 0},
+	"MWh":  {"energy", 1e6, 0},
+	"J":    {"energy", 1.0 / 3600, 0},
+	"kJ":   {"energy", 1e3 / 3600, 0},
+	"MJ":   {"energy", 1e6 / 3600, 0},
+	"Hz":   {"frequency", 1, 0},
+	"kHz":  {"frequency", 1e3, 0},
+	"C":    {"temperature", 1, 273.15},
+	"°C":   {"temperature", 1, 273.15},
+	"F":    {"temperature", 5.0 / 9, 273.15 - 32*5.0/9},
+	"°F":   {"temperature", 5.0 / 9, 273.15 - 32*5.0/9},
+	"K":    {"temperature", 1, 0},
+	"Pa":   {"pressure", 1, 0},
+	"kPa":  {"pressure", 1e3, 0},
+	"MPa":  {"pressure", 1e6, 0},
+	"mbar": {"pressure", 1e2, 0},
+	"bar":  {"pressure", 1e5, 0},
+	"psi":  {"pressure", 6894.757293168361, 0},
+	"%":    {"ratio", 1e-2, 0},
+	"s":    {"time", 1, 0},
+	"ms":   {"time", 1e-3, 0},
+	"min":  {"time", 60, 0},
+	"h":    {"time", 3600, 0},
+}
+
+// ModbusConvertUnit convert a value between two units of the same quantity
+//
+// The units are the SI units of the electrical measures and their multiples (V, A, W, VA, var, Wh, J, Hz with m, k and
+// M), the temperatures (C, F, K), the pressures (Pa, bar, psi), the times (s, min, h) and the percentages (%). The
+// conversion between unknown units or units of different quantities returns EINVAL, a conversion to the same unit
+// returns the value unchanged.
+func ModbusConvertUnit(value float64, from string, to string) (converted float64, err error) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if from == to {
+		return value, nil
+	}
+	f, ok := modbusUnits[from]
+	if !ok {
+		return 0, marshalError("unknown unit %q", from)
+	}
+	t, ok := modbusUnits[to]
+	if !ok {
+		return 0, marshalError("unknown unit %q", to)
+	}
+	if f.quantity != t.quantity {
+		return 0, marshalError("cannot convert %s (%s) to %s (%s)", from, f.quantity, to, t.quantity)
+	}
+	return (value*f.gain + f.offset - t.offset) / t.gain, nil
+}
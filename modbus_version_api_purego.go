@@ -0,0 +1,10 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+// ModbusVersionCheck Evaluates to True if the version is greater than @major, @minor and @micro
+func ModbusVersionCheck(major uint, minor uint, micro uint) bool {
+	return LIBMODBUS_VERSION_MAJOR > major ||
+		(LIBMODBUS_VERSION_MAJOR == major && LIBMODBUS_VERSION_MINOR > minor) ||
+		(LIBMODBUS_VERSION_MAJOR == major && LIBMODBUS_VERSION_MINOR == minor && LIBMODBUS_VERSION_MICRO >= micro)
+}
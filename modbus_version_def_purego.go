@@ -0,0 +1,12 @@
+//go:build !cgo || purego
+
+package libmodbusgo
+
+// The pure Go backend implements the protocol of this libmodbus release
+const (
+	LIBMODBUS_VERSION_MAJOR  = 3        // The major version, (1, if %LIBMODBUS_VERSION is 1.2.3)
+	LIBMODBUS_VERSION_MINOR  = 1        // The minor version (2, if %LIBMODBUS_VERSION is 1.2.3)
+	LIBMODBUS_VERSION_MICRO  = 10       // The micro version (3, if %LIBMODBUS_VERSION is 1.2.3)
+	LIBMODBUS_VERSION_STRING = "3.1.10" // The full version, in string form (suited for string concatenation)
+	LIBMODBUS_VERSION_HEX    = 0x03010A // Numerically encoded version, eg. v1.2.3 is 0x010203
+)